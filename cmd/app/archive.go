@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+
+	serviceRepo "github.com/OlegGibadulin/tech-db-forum/internal/service/repository"
+	serviceUsecase "github.com/OlegGibadulin/tech-db-forum/internal/service/usecases"
+)
+
+// runExport writes archive of whole database or of one forum:
+//
+//	main export [-forum slug] [-o file]
+func runExport(dbConn *sql.DB, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	forumSlug := flags.String("forum", "", "slug of exported forum, whole database by default")
+	outPath := flags.String("o", "", "archive file, standard output by default")
+	flags.Parse(args)
+
+	var out io.Writer = os.Stdout
+	if *outPath != "" {
+		file, err := os.Create(*outPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	writer := bufio.NewWriter(out)
+	serviceUcase := serviceUsecase.NewServiceUsecase(serviceRepo.NewServicePgRepository(dbConn))
+	if err := serviceUcase.Export(*forumSlug, writer); err != nil {
+		return fmt.Errorf("%s", err.Message)
+	}
+	return writer.Flush()
+}
+
+// runRestore loads archive into empty database:
+//
+//	main restore archive.jsonl
+//
+// Archive is read from standard input if file is -
+func runRestore(dbConn *sql.DB, args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("archive file is required")
+	}
+
+	var in io.Reader = os.Stdin
+	if path := flags.Arg(0); path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+
+	serviceUcase := serviceUsecase.NewServiceUsecase(serviceRepo.NewServicePgRepository(dbConn))
+	if err := serviceUcase.Restore(in); err != nil {
+		return fmt.Errorf("%s", err.Message)
+	}
+
+	status, err := serviceUcase.GetStatus()
+	if err != nil {
+		return fmt.Errorf("%s", err.Message)
+	}
+	logrus.Infof("Archive restored: %d users, %d forums, %d threads, %d posts",
+		status.Users, status.Forums, status.Threads, status.Posts)
+	return nil
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// runCommand runs subcommand with database of server instead of starting server
+func runCommand(dbConn *sql.DB, name string, args []string) error {
+	switch name {
+	case "import":
+		return runImport(dbConn, args)
+	case "export":
+		return runExport(dbConn, args)
+	case "restore":
+		return runRestore(dbConn, args)
+	default:
+		return fmt.Errorf("unknown command %s", name)
+	}
+}
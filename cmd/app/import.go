@@ -0,0 +1,70 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/importer"
+)
+
+// runImport loads JSON-lines dump of old forum:
+//
+//	main import [-source name] [-rejects file] [-batch size] dump.jsonl
+//
+// Import stopped by failure is continued by running it again with the same source
+func runImport(dbConn *sql.DB, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	source := flags.String("source", "", "name of imported forum, dump file name by default")
+	rejectsPath := flags.String("rejects", "", "file for rejected records, dump file name with .rejected by default")
+	batchSize := flags.Int("batch", importer.DefaultBatchSize, "number of dump lines imported in one transaction")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("dump file is required")
+	}
+	dumpPath := flags.Arg(0)
+	if *source == "" {
+		*source = filepath.Base(dumpPath)
+	}
+	if *rejectsPath == "" {
+		*rejectsPath = dumpPath + ".rejected"
+	}
+
+	dump, err := os.Open(dumpPath)
+	if err != nil {
+		return err
+	}
+	defer dump.Close()
+
+	// Rejections of previous runs are kept, continued import appends its own
+	rejects, err := os.OpenFile(*rejectsPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer rejects.Close()
+
+	started := time.Now()
+	report := func(progress *importer.Progress) {
+		logrus.Infof("Line %d: imported %d, rejected %d, %s", progress.Line, progress.Imported,
+			progress.Rejected, time.Since(started).Round(time.Second))
+	}
+
+	progress, err := importer.NewImporter(dbConn, *source, *batchSize).Run(dump, rejects, report)
+	if err != nil {
+		if progress != nil {
+			logrus.Errorf("Import stopped after line %d, run it again to continue", progress.Line)
+		}
+		return err
+	}
+
+	logrus.Infof("Import of %s finished: imported %d, rejected %d, rejected records are in %s",
+		*source, progress.Imported, progress.Rejected, *rejectsPath)
+	return nil
+}
@@ -2,13 +2,27 @@ package main
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
+	"net"
+	"net/url"
+	"os"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	_ "github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
 
 	"github.com/OlegGibadulin/tech-db-forum/config"
 	"github.com/OlegGibadulin/tech-db-forum/internal/mwares"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/forumpb"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/idempotency"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/mailer"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/markdown"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/periodic"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/ratelimit"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/storage"
 
 	userHandler "github.com/OlegGibadulin/tech-db-forum/internal/user/delivery"
 	userRepo "github.com/OlegGibadulin/tech-db-forum/internal/user/repository"
@@ -26,6 +40,48 @@ import (
 	postRepo "github.com/OlegGibadulin/tech-db-forum/internal/post/repository"
 	postUsecase "github.com/OlegGibadulin/tech-db-forum/internal/post/usecases"
 
+	notificationHandler "github.com/OlegGibadulin/tech-db-forum/internal/notification/delivery"
+	notificationRepo "github.com/OlegGibadulin/tech-db-forum/internal/notification/repository"
+	notificationUsecase "github.com/OlegGibadulin/tech-db-forum/internal/notification/usecases"
+
+	followHandler "github.com/OlegGibadulin/tech-db-forum/internal/follow/delivery"
+	followRepo "github.com/OlegGibadulin/tech-db-forum/internal/follow/repository"
+	followUsecase "github.com/OlegGibadulin/tech-db-forum/internal/follow/usecases"
+
+	digestHandler "github.com/OlegGibadulin/tech-db-forum/internal/digest/delivery"
+	digestRepo "github.com/OlegGibadulin/tech-db-forum/internal/digest/repository"
+	digestUsecase "github.com/OlegGibadulin/tech-db-forum/internal/digest/usecases"
+
+	reportHandler "github.com/OlegGibadulin/tech-db-forum/internal/report/delivery"
+	reportRepo "github.com/OlegGibadulin/tech-db-forum/internal/report/repository"
+	reportUsecase "github.com/OlegGibadulin/tech-db-forum/internal/report/usecases"
+
+	moderationHandler "github.com/OlegGibadulin/tech-db-forum/internal/moderation/delivery"
+	moderationRepo "github.com/OlegGibadulin/tech-db-forum/internal/moderation/repository"
+	moderationUsecase "github.com/OlegGibadulin/tech-db-forum/internal/moderation/usecases"
+
+	sanctionHandler "github.com/OlegGibadulin/tech-db-forum/internal/sanction/delivery"
+	sanctionRepo "github.com/OlegGibadulin/tech-db-forum/internal/sanction/repository"
+	sanctionUsecase "github.com/OlegGibadulin/tech-db-forum/internal/sanction/usecases"
+
+	attachmentHandler "github.com/OlegGibadulin/tech-db-forum/internal/attachment/delivery"
+	attachmentRepo "github.com/OlegGibadulin/tech-db-forum/internal/attachment/repository"
+	attachmentUsecase "github.com/OlegGibadulin/tech-db-forum/internal/attachment/usecases"
+
+	pollHandler "github.com/OlegGibadulin/tech-db-forum/internal/poll/delivery"
+	pollRepo "github.com/OlegGibadulin/tech-db-forum/internal/poll/repository"
+	pollUsecase "github.com/OlegGibadulin/tech-db-forum/internal/poll/usecases"
+
+	draftHandler "github.com/OlegGibadulin/tech-db-forum/internal/draft/delivery"
+	draftRepo "github.com/OlegGibadulin/tech-db-forum/internal/draft/repository"
+	draftUsecase "github.com/OlegGibadulin/tech-db-forum/internal/draft/usecases"
+
+	feedHandler "github.com/OlegGibadulin/tech-db-forum/internal/feed/delivery"
+
+	graphqlHandler "github.com/OlegGibadulin/tech-db-forum/internal/graphql/delivery"
+
+	rpcServer "github.com/OlegGibadulin/tech-db-forum/internal/rpc/delivery"
+
 	serviceHandler "github.com/OlegGibadulin/tech-db-forum/internal/service/delivery"
 	serviceRepo "github.com/OlegGibadulin/tech-db-forum/internal/service/repository"
 	serviceUsecase "github.com/OlegGibadulin/tech-db-forum/internal/service/usecases"
@@ -48,37 +104,202 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// Subcommands use database of server and exit
+	if len(os.Args) > 1 {
+		if err := runCommand(dbConnection, os.Args[1], os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Mail
+	var mailSender mailer.Sender
+	if config.Mail.Sender == "smtp" {
+		mailSender = mailer.NewSMTPSender(config.GetMailConnString(), config.Mail.Username,
+			config.Mail.Password, config.Mail.From)
+	} else {
+		mailSender = mailer.NewFileSender(config.Mail.Dir, config.Mail.From)
+	}
+
+	// Attachments
+	var fileStorage storage.Storage
+	if config.Attachments.Storage == "s3" {
+		fileStorage = storage.NewS3Storage(config.Attachments.S3.Endpoint, config.Attachments.S3.Region,
+			config.Attachments.S3.Bucket, config.Attachments.S3.AccessKey, config.Attachments.S3.SecretKey)
+	} else {
+		fileStorage = storage.NewLocalStorage(config.Attachments.Dir)
+	}
+
+	// Markdown of messages links mentions and quotes to the same resources as API
+	renderer := markdown.NewRenderer(markdown.Options{
+		MentionURL: func(nickname string) string {
+			return fmt.Sprintf("/api/user/%s/profile", url.PathEscape(nickname))
+		},
+		QuoteURL: func(postID uint64) string {
+			return fmt.Sprintf("/api/post/%d/details", postID)
+		},
+	}, config.Markdown.CacheSize)
+
 	// Repository
 	userRepo := userRepo.NewUserPgRepository(dbConnection)
 	threadRepo := threadRepo.NewThreadPgRepository(dbConnection)
 	forumRepo := forumRepo.NewForumPgRepository(dbConnection)
 	postRepo := postRepo.NewPostPgRepository(dbConnection)
+	notificationRepo := notificationRepo.NewNotificationPgRepository(dbConnection)
+	followRepo := followRepo.NewFollowPgRepository(dbConnection)
+	digestRepo := digestRepo.NewDigestPgRepository(dbConnection)
+	sanctionRepo := sanctionRepo.NewSanctionPgRepository(dbConnection)
+	moderationRepo := moderationRepo.NewModerationPgRepository(dbConnection)
+	reportRepo := reportRepo.NewReportPgRepository(dbConnection)
+	attachmentRepo := attachmentRepo.NewAttachmentPgRepository(dbConnection)
+	pollRepo := pollRepo.NewPollPgRepository(dbConnection)
+	draftRepo := draftRepo.NewDraftPgRepository(dbConnection)
 	serviceRepo := serviceRepo.NewServicePgRepository(dbConnection)
 
 	// Usecases
-	userUcase := userUsecase.NewUserUsecase(userRepo)
-	threadUcase := threadUsecase.NewThreadUsecase(threadRepo)
+	userUcase := userUsecase.NewUserUsecase(userRepo, config.User.NicknameGracePeriod.Duration)
+	sanctionUcase := sanctionUsecase.NewSanctionUsecase(sanctionRepo)
+	notificationUcase := notificationUsecase.NewNotificationUsecase(notificationRepo)
+	moderationUcase := moderationUsecase.NewModerationUsecase(moderationRepo, notificationUcase)
+	threadUcase := threadUsecase.NewThreadUsecase(threadRepo, sanctionUcase, moderationUcase, renderer)
 	forumUcase := forumUsecase.NewForumUsecase(forumRepo)
-	postUcase := postUsecase.NewPostUsecase(postRepo)
+	postUcase := postUsecase.NewPostUsecase(postRepo, notificationUcase, sanctionUcase, moderationUcase,
+		renderer)
+	reportUcase := reportUsecase.NewReportUsecase(reportRepo, config.Reports.HideThreshold)
+	attachmentUcase := attachmentUsecase.NewAttachmentUsecase(attachmentRepo, fileStorage,
+		config.Attachments.MaxSize, config.Attachments.AllowedTypes, config.Attachments.ThumbnailSize)
+	pollUcase := pollUsecase.NewPollUsecase(pollRepo, sanctionUcase)
+	draftUcase := draftUsecase.NewDraftUsecase(draftRepo, threadUcase, postUcase, config.Drafts.PublishBatch)
+	followUcase := followUsecase.NewFollowUsecase(followRepo)
+	digestUcase := digestUsecase.NewDigestUsecase(digestRepo, mailSender, config.Digest.BaseURL,
+		config.Digest.MaxPosts)
 	serviceUcase := serviceUsecase.NewServiceUsecase(serviceRepo)
 
+	// Background jobs
+	go periodic.Run(config.Digest.Interval.Duration, func() {
+		if err := digestUcase.SendDigests(); err != nil {
+			logrus.Error(err.Message)
+		}
+	})
+	go periodic.Run(config.Moderation.SweepInterval.Duration, func() {
+		if err := sanctionUcase.LiftExpired(); err != nil {
+			logrus.Error(err.Message)
+		}
+	})
+	go periodic.Run(config.Drafts.PublishInterval.Duration, func() {
+		if err := draftUcase.PublishDue(); err != nil {
+			logrus.Error(err.Message)
+		}
+	})
+	go periodic.Run(config.Attachments.SweepInterval.Duration, func() {
+		if err := attachmentUcase.SweepOrphaned(); err != nil {
+			logrus.Error(err.Message)
+		}
+	})
+
 	// Middleware
 	e := echo.New()
-	mw := mwares.NewMiddlewareManager()
+	// X-Forwarded-For is taken only from trusted proxies, otherwise clients would pick
+	// their own address and get fresh rate limit bucket with every request
+	if len(config.RateLimit.TrustedProxies) == 0 {
+		e.IPExtractor = echo.ExtractIPDirect()
+	} else {
+		trustOptions := []echo.TrustOption{
+			echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false),
+		}
+		for _, cidr := range config.RateLimit.TrustedProxies {
+			_, ipNet, _ := net.ParseCIDR(cidr)
+			trustOptions = append(trustOptions, echo.TrustIPRange(ipNet))
+		}
+		e.IPExtractor = echo.ExtractIPFromXFFHeader(trustOptions...)
+	}
+	var rateLimiter *ratelimit.Limiter
+	if config.RateLimit.Enabled {
+		var rateLimitStore ratelimit.Store
+		if config.RateLimit.Store == "postgres" {
+			rateLimitStore = ratelimit.NewPgStore(dbConnection)
+		} else {
+			rateLimitStore = ratelimit.NewMemoryStore()
+		}
+		rateLimiter = ratelimit.NewLimiter(rateLimitStore, config.RateLimit.Default, config.RateLimit.Routes)
+
+		// Buckets unused for sweep interval are dropped, they would be recreated full anyway
+		go periodic.Run(config.RateLimit.SweepInterval.Duration, func() {
+			before := time.Now().Add(-config.RateLimit.SweepInterval.Duration)
+			if err := rateLimiter.Sweep(before); err != nil {
+				logrus.Error(err)
+			}
+		})
+	}
+
+	idempotencyStore := idempotency.NewPgStore(dbConnection)
+	go periodic.Run(config.Idempotency.SweepInterval.Duration, func() {
+		if err := idempotencyStore.Sweep(time.Now()); err != nil {
+			logrus.Error(err)
+		}
+	})
+
+	mw := mwares.NewMiddlewareManager(rateLimiter, config.RateLimit.UserHeader,
+		idempotencyStore, config.Idempotency.TTL.Duration, config.Service.AdminToken)
 	// e.Use(mw.PanicRecovering, mw.AccessLog)
+	e.Use(mw.RateLimiting)
 
 	// Delivery
-	userHandler := userHandler.NewUserHandler(userUcase)
-	threadHandler := threadHandler.NewThreadHandler(threadUcase, userUcase, postUcase, forumUcase)
-	forumHandler := forumHandler.NewForumHandler(forumUcase, userUcase, threadUcase)
-	postHandler := postHandler.NewPostHandler(postUcase, userUcase, threadUcase, forumUcase)
+	userHandler := userHandler.NewUserHandler(userUcase, threadUcase, postUcase, forumUcase, attachmentUcase,
+		config.Posts.MaxListLimit)
+	threadHandler := threadHandler.NewThreadHandler(threadUcase, userUcase, postUcase, forumUcase, attachmentUcase,
+		pollUcase, config.RateLimit.MaxPostsBatch, config.Posts.MaxListLimit)
+	forumHandler := forumHandler.NewForumHandler(forumUcase, userUcase, threadUcase, pollUcase)
+	postHandler := postHandler.NewPostHandler(postUcase, userUcase, threadUcase, forumUcase, reportUcase,
+		attachmentUcase)
+	notificationHandler := notificationHandler.NewNotificationHandler(notificationUcase, userUcase)
+	followHandler := followHandler.NewFollowHandler(followUcase, userUcase, threadUcase, postUcase, forumUcase,
+		attachmentUcase)
+	digestHandler := digestHandler.NewDigestHandler(digestUcase, userUcase)
+	reportHandler := reportHandler.NewReportHandler(reportUcase, userUcase, postUcase, forumUcase)
+	moderationHandler := moderationHandler.NewModerationHandler(moderationUcase, forumUcase)
+	sanctionHandler := sanctionHandler.NewSanctionHandler(sanctionUcase, userUcase, forumUcase)
+	attachmentHandler := attachmentHandler.NewAttachmentHandler(attachmentUcase, postUcase)
+	pollHandler := pollHandler.NewPollHandler(pollUcase, threadUcase, userUcase)
+	draftHandler := draftHandler.NewDraftHandler(draftUcase, userUcase, forumUcase, threadUcase,
+		config.Posts.MaxListLimit)
+	feedHandler := feedHandler.NewFeedHandler(forumUcase, threadUcase, postUcase, userUcase,
+		config.Feed.BaseURL, config.Feed.MaxEntries)
+	graphqlHandler := graphqlHandler.NewGraphQLHandler(userUcase, forumUcase, threadUcase, postUcase,
+		config.RateLimit.MaxPostsBatch, config.GraphQL.MaxDepth, config.GraphQL.MaxParallelism)
 	serviceHandler := serviceHandler.NewServiceHandler(serviceUcase)
 
 	userHandler.Configure(e, mw)
 	threadHandler.Configure(e, mw)
 	forumHandler.Configure(e, mw)
 	postHandler.Configure(e, mw)
+	notificationHandler.Configure(e, mw)
+	followHandler.Configure(e, mw)
+	digestHandler.Configure(e, mw)
+	reportHandler.Configure(e, mw)
+	moderationHandler.Configure(e, mw)
+	sanctionHandler.Configure(e, mw)
+	attachmentHandler.Configure(e, mw)
+	pollHandler.Configure(e, mw)
+	draftHandler.Configure(e, mw)
+	feedHandler.Configure(e, mw)
+	graphqlHandler.Configure(e, mw)
 	serviceHandler.Configure(e, mw)
 
+	// gRPC is served on its own port, zero port disables it
+	if config.GRPC.Port != 0 {
+		listener, err := net.Listen("tcp", config.GetGRPCConnString())
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		grpcServer := grpc.NewServer()
+		forumpb.RegisterForumServiceServer(grpcServer, rpcServer.NewForumServer(userUcase, forumUcase,
+			threadUcase, postUcase, serviceUcase, config.RateLimit.MaxPostsBatch))
+		go func() {
+			log.Fatal(grpcServer.Serve(listener))
+		}()
+	}
+
 	log.Fatal(e.Start(config.GetServerConnString()))
 }
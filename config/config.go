@@ -2,10 +2,35 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"os"
+	"time"
+
+	"github.com/OlegGibadulin/tech-db-forum/pkg/ratelimit"
 )
 
+const defaultMaxPostsBatch = 100
+
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = duration
+	return nil
+}
+
 type Config struct {
 	Database struct {
 		User     string `json:"user"`
@@ -18,6 +43,84 @@ type Config struct {
 		Host string `json:"host"`
 		Port int    `json:"port"`
 	} `json:"server"`
+	User struct {
+		NicknameGracePeriod Duration `json:"nickname_grace_period"`
+	} `json:"user"`
+	Mail struct {
+		Sender   string `json:"sender"`
+		Dir      string `json:"dir"`
+		Host     string `json:"host"`
+		Port     int    `json:"port"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+		From     string `json:"from"`
+	} `json:"mail"`
+	Digest struct {
+		Interval Duration `json:"interval"`
+		BaseURL  string   `json:"base_url"`
+		MaxPosts uint64   `json:"max_posts"`
+	} `json:"digest"`
+	Moderation struct {
+		SweepInterval Duration `json:"sweep_interval"`
+	} `json:"moderation"`
+	Posts struct {
+		MaxListLimit uint64 `json:"max_list_limit"`
+	} `json:"posts"`
+	Markdown struct {
+		CacheSize int `json:"cache_size"`
+	} `json:"markdown"`
+	Feed struct {
+		BaseURL    string `json:"base_url"`
+		MaxEntries uint64 `json:"max_entries"`
+	} `json:"feed"`
+	Attachments struct {
+		Storage       string   `json:"storage"`
+		Dir           string   `json:"dir"`
+		MaxSize       int64    `json:"max_size"`
+		AllowedTypes  []string `json:"allowed_types"`
+		ThumbnailSize int      `json:"thumbnail_size"`
+		SweepInterval Duration `json:"sweep_interval"`
+		S3            struct {
+			Endpoint  string `json:"endpoint"`
+			Region    string `json:"region"`
+			Bucket    string `json:"bucket"`
+			AccessKey string `json:"access_key"`
+			SecretKey string `json:"secret_key"`
+		} `json:"s3"`
+	} `json:"attachments"`
+	Drafts struct {
+		PublishInterval Duration `json:"publish_interval"`
+		PublishBatch    int      `json:"publish_batch"`
+	} `json:"drafts"`
+	Reports struct {
+		HideThreshold int `json:"hide_threshold"`
+	} `json:"reports"`
+	RateLimit struct {
+		Enabled       bool                       `json:"enabled"`
+		Store         string                     `json:"store"`
+		UserHeader    string                     `json:"user_header"`
+		Default       ratelimit.Limit            `json:"default"`
+		Routes        map[string]ratelimit.Limit `json:"routes"`
+		MaxPostsBatch int                        `json:"max_posts_batch"`
+		// Networks of reverse proxies whose X-Forwarded-For is trusted, client address is used without them
+		TrustedProxies []string `json:"trusted_proxies"`
+		SweepInterval  Duration `json:"sweep_interval"`
+	} `json:"rate_limit"`
+	Idempotency struct {
+		TTL           Duration `json:"ttl"`
+		SweepInterval Duration `json:"sweep_interval"`
+	} `json:"idempotency"`
+	GraphQL struct {
+		MaxDepth       int `json:"max_depth"`
+		MaxParallelism int `json:"max_parallelism"`
+	} `json:"graphql"`
+	Service struct {
+		AdminToken string `json:"admin_token"`
+	} `json:"service"`
+	GRPC struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	} `json:"grpc"`
 }
 
 func (c *Config) GetDbConnString() string {
@@ -29,6 +132,14 @@ func (c *Config) GetServerConnString() string {
 	return fmt.Sprintf("%s:%d", c.Server.Host, c.Server.Port)
 }
 
+func (c *Config) GetGRPCConnString() string {
+	return fmt.Sprintf("%s:%d", c.GRPC.Host, c.GRPC.Port)
+}
+
+func (c *Config) GetMailConnString() string {
+	return fmt.Sprintf("%s:%d", c.Mail.Host, c.Mail.Port)
+}
+
 func LoadConfig(name string) (*Config, error) {
 	file, err := os.Open(name)
 
@@ -38,10 +149,28 @@ func LoadConfig(name string) (*Config, error) {
 		return nil, err
 	}
 
+	// Missing batch limit gets default, so only zero set on purpose leaves batches unlimited
 	config := &Config{}
+	config.RateLimit.MaxPostsBatch = defaultMaxPostsBatch
 	if err := json.NewDecoder(file).Decode(config); err != nil {
 		return nil, err
 	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
 
 	return config, nil
 }
+
+// validate rejects values which would make server run but silently do nothing
+func (c *Config) validate() error {
+	if c.Drafts.PublishInterval.Duration > 0 && c.Drafts.PublishBatch <= 0 {
+		return errors.New("drafts.publish_batch must be positive")
+	}
+	for _, cidr := range c.RateLimit.TrustedProxies {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("rate_limit.trusted_proxies: %s", err)
+		}
+	}
+	return nil
+}
@@ -8,18 +8,21 @@ require (
 	github.com/bozaro/tech-db-forum v0.2.2 // indirect
 	github.com/go-openapi/validate v0.20.0 // indirect
 	github.com/go-playground/validator/v10 v10.4.1
+	github.com/golang/protobuf v1.5.0
+	github.com/graph-gophers/graphql-go v1.3.0
 	github.com/jackc/pgconn v1.8.0
 	github.com/jackc/pgx v3.6.2+incompatible
 	github.com/labstack/echo/v4 v4.1.17
-	github.com/lib/pq v1.8.0 // indirect
+	github.com/lib/pq v1.8.0
 	github.com/mailcourses/technopark-dbms-forum v0.2.2 // indirect
 	github.com/mattn/go-colorable v0.1.8 // indirect
+	github.com/microcosm-cc/bluemonday v1.0.27
 	github.com/mkideal/cli v0.2.3 // indirect
 	github.com/op/go-logging v0.0.0-20160315200505-970db520ece7 // indirect
 	github.com/sirupsen/logrus v1.7.0
 	github.com/tinylib/msgp v1.1.5 // indirect
-	golang.org/x/crypto v0.0.0-20201221181555-eec23a3978ad // indirect
-	golang.org/x/net v0.0.0-20201224014010-6772e930b67b // indirect
-	golang.org/x/sys v0.0.0-20210105210732-16f7687f5001 // indirect
-	golang.org/x/term v0.0.0-20201210144234-2321bbc49cbf // indirect
+	github.com/yuin/goldmark v1.7.1
+	golang.org/x/image v0.18.0
+	google.golang.org/grpc v1.43.0
+	google.golang.org/protobuf v1.27.1
 )
@@ -0,0 +1,158 @@
+package delivery
+
+import (
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/attachment"
+	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/conditional"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/mwares"
+	"github.com/OlegGibadulin/tech-db-forum/internal/post"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// Room for multipart headers and boundaries around uploaded file
+const multipartOverhead = 64 * 1024
+
+// Only raster images are shown by browser in place, other files are always downloaded,
+// so uploaded HTML or SVG can't run scripts on forum origin
+var inlineTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+type AttachmentHandler struct {
+	attachmentUcase attachment.AttachmentUsecase
+	postUcase       post.PostUsecase
+}
+
+func NewAttachmentHandler(attachmentUcase attachment.AttachmentUsecase, postUcase post.PostUsecase) *AttachmentHandler {
+	return &AttachmentHandler{
+		attachmentUcase: attachmentUcase,
+		postUcase:       postUcase,
+	}
+}
+
+func (ah *AttachmentHandler) Configure(e *echo.Echo, mw *mwares.MiddlewareManager) {
+	// Body is limited before idempotency middleware reads it to fingerprint request
+	e.POST("/api/post/:pid/attachments", ah.CreateAttachmentHandler(), ah.limitBody, mw.Idempotency)
+	e.GET("/api/attachment/:id", ah.GetAttachmentHandler(false))
+	e.GET("/api/attachment/:id/thumbnail", ah.GetAttachmentHandler(true))
+}
+
+// limitBody cuts body off on limit, so too large file isn't read to the end
+func (ah *AttachmentHandler) limitBody(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(cntx echo.Context) error {
+		if maxSize := ah.attachmentUcase.MaxSize(); maxSize != 0 {
+			req := cntx.Request()
+			if req.ContentLength > maxSize+multipartOverhead {
+				err := errors.BuildByMsg(CodeAttachmentIsTooLarge, maxSize)
+				return cntx.JSON(err.HTTPCode, err.Response())
+			}
+			req.Body = http.MaxBytesReader(cntx.Response(), req.Body, maxSize+multipartOverhead)
+		}
+		return next(cntx)
+	}
+}
+
+func (ah *AttachmentHandler) CreateAttachmentHandler() echo.HandlerFunc {
+	return func(cntx echo.Context) error {
+		postID, _ := strconv.ParseUint(cntx.Param("pid"), 10, 64)
+		post, err := ah.postUcase.GetByID(postID)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		header, parseErr := cntx.FormFile("file")
+		if parseErr != nil {
+			if parseErr.Error() == "http: request body too large" {
+				err := errors.BuildByMsg(CodeAttachmentIsTooLarge, ah.attachmentUcase.MaxSize())
+				return cntx.JSON(err.HTTPCode, err.Response())
+			}
+			err := errors.Get(CodeBadRequest)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		file, openErr := header.Open()
+		if openErr != nil {
+			err := errors.New(CodeInternalError, openErr)
+			logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		defer file.Close()
+
+		attachment := &models.Attachment{
+			Post:     post.ID,
+			Filename: filepath.Base(header.Filename),
+			Size:     header.Size,
+		}
+		if err := ah.attachmentUcase.Create(attachment, file); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusCreated, attachment)
+	}
+}
+
+func (ah *AttachmentHandler) GetAttachmentHandler(thumbnail bool) echo.HandlerFunc {
+	return func(cntx echo.Context) error {
+		attachmentID, _ := strconv.ParseUint(cntx.Param("id"), 10, 64)
+		attachment, err := ah.attachmentUcase.GetByID(attachmentID)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		// Stored files never change, so creation time is enough for tag
+		var variant int64
+		if thumbnail {
+			if attachment.Thumbnail == "" {
+				err := errors.BuildByMsg(CodeThumbnailDoesNotExist, attachment.ID)
+				return cntx.JSON(err.HTTPCode, err.Response())
+			}
+			variant = 1
+		}
+		etag := conditional.ETag(attachment.ID, attachment.Created, variant)
+		if conditional.IsNotModified(cntx, etag, attachment.Created) {
+			return cntx.NoContent(http.StatusNotModified)
+		}
+
+		body, err := ah.attachmentUcase.Open(attachment, thumbnail)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		defer body.Close()
+
+		contentType := attachment.ContentType
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		disposition := "attachment"
+		if inlineTypes[mediaType] {
+			disposition = "inline"
+		}
+
+		header := cntx.Response().Header()
+		if thumbnail {
+			// Thumbnails of JPEG images stay JPEG, other images are scaled to PNG
+			contentType = "image/png"
+			if mediaType == "image/jpeg" {
+				contentType = mediaType
+			}
+		} else {
+			header.Set(echo.HeaderContentLength, strconv.FormatInt(attachment.Size, 10))
+			header.Set(echo.HeaderContentDisposition, mime.FormatMediaType(disposition,
+				map[string]string{"filename": attachment.Filename}))
+		}
+		header.Set(echo.HeaderXContentTypeOptions, "nosniff")
+		return cntx.Stream(http.StatusOK, contentType, body)
+	}
+}
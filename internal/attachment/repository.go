@@ -0,0 +1,13 @@
+package attachment
+
+import (
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+)
+
+type AttachmentRepository interface {
+	Insert(attachment *models.Attachment) error
+	SelectByID(attachmentID uint64) (*models.Attachment, error)
+	SelectAllByPosts(postIDs []uint64) ([]*models.Attachment, error)
+	SelectOrphanedKeys(limit uint64) ([]string, error)
+	DeleteOrphanedKeys(keys []string) error
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/attachment"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/lib/pq"
+)
+
+type AttachmentPgRepository struct {
+	dbConn *sql.DB
+}
+
+func NewAttachmentPgRepository(conn *sql.DB) attachment.AttachmentRepository {
+	return &AttachmentPgRepository{
+		dbConn: conn,
+	}
+}
+
+func (ar *AttachmentPgRepository) Insert(attachment *models.Attachment) error {
+	tx, err := ar.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(
+		`INSERT INTO attachments(post, filename, content_type, size, storage_key, thumbnail_key)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created`,
+		attachment.Post, attachment.Filename, attachment.ContentType, attachment.Size,
+		attachment.StorageKey, attachment.ThumbnailKey)
+
+	err = row.Scan(&attachment.ID, &attachment.Created)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (ar *AttachmentPgRepository) SelectByID(attachmentID uint64) (*models.Attachment, error) {
+	attachment := &models.Attachment{}
+
+	row := ar.dbConn.QueryRow(
+		`SELECT id, post, filename, content_type, size, storage_key, thumbnail_key, created
+		FROM attachments
+		WHERE id=$1`,
+		attachmentID)
+
+	err := row.Scan(&attachment.ID, &attachment.Post, &attachment.Filename, &attachment.ContentType,
+		&attachment.Size, &attachment.StorageKey, &attachment.ThumbnailKey, &attachment.Created)
+	if err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+func (ar *AttachmentPgRepository) SelectAllByPosts(postIDs []uint64) ([]*models.Attachment, error) {
+	ids := make([]int64, 0, len(postIDs))
+	for _, postID := range postIDs {
+		ids = append(ids, int64(postID))
+	}
+
+	rows, err := ar.dbConn.Query(
+		`SELECT id, post, filename, content_type, size, storage_key, thumbnail_key, created
+		FROM attachments
+		WHERE post = ANY($1)
+		ORDER BY post, id`,
+		pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []*models.Attachment
+	for rows.Next() {
+		attachment := &models.Attachment{}
+		err := rows.Scan(&attachment.ID, &attachment.Post, &attachment.Filename, &attachment.ContentType,
+			&attachment.Size, &attachment.StorageKey, &attachment.ThumbnailKey, &attachment.Created)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+func (ar *AttachmentPgRepository) SelectOrphanedKeys(limit uint64) ([]string, error) {
+	rows, err := ar.dbConn.Query(
+		`SELECT key
+		FROM orphaned_files
+		ORDER BY created
+		LIMIT $1`,
+		limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (ar *AttachmentPgRepository) DeleteOrphanedKeys(keys []string) error {
+	tx, err := ar.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`DELETE FROM orphaned_files
+		WHERE key = ANY($1)`,
+		pq.Array(keys))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
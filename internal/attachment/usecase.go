@@ -0,0 +1,19 @@
+package attachment
+
+import (
+	"io"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/post"
+)
+
+type AttachmentUsecase interface {
+	Create(attachment *models.Attachment, body io.ReadSeeker) *errors.Error
+	GetByID(attachmentID uint64) (*models.Attachment, *errors.Error)
+	Open(attachment *models.Attachment, thumbnail bool) (io.ReadCloser, *errors.Error)
+	FillPosts(posts []*models.Post) *errors.Error
+	WithAttachments(send post.PostSender) (post.PostSender, func() *errors.Error)
+	MaxSize() int64
+	SweepOrphaned() *errors.Error
+}
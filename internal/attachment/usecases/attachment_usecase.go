@@ -0,0 +1,265 @@
+package usecases
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/attachment"
+	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/post"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/storage"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/thumbnail"
+	"github.com/sirupsen/logrus"
+)
+
+// Streamed posts are sent in batches, so attachments of a batch are selected by one query
+const streamBatchSize = 64
+
+// http.DetectContentType considers at most the first 512 bytes
+const sniffLen = 512
+
+// Files of deleted attachments are removed from storage in batches of this size
+const sweepBatchSize = 100
+
+type AttachmentUsecase struct {
+	attachmentRepo attachment.AttachmentRepository
+	storage        storage.Storage
+	maxSize        int64
+	allowedTypes   map[string]bool
+	thumbnailSize  int
+}
+
+func NewAttachmentUsecase(repo attachment.AttachmentRepository, storage storage.Storage,
+	maxSize int64, allowedTypes []string, thumbnailSize int) attachment.AttachmentUsecase {
+	types := map[string]bool{}
+	for _, contentType := range allowedTypes {
+		types[strings.ToLower(contentType)] = true
+	}
+
+	return &AttachmentUsecase{
+		attachmentRepo: repo,
+		storage:        storage,
+		maxSize:        maxSize,
+		allowedTypes:   types,
+		thumbnailSize:  thumbnailSize,
+	}
+}
+
+func (au *AttachmentUsecase) MaxSize() int64 {
+	return au.maxSize
+}
+
+func (au *AttachmentUsecase) Create(attachment *models.Attachment, body io.ReadSeeker) *errors.Error {
+	if au.maxSize != 0 && attachment.Size > au.maxSize {
+		return errors.BuildByMsg(CodeAttachmentIsTooLarge, au.maxSize)
+	}
+
+	// Type declared by client isn't trusted, it is detected by content
+	contentType, err := detectContentType(body)
+	if err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if !au.allowedTypes[mediaType] {
+		return errors.BuildByMsg(CodeWrongAttachmentType, mediaType)
+	}
+	attachment.ContentType = contentType
+
+	key, err := newStorageKey(attachment.Post)
+	if err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	if err := au.storage.Put(key, body, attachment.Size, contentType); err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	attachment.StorageKey = key
+
+	// Attachment is kept without thumbnail if image can't be scaled
+	if au.thumbnailSize != 0 && strings.HasPrefix(mediaType, "image/") {
+		if err := au.putThumbnail(attachment, body); err != nil {
+			logrus.Warn(fmt.Sprintf("thumbnail of %s: %s", key, err))
+		}
+	}
+
+	if err := au.attachmentRepo.Insert(attachment); err != nil {
+		au.deleteFiles(attachment)
+		return errors.New(CodeInternalError, err)
+	}
+	attachment.SetURLs()
+	return nil
+}
+
+func (au *AttachmentUsecase) putThumbnail(attachment *models.Attachment, body io.ReadSeeker) error {
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	data, contentType, err := thumbnail.Make(body, au.thumbnailSize)
+	if err != nil {
+		return err
+	}
+
+	key := attachment.StorageKey + "-thumbnail"
+	if err := au.storage.Put(key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return err
+	}
+	attachment.ThumbnailKey = key
+	return nil
+}
+
+func (au *AttachmentUsecase) deleteFiles(attachment *models.Attachment) {
+	for _, key := range []string{attachment.StorageKey, attachment.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := au.storage.Delete(key); err != nil {
+			logrus.Error(err)
+		}
+	}
+}
+
+// SweepOrphaned removes files left by attachments deleted with their posts or by clearing
+// of database. Keys which storage failed to delete are kept for the next sweep
+func (au *AttachmentUsecase) SweepOrphaned() *errors.Error {
+	keys, err := au.attachmentRepo.SelectOrphanedKeys(sweepBatchSize)
+	if err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+
+	var deleted []string
+	for _, key := range keys {
+		if err := au.storage.Delete(key); err != nil {
+			logrus.Error(err)
+			continue
+		}
+		deleted = append(deleted, key)
+	}
+	if len(deleted) == 0 {
+		return nil
+	}
+
+	if err := au.attachmentRepo.DeleteOrphanedKeys(deleted); err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	logrus.Info("Orphaned attachment files removed: ", len(deleted))
+	return nil
+}
+
+func (au *AttachmentUsecase) GetByID(attachmentID uint64) (*models.Attachment, *errors.Error) {
+	attachment, err := au.attachmentRepo.SelectByID(attachmentID)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, errors.BuildByMsg(CodeAttachmentDoesNotExist, "id", strconv.Itoa(int(attachmentID)))
+	case err != nil:
+		return nil, errors.New(CodeInternalError, err)
+	}
+	attachment.SetURLs()
+	return attachment, nil
+}
+
+func (au *AttachmentUsecase) Open(attachment *models.Attachment, thumbnail bool) (io.ReadCloser, *errors.Error) {
+	key := attachment.StorageKey
+	if thumbnail {
+		if attachment.ThumbnailKey == "" {
+			return nil, errors.BuildByMsg(CodeThumbnailDoesNotExist, attachment.ID)
+		}
+		key = attachment.ThumbnailKey
+	}
+
+	body, err := au.storage.Get(key)
+	switch {
+	case err == storage.ErrNotFound:
+		return nil, errors.BuildByMsg(CodeAttachmentDoesNotExist, "id", strconv.Itoa(int(attachment.ID)))
+	case err != nil:
+		return nil, errors.New(CodeInternalError, err)
+	}
+	return body, nil
+}
+
+func (au *AttachmentUsecase) FillPosts(posts []*models.Post) *errors.Error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	postIDs := make([]uint64, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+	}
+
+	attachments, err := au.attachmentRepo.SelectAllByPosts(postIDs)
+	if err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+
+	byPost := map[uint64][]*models.Attachment{}
+	for _, attachment := range attachments {
+		attachment.SetURLs()
+		byPost[attachment.Post] = append(byPost[attachment.Post], attachment)
+	}
+	for _, post := range posts {
+		post.Attachments = byPost[post.ID]
+	}
+	return nil
+}
+
+// WithAttachments wraps sender of streamed listing, so posts are sent with attachments.
+// Posts are held until batch is full, returned flush sends the rest after listing is over
+func (au *AttachmentUsecase) WithAttachments(send post.PostSender) (post.PostSender, func() *errors.Error) {
+	var batch []*models.Post
+
+	flush := func() *errors.Error {
+		if err := au.FillPosts(batch); err != nil {
+			return err
+		}
+		for _, post := range batch {
+			if err := send(post); err != nil {
+				return errors.New(CodeInternalError, err)
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	batchSend := func(post *models.Post) error {
+		batch = append(batch, post)
+		if len(batch) < streamBatchSize {
+			return nil
+		}
+		if err := flush(); err != nil {
+			return fmt.Errorf("%s", err.Message)
+		}
+		return nil
+	}
+	return batchSend, flush
+}
+
+func detectContentType(body io.ReadSeeker) (string, error) {
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(body, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// newStorageKey groups files by post, random part keeps names of files from being guessed
+func newStorageKey(postID uint64) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("posts/%d/%s", postID, hex.EncodeToString(random)), nil
+}
@@ -14,6 +14,49 @@ const (
 	CodeThreadDoesNotExist
 	CodeParentPostDoesNotExist
 	CodePostDoesNotExist
+	CodeWrongVoice
+	CodeSelfFollowing
+	CodeWrongDigestFrequency
+	CodeWrongNickname
+	CodeNicknameIsReserved
+	CodeNicknameIsForbidden
+	CodeUserIsBanned
+	CodeUserIsMuted
+	CodeSanctionDoesNotExist
+	CodeSanctionIsExpired
+	CodeWrongModerationPattern
+	CodeWrongModerationAction
+	CodeReportAlreadyExists
+	CodeReportDoesNotExist
+	CodeTooManyRequests
+	CodeBatchIsTooLarge
+	CodeIdempotencyKeyIsReused
+	CodeIdempotencyKeyIsInProgress
+	CodePreconditionFailed
+	CodeVersionConflict
+	CodeWrongCursor
+	CodeWrongArchive
+	CodeDatabaseIsNotEmpty
+	CodeAttachmentIsTooLarge
+	CodeWrongAttachmentType
+	CodeAttachmentDoesNotExist
+	CodeThumbnailDoesNotExist
+	CodeWrongPoll
+	CodePollDoesNotExist
+	CodePollIsClosed
+	CodeWrongBallot
+	CodeBallotAlreadyExists
+	CodeWrongDraft
+	CodeDraftDoesNotExist
+	CodeAdminAccessDenied
+	CodeWrongModerationRules
+	CodeWrongReport
 )
 
 const OnPostInsertExceptionMsgConflict = "pq: Can not find parent post into thread"
+const OnArchiveRestoreExceptionMsgNotEmpty = "database is not empty"
+const OnArchiveRestoreExceptionMsgUnknownTable = "unknown table in archive"
+const OnUserUpdateExceptionMsgEmailConflict = `pq: duplicate key value violates unique constraint "users_email_key"`
+const OnUserRenameExceptionMsgNicknameConflict = `pq: duplicate key value violates unique constraint "users_nickname_key"`
+const OnUserDeleteExceptionMsgGhostIsTaken = "nickname of ghost user is taken by another user"
+const OnReportInsertExceptionMsgConflict = `pq: duplicate key value violates unique constraint "post_reports_post_nickname_key"`
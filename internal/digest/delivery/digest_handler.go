@@ -0,0 +1,76 @@
+package delivery
+
+import (
+	"net/http"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/digest"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/mwares"
+	"github.com/OlegGibadulin/tech-db-forum/internal/user"
+	reader "github.com/OlegGibadulin/tech-db-forum/tools/request_reader"
+	"github.com/labstack/echo/v4"
+)
+
+type DigestHandler struct {
+	digestUcase digest.DigestUsecase
+	userUcase   user.UserUsecase
+}
+
+func NewDigestHandler(digestUcase digest.DigestUsecase, userUcase user.UserUsecase) *DigestHandler {
+	return &DigestHandler{
+		digestUcase: digestUcase,
+		userUcase:   userUcase,
+	}
+}
+
+func (dh *DigestHandler) Configure(e *echo.Echo, mw *mwares.MiddlewareManager) {
+	e.GET("/api/user/:nickname/digest", dh.GetDigestSettingsHandler())
+	e.POST("/api/user/:nickname/digest", dh.UpdateDigestSettingsHandler())
+}
+
+func (dh *DigestHandler) GetDigestSettingsHandler() echo.HandlerFunc {
+	return func(cntx echo.Context) error {
+		user, err := dh.userUcase.GetByNickname(cntx.Param("nickname"))
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		settings, err := dh.digestUcase.GetSettings(user.Nickname)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, settings)
+	}
+}
+
+func (dh *DigestHandler) UpdateDigestSettingsHandler() echo.HandlerFunc {
+	type Request struct {
+		Frequency string `json:"frequency" validate:"oneof=never daily weekly"`
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		user, err := dh.userUcase.GetByNickname(cntx.Param("nickname"))
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		settings := &models.DigestSettings{
+			Nickname:  user.Nickname,
+			Frequency: req.Frequency,
+		}
+		if err := dh.digestUcase.UpdateSettings(settings); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, settings)
+	}
+}
@@ -0,0 +1,15 @@
+package digest
+
+import (
+	"time"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+)
+
+type DigestRepository interface {
+	Upsert(settings *models.DigestSettings) error
+	UpdateLastSent(nickname string, sent time.Time, lastPost *models.Post) error
+	SelectByNickname(nickname string) (*models.DigestSettings, error)
+	SelectAllDue(now time.Time) ([]*models.Digest, error)
+	SelectNewPosts(nickname string, since time.Time, sincePost uint64, limit uint64) ([]*models.Post, error)
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/digest"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+)
+
+type DigestPgRepository struct {
+	dbConn *sql.DB
+}
+
+func NewDigestPgRepository(conn *sql.DB) digest.DigestRepository {
+	return &DigestPgRepository{
+		dbConn: conn,
+	}
+}
+
+func (dr *DigestPgRepository) Upsert(settings *models.DigestSettings) error {
+	tx, err := dr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	// Digest period starts over when digests are turned on
+	row := tx.QueryRow(
+		`INSERT INTO digest_settings(nickname, frequency)
+		VALUES ($1, $2)
+		ON CONFLICT (nickname) DO UPDATE
+		SET frequency = EXCLUDED.frequency,
+			last_sent = CASE
+				WHEN digest_settings.frequency = 'never' THEN now()
+				ELSE digest_settings.last_sent
+			END,
+			cursor_published = CASE
+				WHEN digest_settings.frequency = 'never' THEN NULL
+				ELSE digest_settings.cursor_published
+			END,
+			cursor_post = CASE
+				WHEN digest_settings.frequency = 'never' THEN NULL
+				ELSE digest_settings.cursor_post
+			END
+		RETURNING last_sent`,
+		settings.Nickname, settings.Frequency)
+
+	err = row.Scan(&settings.LastSent)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateLastSent starts digest period over, cursor moves to last post if any was sent
+func (dr *DigestPgRepository) UpdateLastSent(nickname string, sent time.Time, lastPost *models.Post) error {
+	tx, err := dr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	if lastPost == nil {
+		_, err = tx.Exec(
+			`UPDATE digest_settings
+			SET last_sent = $2
+			WHERE nickname = $1`,
+			nickname, sent)
+	} else {
+		_, err = tx.Exec(
+			`UPDATE digest_settings
+			SET last_sent = $2, cursor_published = $3, cursor_post = $4
+			WHERE nickname = $1`,
+			nickname, sent, lastPost.Published, lastPost.ID)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (dr *DigestPgRepository) SelectByNickname(nickname string) (*models.DigestSettings, error) {
+	settings := &models.DigestSettings{}
+
+	row := dr.dbConn.QueryRow(
+		`SELECT nickname, frequency, last_sent
+		FROM digest_settings
+		WHERE nickname=$1`,
+		nickname)
+
+	err := row.Scan(&settings.Nickname, &settings.Frequency, &settings.LastSent)
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func (dr *DigestPgRepository) SelectAllDue(now time.Time) ([]*models.Digest, error) {
+	rows, err := dr.dbConn.Query(
+		`SELECT u.nickname, u.fullname, u.email, u.about,
+		COALESCE(d.cursor_published, d.last_sent), COALESCE(d.cursor_post, 0)
+		FROM digest_settings AS d
+		JOIN users AS u ON u.nickname=d.nickname
+		WHERE d.frequency='daily' AND d.last_sent <= $1::timestamptz - interval '1 day'
+		OR d.frequency='weekly' AND d.last_sent <= $1::timestamptz - interval '7 days'`,
+		now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var digests []*models.Digest
+	for rows.Next() {
+		digest := &models.Digest{
+			User: &models.User{},
+		}
+		err := rows.Scan(&digest.User.Nickname, &digest.User.Fullname, &digest.User.Email,
+			&digest.User.About, &digest.Since, &digest.SincePost)
+		if err != nil {
+			return nil, err
+		}
+		digests = append(digests, digest)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return digests, nil
+}
+
+func (dr *DigestPgRepository) SelectNewPosts(nickname string, since time.Time, sincePost uint64,
+	limit uint64) ([]*models.Post, error) {
+	var values []interface{}
+
+	// New posts in followed threads and forums except own ones, held posts count from approval.
+	// Posts of one batch share time, so id is part of cursor
+	selectQuery := `
+		SELECT id, parent, author, message, isedited, forum, thread, created, score,
+		COALESCE(published, created)
+		FROM posts
+		WHERE (COALESCE(published, created), id) > ($1, $3)
+		AND author<>$2
+		AND status='approved'
+		AND (
+			thread IN (SELECT thread FROM thread_follows WHERE nickname=$2)
+			OR forum IN (SELECT forum FROM forum_follows WHERE nickname=$2)
+		)
+		ORDER BY COALESCE(published, created), id`
+	values = append(values, since, nickname, sincePost)
+
+	var pgntQuery string
+	if limit != 0 {
+		pgntQuery = "LIMIT $4"
+		values = append(values, limit)
+	}
+
+	resultQuery := strings.Join([]string{
+		selectQuery,
+		pgntQuery,
+	}, " ")
+
+	rows, err := dr.dbConn.Query(resultQuery, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []*models.Post
+	for rows.Next() {
+		post := &models.Post{}
+		err := rows.Scan(&post.ID, &post.Parent, &post.Author, &post.Message, &post.IsEdited,
+			&post.Forum, &post.Thread, &post.Created, &post.Score, &post.Published)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
@@ -0,0 +1,12 @@
+package digest
+
+import (
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+)
+
+type DigestUsecase interface {
+	UpdateSettings(settings *models.DigestSettings) *errors.Error
+	GetSettings(nickname string) (*models.DigestSettings, *errors.Error)
+	SendDigests() *errors.Error
+}
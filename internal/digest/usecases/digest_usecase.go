@@ -0,0 +1,134 @@
+package usecases
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"time"
+
+	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
+	"github.com/OlegGibadulin/tech-db-forum/internal/digest"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/mailer"
+	"github.com/sirupsen/logrus"
+)
+
+type DigestUsecase struct {
+	digestRepo digest.DigestRepository
+	sender     mailer.Sender
+	baseURL    string
+	maxPosts   uint64
+}
+
+func NewDigestUsecase(repo digest.DigestRepository, sender mailer.Sender,
+	baseURL string, maxPosts uint64) digest.DigestUsecase {
+	return &DigestUsecase{
+		digestRepo: repo,
+		sender:     sender,
+		baseURL:    baseURL,
+		maxPosts:   maxPosts,
+	}
+}
+
+func (du *DigestUsecase) UpdateSettings(settings *models.DigestSettings) *errors.Error {
+	switch settings.Frequency {
+	case models.DigestNever, models.DigestDaily, models.DigestWeekly:
+	default:
+		return errors.BuildByMsg(CodeWrongDigestFrequency, settings.Frequency)
+	}
+
+	if err := du.digestRepo.Upsert(settings); err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	return nil
+}
+
+func (du *DigestUsecase) GetSettings(nickname string) (*models.DigestSettings, *errors.Error) {
+	settings, err := du.digestRepo.SelectByNickname(nickname)
+	switch {
+	case err == sql.ErrNoRows:
+		// Digests are turned off until user sets frequency
+		return &models.DigestSettings{
+			Nickname:  nickname,
+			Frequency: models.DigestNever,
+		}, nil
+	case err != nil:
+		return nil, errors.New(CodeInternalError, err)
+	}
+	return settings, nil
+}
+
+func (du *DigestUsecase) render(digest *models.Digest) (*mailer.Message, error) {
+	data := struct {
+		*models.Digest
+		BaseURL string
+	}{
+		Digest:  digest,
+		BaseURL: du.baseURL,
+	}
+
+	text := &bytes.Buffer{}
+	if err := digestTextTemplate.Execute(text, data); err != nil {
+		return nil, err
+	}
+
+	html := &bytes.Buffer{}
+	if err := digestHTMLTemplate.Execute(html, data); err != nil {
+		return nil, err
+	}
+
+	return &mailer.Message{
+		To:      digest.User.Email,
+		Subject: digestSubject,
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+func (du *DigestUsecase) send(digest *models.Digest, now time.Time) error {
+	posts, err := du.digestRepo.SelectNewPosts(digest.User.Nickname, digest.Since, digest.SincePost, du.maxPosts)
+	if err != nil {
+		return err
+	}
+
+	// Nothing to tell about, digest period just starts over
+	if len(posts) == 0 {
+		return du.digestRepo.UpdateLastSent(digest.User.Nickname, now, nil)
+	}
+
+	digest.Posts = posts
+	msg, err := du.render(digest)
+	if err != nil {
+		return err
+	}
+	if err := du.sender.Send(msg); err != nil {
+		return err
+	}
+
+	// Next digest continues after the last post sent, so posts cut by limit go into it
+	return du.digestRepo.UpdateLastSent(digest.User.Nickname, now, posts[len(posts)-1])
+}
+
+func (du *DigestUsecase) SendDigests() *errors.Error {
+	now := time.Now()
+
+	digests, err := du.digestRepo.SelectAllDue(now)
+	if err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+
+	// Failed digest is left due and will be sent on the next run
+	var failed int
+	for _, digest := range digests {
+		if err := du.send(digest, now); err != nil {
+			logrus.Warn("Digest for ", digest.User.Nickname, ": ", err)
+			failed += 1
+		}
+	}
+
+	if failed != 0 {
+		return errors.New(CodeInternalError, fmt.Errorf("%d of %d digests are not sent", failed, len(digests)))
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package usecases
+
+import (
+	htmlTemplate "html/template"
+	textTemplate "text/template"
+)
+
+const digestSubject = "New posts on forum"
+
+var digestTextTemplate = textTemplate.Must(textTemplate.New("digest.txt").Parse(
+	`Hello, {{.User.Fullname}}!
+
+New posts in threads and forums you follow since {{.Since.Format "02 Jan 2006 15:04"}}:
+{{range .Posts}}
+{{.Author}} in {{.Forum}}, thread {{.Thread}} ({{.Created.Format "02 Jan 2006 15:04"}}):
+{{.Message}}
+{{$.BaseURL}}/api/post/{{.ID}}/details
+{{end}}
+You receive this email because digests are turned on for {{.User.Nickname}}.
+Digest frequency can be changed at {{.BaseURL}}/api/user/{{.User.Nickname}}/digest
+`))
+
+var digestHTMLTemplate = htmlTemplate.Must(htmlTemplate.New("digest.html").Parse(
+	`<!DOCTYPE html>
+<html>
+<body>
+<p>Hello, {{.User.Fullname}}!</p>
+<p>New posts in threads and forums you follow since {{.Since.Format "02 Jan 2006 15:04"}}:</p>
+{{range .Posts}}
+<div>
+<p><b>{{.Author}}</b> in {{.Forum}}, thread {{.Thread}} ({{.Created.Format "02 Jan 2006 15:04"}}):</p>
+<blockquote>{{.Message}}</blockquote>
+<p><a href="{{$.BaseURL}}/api/post/{{.ID}}/details">Open post</a></p>
+</div>
+{{end}}
+<p><small>You receive this email because digests are turned on for {{.User.Nickname}}.
+Digest frequency can be changed at
+<a href="{{.BaseURL}}/api/user/{{.User.Nickname}}/digest">{{.BaseURL}}/api/user/{{.User.Nickname}}/digest</a></small></p>
+</body>
+</html>
+`))
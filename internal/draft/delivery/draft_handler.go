@@ -0,0 +1,179 @@
+package delivery
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/draft"
+	"github.com/OlegGibadulin/tech-db-forum/internal/forum"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/mwares"
+	"github.com/OlegGibadulin/tech-db-forum/internal/thread"
+	"github.com/OlegGibadulin/tech-db-forum/internal/user"
+	reader "github.com/OlegGibadulin/tech-db-forum/tools/request_reader"
+	"github.com/labstack/echo/v4"
+)
+
+type DraftHandler struct {
+	draftUcase  draft.DraftUsecase
+	userUcase   user.UserUsecase
+	forumUcase  forum.ForumUsecase
+	threadUcase thread.ThreadUsecase
+	maxLimit    uint64
+}
+
+func NewDraftHandler(draftUcase draft.DraftUsecase, userUcase user.UserUsecase, forumUcase forum.ForumUsecase,
+	threadUcase thread.ThreadUsecase, maxLimit uint64) *DraftHandler {
+	return &DraftHandler{
+		draftUcase:  draftUcase,
+		userUcase:   userUcase,
+		forumUcase:  forumUcase,
+		threadUcase: threadUcase,
+		maxLimit:    maxLimit,
+	}
+}
+
+func (dh *DraftHandler) Configure(e *echo.Echo, mw *mwares.MiddlewareManager) {
+	e.GET("/api/user/:nickname/drafts", dh.GetDraftsHandler())
+	e.POST("/api/user/:nickname/drafts", dh.CreateDraftHandler(), mw.Idempotency)
+	e.GET("/api/user/:nickname/drafts/:id", dh.GetDraftHandler())
+	e.POST("/api/user/:nickname/drafts/:id", dh.UpdateDraftHandler())
+	e.DELETE("/api/user/:nickname/drafts/:id", dh.DeleteDraftHandler())
+}
+
+func (dh *DraftHandler) CreateDraftHandler() echo.HandlerFunc {
+	type Request struct {
+		models.Draft
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		user, err := dh.userUcase.GetByNickname(cntx.Param("nickname"))
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		req.Author = user.Nickname
+
+		if req.Forum != "" {
+			forum, err := dh.forumUcase.GetBySlug(req.Forum)
+			if err != nil {
+				// logrus.Error(err.Message)
+				return cntx.JSON(err.HTTPCode, err.Response())
+			}
+			req.Forum = forum.Slug
+		}
+
+		if req.Thread != 0 {
+			if _, err := dh.threadUcase.GetByID(req.Thread); err != nil {
+				// logrus.Error(err.Message)
+				return cntx.JSON(err.HTTPCode, err.Response())
+			}
+		}
+
+		if err := dh.draftUcase.Create(&req.Draft); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusCreated, req.Draft)
+	}
+}
+
+func (dh *DraftHandler) UpdateDraftHandler() echo.HandlerFunc {
+	type Request struct {
+		models.Draft
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		user, err := dh.userUcase.GetByNickname(cntx.Param("nickname"))
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		req.Author = user.Nickname
+
+		draftID, _ := strconv.ParseUint(cntx.Param("id"), 10, 64)
+		draft, err := dh.draftUcase.Update(draftID, &req.Draft)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, draft)
+	}
+}
+
+func (dh *DraftHandler) GetDraftHandler() echo.HandlerFunc {
+	return func(cntx echo.Context) error {
+		user, err := dh.userUcase.GetByNickname(cntx.Param("nickname"))
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		draftID, _ := strconv.ParseUint(cntx.Param("id"), 10, 64)
+		draft, err := dh.draftUcase.GetByID(draftID, user.Nickname)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, draft)
+	}
+}
+
+func (dh *DraftHandler) DeleteDraftHandler() echo.HandlerFunc {
+	return func(cntx echo.Context) error {
+		user, err := dh.userUcase.GetByNickname(cntx.Param("nickname"))
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		draftID, _ := strconv.ParseUint(cntx.Param("id"), 10, 64)
+		draft, err := dh.draftUcase.Delete(draftID, user.Nickname)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, draft)
+	}
+}
+
+func (dh *DraftHandler) GetDraftsHandler() echo.HandlerFunc {
+	type Request struct {
+		Since uint64 `query:"since"`
+		models.Pagination
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		user, err := dh.userUcase.GetByNickname(cntx.Param("nickname"))
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		req.Pagination.LimitTo(dh.maxLimit)
+		drafts, err := dh.draftUcase.ListByAuthor(user.Nickname, req.Since, &req.Pagination)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, drafts)
+	}
+}
@@ -0,0 +1,19 @@
+package draft
+
+import (
+	"time"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+)
+
+type DraftRepository interface {
+	Insert(draft *models.Draft) error
+	Update(draft *models.Draft) error
+	UpdateSchedule(draftID uint64, publishAt *time.Time, publishError string) error
+	UpdateAttempts(draftID uint64, attempts int, retryAt time.Time) error
+	UpdateAsPublishing(draftID uint64) error
+	Delete(draftID uint64, nickname string) error
+	SelectByID(draftID uint64, nickname string) (*models.Draft, error)
+	SelectAllByAuthor(nickname string, since uint64, pgnt *models.Pagination) ([]*models.Draft, error)
+	ClaimDue(now time.Time, claimedUntil time.Time, limit int) ([]*models.Draft, error)
+}
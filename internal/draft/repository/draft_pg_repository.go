@@ -0,0 +1,316 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/draft"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+)
+
+type DraftPgRepository struct {
+	dbConn *sql.DB
+}
+
+func NewDraftPgRepository(conn *sql.DB) draft.DraftRepository {
+	return &DraftPgRepository{
+		dbConn: conn,
+	}
+}
+
+const draftColumns = `id, author, forum, thread, parent, title, slug, message,
+	publish_at, publish_error, attempts, publishing, created, updated`
+
+func scanDraft(scanner interface{ Scan(...interface{}) error }) (*models.Draft, error) {
+	draft := &models.Draft{}
+	var forum sql.NullString
+	var thread sql.NullInt64
+	var publishAt sql.NullTime
+
+	err := scanner.Scan(&draft.ID, &draft.Author, &forum, &thread, &draft.Parent, &draft.Title,
+		&draft.Slug, &draft.Message, &publishAt, &draft.PublishError, &draft.Attempts, &draft.Publishing,
+		&draft.Created, &draft.Updated)
+	if err != nil {
+		return nil, err
+	}
+
+	draft.Forum = forum.String
+	draft.Thread = uint64(thread.Int64)
+	if publishAt.Valid {
+		draft.PublishAt = &publishAt.Time
+	}
+	return draft, nil
+}
+
+func (dr *DraftPgRepository) Insert(draft *models.Draft) error {
+	tx, err := dr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	forum := sql.NullString{String: draft.Forum, Valid: draft.Forum != ""}
+	thread := sql.NullInt64{Int64: int64(draft.Thread), Valid: draft.Thread != 0}
+	row := tx.QueryRow(
+		`INSERT INTO drafts(author, forum, thread, parent, title, slug, message, publish_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created, updated`,
+		draft.Author, forum, thread, draft.Parent, draft.Title, draft.Slug, draft.Message, draft.PublishAt)
+
+	err = row.Scan(&draft.ID, &draft.Created, &draft.Updated)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (dr *DraftPgRepository) Update(draft *models.Draft) error {
+	tx, err := dr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(
+		`UPDATE drafts
+		SET parent = $3, title = $4, slug = $5, message = $6, publish_at = $7,
+			publish_error = '', attempts = 0, publishing = FALSE, updated = now()
+		WHERE id = $1 AND author = $2
+		RETURNING updated`,
+		draft.ID, draft.Author, draft.Parent, draft.Title, draft.Slug, draft.Message, draft.PublishAt)
+
+	err = row.Scan(&draft.Updated)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	draft.PublishError = ""
+	draft.Attempts = 0
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (dr *DraftPgRepository) UpdateSchedule(draftID uint64, publishAt *time.Time, publishError string) error {
+	tx, err := dr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`UPDATE drafts
+		SET publish_at = $2, publish_error = $3, attempts = 0, claimed_until = NULL, publishing = FALSE
+		WHERE id = $1`,
+		draftID, publishAt, publishError)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (dr *DraftPgRepository) UpdateAttempts(draftID uint64, attempts int, retryAt time.Time) error {
+	tx, err := dr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`UPDATE drafts
+		SET attempts = $2, claimed_until = $3, publishing = FALSE
+		WHERE id = $1`,
+		draftID, attempts, retryAt)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (dr *DraftPgRepository) UpdateAsPublishing(draftID uint64) error {
+	tx, err := dr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`UPDATE drafts
+		SET publishing = TRUE
+		WHERE id = $1`,
+		draftID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (dr *DraftPgRepository) Delete(draftID uint64, nickname string) error {
+	tx, err := dr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`DELETE FROM drafts
+		WHERE id = $1 AND author = $2`,
+		draftID, nickname)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (dr *DraftPgRepository) SelectByID(draftID uint64, nickname string) (*models.Draft, error) {
+	row := dr.dbConn.QueryRow(
+		`SELECT `+draftColumns+`
+		FROM drafts
+		WHERE id=$1 AND author=$2`,
+		draftID, nickname)
+
+	return scanDraft(row)
+}
+
+func (dr *DraftPgRepository) SelectAllByAuthor(nickname string, since uint64, pgnt *models.Pagination) ([]*models.Draft, error) {
+	var values []interface{}
+
+	selectQuery := `
+		SELECT ` + draftColumns + `
+		FROM drafts
+		WHERE author=$1`
+	values = append(values, nickname)
+
+	var filterQuery string
+	if since != 0 {
+		ind := len(values) + 1
+		if pgnt.Desc {
+			filterQuery = "AND id < $" + strconv.Itoa(ind)
+		} else {
+			filterQuery = "AND id > $" + strconv.Itoa(ind)
+		}
+		values = append(values, since)
+	}
+
+	var sortQuery string
+	if pgnt.Desc {
+		sortQuery = "ORDER BY id DESC"
+	} else {
+		sortQuery = "ORDER BY id"
+	}
+
+	var pgntQuery string
+	if pgnt.Limit != 0 {
+		pgntQuery = "LIMIT $" + strconv.Itoa(len(values)+1)
+		values = append(values, pgnt.Limit)
+	}
+
+	resultQuery := strings.Join([]string{
+		selectQuery,
+		filterQuery,
+		sortQuery,
+		pgntQuery,
+	}, " ")
+
+	rows, err := dr.dbConn.Query(resultQuery, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var drafts []*models.Draft
+	for rows.Next() {
+		draft, err := scanDraft(rows)
+		if err != nil {
+			return nil, err
+		}
+		drafts = append(drafts, draft)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return drafts, nil
+}
+
+// ClaimDue leases drafts whose publish time has come until claimedUntil, so every draft is published
+// by one instance only even if several of them run scheduler. Draft stays scheduled while it is leased,
+// so it is claimed again after lease if instance stopped before publishing it
+func (dr *DraftPgRepository) ClaimDue(now time.Time, claimedUntil time.Time, limit int) ([]*models.Draft, error) {
+	tx, err := dr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(
+		`WITH due AS (
+			SELECT id AS due_id
+			FROM drafts
+			WHERE publish_at <= $1 AND (claimed_until IS NULL OR claimed_until <= $1)
+			ORDER BY publish_at, id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE drafts
+		SET claimed_until = $2
+		FROM due
+		WHERE id = due.due_id
+		RETURNING `+draftColumns,
+		now, claimedUntil, limit)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	var drafts []*models.Draft
+	for rows.Next() {
+		draft, err := scanDraft(rows)
+		if err != nil {
+			rows.Close()
+			tx.Rollback()
+			return nil, err
+		}
+		drafts = append(drafts, draft)
+	}
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	// Rows returned by update have no order, drafts are published in order of their time
+	sort.Slice(drafts, func(i, j int) bool {
+		if drafts[i].PublishAt.Equal(*drafts[j].PublishAt) {
+			return drafts[i].ID < drafts[j].ID
+		}
+		return drafts[i].PublishAt.Before(*drafts[j].PublishAt)
+	})
+	return drafts, nil
+}
@@ -0,0 +1,15 @@
+package draft
+
+import (
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+)
+
+type DraftUsecase interface {
+	Create(draft *models.Draft) *errors.Error
+	Update(draftID uint64, draftData *models.Draft) (*models.Draft, *errors.Error)
+	Delete(draftID uint64, nickname string) (*models.Draft, *errors.Error)
+	GetByID(draftID uint64, nickname string) (*models.Draft, *errors.Error)
+	ListByAuthor(nickname string, since uint64, pgnt *models.Pagination) ([]*models.Draft, *errors.Error)
+	PublishDue() *errors.Error
+}
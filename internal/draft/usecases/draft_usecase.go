@@ -0,0 +1,241 @@
+package usecases
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
+	"github.com/OlegGibadulin/tech-db-forum/internal/draft"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/post"
+	"github.com/OlegGibadulin/tech-db-forum/internal/thread"
+	"github.com/sirupsen/logrus"
+)
+
+type DraftUsecase struct {
+	draftRepo   draft.DraftRepository
+	threadUcase thread.ThreadUsecase
+	postUcase   post.PostUsecase
+	batchSize   int
+}
+
+func NewDraftUsecase(repo draft.DraftRepository, threadUcase thread.ThreadUsecase, postUcase post.PostUsecase,
+	batchSize int) draft.DraftUsecase {
+	return &DraftUsecase{
+		draftRepo:   repo,
+		threadUcase: threadUcase,
+		postUcase:   postUcase,
+		batchSize:   batchSize,
+	}
+}
+
+func (du *DraftUsecase) check(draft *models.Draft) *errors.Error {
+	if (draft.Thread == 0) == (draft.Forum == "") {
+		return errors.BuildByMsg(CodeWrongDraft, "draft must have either thread or forum")
+	}
+	if draft.IsThread() && draft.Parent != 0 {
+		return errors.BuildByMsg(CodeWrongDraft, "draft of thread can't have parent post")
+	}
+	if !draft.IsThread() && (draft.Title != "" || draft.Slug != "") {
+		return errors.BuildByMsg(CodeWrongDraft, "draft of post can't have title or slug")
+	}
+
+	if draft.Parent != 0 {
+		parent, customErr := du.postUcase.GetByID(draft.Parent)
+		if customErr != nil && customErr.Code != CodePostDoesNotExist {
+			return customErr
+		}
+		if customErr != nil || parent.Thread != draft.Thread {
+			return errors.BuildByMsg(CodeParentPostDoesNotExist, "id", draft.Thread)
+		}
+	}
+
+	// Unscheduled draft is saved as it is typed, scheduled one must be ready for publishing
+	if draft.PublishAt != nil {
+		if strings.TrimSpace(draft.Message) == "" {
+			return errors.BuildByMsg(CodeWrongDraft, "message of scheduled draft is empty")
+		}
+		if draft.IsThread() && strings.TrimSpace(draft.Title) == "" {
+			return errors.BuildByMsg(CodeWrongDraft, "title of scheduled thread is empty")
+		}
+	}
+	return nil
+}
+
+func (du *DraftUsecase) Create(draft *models.Draft) *errors.Error {
+	draft.PublishError = ""
+	if customErr := du.check(draft); customErr != nil {
+		return customErr
+	}
+
+	if err := du.draftRepo.Insert(draft); err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	return nil
+}
+
+func (du *DraftUsecase) Update(draftID uint64, draftData *models.Draft) (*models.Draft, *errors.Error) {
+	draft, customErr := du.GetByID(draftID, draftData.Author)
+	if customErr != nil {
+		return nil, customErr
+	}
+
+	// Autosave replaces the whole text of draft, its thread or forum stays the same
+	draft.Parent = draftData.Parent
+	draft.Title = draftData.Title
+	draft.Slug = draftData.Slug
+	draft.Message = draftData.Message
+	draft.PublishAt = draftData.PublishAt
+	draft.PublishError = ""
+	if customErr := du.check(draft); customErr != nil {
+		return nil, customErr
+	}
+
+	err := du.draftRepo.Update(draft)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, errors.BuildByMsg(CodeDraftDoesNotExist, draftID, draftData.Author)
+	case err != nil:
+		return nil, errors.New(CodeInternalError, err)
+	}
+	return draft, nil
+}
+
+func (du *DraftUsecase) Delete(draftID uint64, nickname string) (*models.Draft, *errors.Error) {
+	draft, customErr := du.GetByID(draftID, nickname)
+	if customErr != nil {
+		return nil, customErr
+	}
+
+	if err := du.draftRepo.Delete(draft.ID, draft.Author); err != nil {
+		return nil, errors.New(CodeInternalError, err)
+	}
+	return draft, nil
+}
+
+func (du *DraftUsecase) GetByID(draftID uint64, nickname string) (*models.Draft, *errors.Error) {
+	draft, err := du.draftRepo.SelectByID(draftID, nickname)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, errors.BuildByMsg(CodeDraftDoesNotExist, draftID, nickname)
+	case err != nil:
+		return nil, errors.New(CodeInternalError, err)
+	}
+	return draft, nil
+}
+
+func (du *DraftUsecase) ListByAuthor(nickname string, since uint64, pgnt *models.Pagination) ([]*models.Draft, *errors.Error) {
+	drafts, err := du.draftRepo.SelectAllByAuthor(nickname, since, pgnt)
+	if err != nil {
+		return nil, errors.New(CodeInternalError, err)
+	}
+	if len(drafts) == 0 {
+		return []*models.Draft{}, nil
+	}
+	return drafts, nil
+}
+
+// Lease of claimed drafts outlasts their publishing, failed ones are retried with growing delay
+const publishLease = 10 * time.Minute
+const publishRetryDelay = time.Minute
+const maxPublishAttempts = 5
+
+// PublishDue publishes drafts whose time has come through the same usecases as API does,
+// so sanctions, moderation, notifications and counters apply to them as well
+func (du *DraftUsecase) PublishDue() *errors.Error {
+	now := time.Now()
+	drafts, err := du.draftRepo.ClaimDue(now, now.Add(publishLease), du.batchSize)
+	if err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+
+	var failed int
+	for _, draft := range drafts {
+		if err := du.publishClaimed(draft, now); err != nil {
+			logrus.Warn("Draft ", draft.ID, ": ", err)
+			failed += 1
+		}
+	}
+
+	if failed != 0 {
+		return errors.New(CodeInternalError, fmt.Errorf("%d of %d drafts are not published", failed, len(drafts)))
+	}
+	return nil
+}
+
+// publishClaimed publishes draft and deletes it, draft is marked before its content is created,
+// so publishing interrupted by failed deletion or crash is never repeated
+func (du *DraftUsecase) publishClaimed(draft *models.Draft, now time.Time) error {
+	if draft.Publishing {
+		return du.draftRepo.UpdateSchedule(draft.ID, nil,
+			"publishing was interrupted, check whether it was published before scheduling it again")
+	}
+	if err := du.draftRepo.UpdateAsPublishing(draft.ID); err != nil {
+		return err
+	}
+
+	customErr := du.publish(draft)
+	switch {
+	case customErr == nil:
+		return du.draftRepo.Delete(draft.ID, draft.Author)
+	case customErr.Code == CodeInternalError:
+		// Content is created by the last statement of publishing, so failed one can be retried
+		var err error
+		attempts := draft.Attempts + 1
+		if attempts < maxPublishAttempts {
+			// Draft stays claimed until retry, delay is doubled on every failure
+			err = du.draftRepo.UpdateAttempts(draft.ID, attempts, now.Add(publishRetryDelay<<attempts))
+		} else {
+			err = du.draftRepo.UpdateSchedule(draft.ID, nil,
+				fmt.Sprintf("not published after %d attempts, schedule it again later", attempts))
+		}
+		if err != nil {
+			return fmt.Errorf("%s, then %s", customErr.Message, err)
+		}
+		return fmt.Errorf("%s", customErr.Message)
+	default:
+		// Draft which can't be published is left unscheduled with the reason for its author
+		return du.draftRepo.UpdateSchedule(draft.ID, nil, publishError(customErr))
+	}
+}
+
+func (du *DraftUsecase) publish(draft *models.Draft) *errors.Error {
+	if draft.IsThread() {
+		thread := &models.Thread{
+			Title:   draft.Title,
+			Author:  draft.Author,
+			Forum:   draft.Forum,
+			Message: draft.Message,
+			Slug:    draft.Slug,
+			Created: time.Now(),
+		}
+		customErr := du.threadUcase.Create(thread)
+		if customErr != nil && customErr.Code == CodeThreadAlreadyExists {
+			return errors.BuildByMsg(CodeWrongDraft, "thread with slug "+draft.Slug+" already exists")
+		}
+		return customErr
+	}
+
+	thread, customErr := du.threadUcase.GetByID(draft.Thread)
+	if customErr != nil {
+		return customErr
+	}
+
+	post := &models.Post{
+		Parent:  draft.Parent,
+		Author:  draft.Author,
+		Message: draft.Message,
+	}
+	return du.postUcase.Create([]*models.Post{post}, thread)
+}
+
+func publishError(customErr *errors.Error) string {
+	if customErr.Message != "" {
+		return customErr.Message
+	}
+	return http.StatusText(customErr.HTTPCode)
+}
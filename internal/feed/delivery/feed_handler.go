@@ -0,0 +1,291 @@
+package delivery
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
+	"github.com/OlegGibadulin/tech-db-forum/internal/forum"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/conditional"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/syndication"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/mwares"
+	"github.com/OlegGibadulin/tech-db-forum/internal/post"
+	"github.com/OlegGibadulin/tech-db-forum/internal/thread"
+	"github.com/OlegGibadulin/tech-db-forum/internal/user"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	formatAtom = "atom"
+	formatRSS  = "rss"
+)
+
+type FeedHandler struct {
+	forumUcase  forum.ForumUsecase
+	threadUcase thread.ThreadUsecase
+	postUcase   post.PostUsecase
+	userUcase   user.UserUsecase
+	baseURL     string
+	maxEntries  uint64
+}
+
+func NewFeedHandler(forumUcase forum.ForumUsecase, threadUcase thread.ThreadUsecase,
+	postUcase post.PostUsecase, userUcase user.UserUsecase, baseURL string, maxEntries uint64) *FeedHandler {
+	return &FeedHandler{
+		forumUcase:  forumUcase,
+		threadUcase: threadUcase,
+		postUcase:   postUcase,
+		userUcase:   userUcase,
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		maxEntries:  maxEntries,
+	}
+}
+
+func (fh *FeedHandler) Configure(e *echo.Echo, mw *mwares.MiddlewareManager) {
+	for _, format := range []string{formatAtom, formatRSS} {
+		e.GET("/api/forum/:slug/feed."+format, fh.GetForumFeedHandler(format))
+		e.GET("/api/thread/:slug_or_id/feed."+format, fh.GetThreadFeedHandler(format))
+		e.GET("/api/user/:nickname/feed."+format, fh.GetUserFeedHandler(format))
+	}
+}
+
+func (fh *FeedHandler) GetForumFeedHandler(format string) echo.HandlerFunc {
+	return func(cntx echo.Context) error {
+		forum, err := fh.forumUcase.GetBySlug(cntx.Param("slug"))
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		// Listing is ordered and filtered by visibility, but it doesn't select update time
+		pgnt := &models.Pagination{Limit: fh.maxEntries, Desc: true}
+		listed, err := fh.threadUcase.ListByForum(forum.Slug, time.Time{}, pgnt)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		threads, err := fh.listThreads(listed)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		escapedSlug := url.PathEscape(forum.Slug)
+		feed := &syndication.Feed{
+			ID:       syndication.TagURI(fh.baseURL, "forum:"+strings.ToLower(forum.Slug)),
+			Title:    forum.Title,
+			Subtitle: fmt.Sprintf("New threads of forum %s", forum.Slug),
+			Link:     fh.link("/api/forum/%s/details", escapedSlug),
+			Self:     fh.link("/api/forum/%s/feed.%s", escapedSlug, format),
+		}
+		for _, thread := range threads {
+			feed.Add(fh.threadEntry(thread))
+		}
+		if len(feed.Entries) == 0 {
+			feed.Updated = forum.Updated
+		}
+		return fh.sendFeed(cntx, feed, format)
+	}
+}
+
+func (fh *FeedHandler) GetThreadFeedHandler(format string) echo.HandlerFunc {
+	return func(cntx echo.Context) error {
+		thread, err := fh.threadUcase.GetBySlugOrID(cntx.Param("slug_or_id"))
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		pgnt := &models.Pagination{Limit: fh.maxEntries, Desc: true, Sort: models.Flat}
+		listed, err := fh.postUcase.ListByThread(thread.ID, 0, pgnt)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		posts, err := fh.listPosts(listed)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		feed := &syndication.Feed{
+			ID:       syndication.TagURI(fh.baseURL, fmt.Sprintf("thread:%d", thread.ID)),
+			Title:    thread.Title,
+			Subtitle: fmt.Sprintf("New posts of thread in forum %s", thread.Forum),
+			Link:     fh.link("/api/thread/%d/details", thread.ID),
+			Self:     fh.link("/api/thread/%d/feed.%s", thread.ID, format),
+		}
+		threadTitles := map[uint64]string{thread.ID: thread.Title}
+		for _, post := range posts {
+			feed.Add(fh.postEntry(post, threadTitles))
+		}
+		if len(feed.Entries) == 0 {
+			feed.Updated = thread.Created
+		}
+		return fh.sendFeed(cntx, feed, format)
+	}
+}
+
+func (fh *FeedHandler) GetUserFeedHandler(format string) echo.HandlerFunc {
+	return func(cntx echo.Context) error {
+		user, err := fh.userUcase.GetByNickname(cntx.Param("nickname"))
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		pgnt := &models.Pagination{Limit: fh.maxEntries, Desc: true}
+		listed, err := fh.postUcase.ListByAuthor(user.Nickname, 0, pgnt)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		posts, err := fh.listPosts(listed)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		// Posts of user are spread over threads, titles of all of them are loaded at once
+		var threadIDs []uint64
+		for _, post := range posts {
+			threadIDs = append(threadIDs, post.Thread)
+		}
+		threads, err := fh.threadUcase.ListByIDs(threadIDs)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		threadTitles := map[uint64]string{}
+		for _, thread := range threads {
+			threadTitles[thread.ID] = thread.Title
+		}
+
+		escapedNickname := url.PathEscape(user.Nickname)
+		feed := &syndication.Feed{
+			ID:       syndication.TagURI(fh.baseURL, "user:"+strings.ToLower(user.Nickname)),
+			Title:    fmt.Sprintf("Posts of %s", user.Fullname),
+			Subtitle: user.About,
+			Link:     fh.link("/api/user/%s/profile", escapedNickname),
+			Self:     fh.link("/api/user/%s/feed.%s", escapedNickname, format),
+		}
+		for _, post := range posts {
+			feed.Add(fh.postEntry(post, threadTitles))
+		}
+		if len(feed.Entries) == 0 {
+			feed.Updated = user.Updated
+		}
+		return fh.sendFeed(cntx, feed, format)
+	}
+}
+
+// listThreads reloads listed threads with their update time keeping order of listing
+func (fh *FeedHandler) listThreads(listed []*models.Thread) ([]*models.Thread, *errors.Error) {
+	var threadIDs []uint64
+	for _, thread := range listed {
+		threadIDs = append(threadIDs, thread.ID)
+	}
+	loaded, err := fh.threadUcase.ListByIDs(threadIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	threadsByID := map[uint64]*models.Thread{}
+	for _, thread := range loaded {
+		threadsByID[thread.ID] = thread
+	}
+	threads := make([]*models.Thread, 0, len(listed))
+	for _, thread := range listed {
+		if loadedThread, ok := threadsByID[thread.ID]; ok {
+			threads = append(threads, loadedThread)
+		}
+	}
+	return threads, nil
+}
+
+// listPosts reloads listed posts with their update time keeping order of listing
+func (fh *FeedHandler) listPosts(listed []*models.Post) ([]*models.Post, *errors.Error) {
+	var postIDs []uint64
+	for _, post := range listed {
+		postIDs = append(postIDs, post.ID)
+	}
+	loaded, err := fh.postUcase.ListByIDs(postIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	postsByID := map[uint64]*models.Post{}
+	for _, post := range loaded {
+		postsByID[post.ID] = post
+	}
+	posts := make([]*models.Post, 0, len(listed))
+	for _, post := range listed {
+		if loadedPost, ok := postsByID[post.ID]; ok {
+			posts = append(posts, loadedPost)
+		}
+	}
+	return posts, nil
+}
+
+func (fh *FeedHandler) link(format string, args ...interface{}) string {
+	return fh.baseURL + fmt.Sprintf(format, args...)
+}
+
+func (fh *FeedHandler) threadEntry(thread *models.Thread) *syndication.Entry {
+	return &syndication.Entry{
+		ID:        syndication.TagURI(fh.baseURL, fmt.Sprintf("thread:%d", thread.ID)),
+		Title:     thread.Title,
+		Link:      fh.link("/api/thread/%d/details", thread.ID),
+		Author:    thread.Author,
+		Content:   thread.Message,
+		Published: thread.Created,
+		Updated:   thread.Updated,
+	}
+}
+
+// Update time of post is also moved by votes, so only edited posts are shown as updated
+func (fh *FeedHandler) postEntry(post *models.Post, threadTitles map[uint64]string) *syndication.Entry {
+	entry := &syndication.Entry{
+		ID:        syndication.TagURI(fh.baseURL, fmt.Sprintf("post:%d", post.ID)),
+		Title:     fmt.Sprintf("Post by %s", post.Author),
+		Link:      fh.link("/api/post/%d/details", post.ID),
+		Author:    post.Author,
+		Content:   post.Message,
+		Published: post.Created,
+	}
+	if threadTitle, ok := threadTitles[post.Thread]; ok {
+		entry.Title = fmt.Sprintf("Re: %s", threadTitle)
+	}
+	if post.IsEdited {
+		entry.Updated = post.Updated
+	}
+	return entry
+}
+
+func (fh *FeedHandler) sendFeed(cntx echo.Context, feed *syndication.Feed, format string) error {
+	etag := conditional.ETag(feed.Revision(), feed.Updated)
+	if conditional.IsNotModified(cntx, etag, feed.Updated) {
+		return cntx.NoContent(http.StatusNotModified)
+	}
+
+	var body []byte
+	var err error
+	contentType := syndication.MIMEApplicationAtom
+	if format == formatRSS {
+		contentType = syndication.MIMEApplicationRSS
+		body, err = feed.RSS()
+	} else {
+		body, err = feed.Atom()
+	}
+	if err != nil {
+		customErr := errors.New(CodeInternalError, err)
+		// logrus.Error(customErr.Message)
+		return cntx.JSON(customErr.HTTPCode, customErr.Response())
+	}
+	return cntx.Blob(http.StatusOK, contentType, body)
+}
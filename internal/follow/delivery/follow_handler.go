@@ -0,0 +1,271 @@
+package delivery
+
+import (
+	"net/http"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/attachment"
+	"github.com/OlegGibadulin/tech-db-forum/internal/follow"
+	"github.com/OlegGibadulin/tech-db-forum/internal/forum"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/mwares"
+	"github.com/OlegGibadulin/tech-db-forum/internal/post"
+	"github.com/OlegGibadulin/tech-db-forum/internal/thread"
+	"github.com/OlegGibadulin/tech-db-forum/internal/user"
+	reader "github.com/OlegGibadulin/tech-db-forum/tools/request_reader"
+	"github.com/labstack/echo/v4"
+)
+
+type FollowHandler struct {
+	followUcase     follow.FollowUsecase
+	userUcase       user.UserUsecase
+	threadUcase     thread.ThreadUsecase
+	postUcase       post.PostUsecase
+	forumUcase      forum.ForumUsecase
+	attachmentUcase attachment.AttachmentUsecase
+}
+
+func NewFollowHandler(followUcase follow.FollowUsecase, userUcase user.UserUsecase,
+	threadUcase thread.ThreadUsecase, postUcase post.PostUsecase, forumUcase forum.ForumUsecase,
+	attachmentUcase attachment.AttachmentUsecase) *FollowHandler {
+	return &FollowHandler{
+		followUcase:     followUcase,
+		userUcase:       userUcase,
+		threadUcase:     threadUcase,
+		postUcase:       postUcase,
+		forumUcase:      forumUcase,
+		attachmentUcase: attachmentUcase,
+	}
+}
+
+func (fh *FollowHandler) Configure(e *echo.Echo, mw *mwares.MiddlewareManager) {
+	e.POST("/api/user/:nickname/follow", fh.FollowUserHandler())
+	e.POST("/api/user/:nickname/unfollow", fh.UnfollowUserHandler())
+	e.POST("/api/thread/:slug_or_id/follow", fh.FollowThreadHandler())
+	e.POST("/api/thread/:slug_or_id/unfollow", fh.UnfollowThreadHandler())
+	e.POST("/api/forum/:slug/follow", fh.FollowForumHandler())
+	e.POST("/api/forum/:slug/unfollow", fh.UnfollowForumHandler())
+	e.GET("/api/feed", fh.GetFeedHandler())
+}
+
+type followRequest struct {
+	Nickname string `json:"nickname" validate:"required,gte=3,lte=32"`
+}
+
+func (fh *FollowHandler) FollowUserHandler() echo.HandlerFunc {
+	return func(cntx echo.Context) error {
+		req := &followRequest{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		follower, err := fh.userUcase.GetByNickname(req.Nickname)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		followee, err := fh.userUcase.GetByNickname(cntx.Param("nickname"))
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		if err := fh.followUcase.FollowUser(follower.Nickname, followee.Nickname); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		profile, err := fh.userUcase.GetProfile(followee.Nickname)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, profile)
+	}
+}
+
+func (fh *FollowHandler) UnfollowUserHandler() echo.HandlerFunc {
+	return func(cntx echo.Context) error {
+		req := &followRequest{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		follower, err := fh.userUcase.GetByNickname(req.Nickname)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		followee, err := fh.userUcase.GetByNickname(cntx.Param("nickname"))
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		if err := fh.followUcase.UnfollowUser(follower.Nickname, followee.Nickname); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		profile, err := fh.userUcase.GetProfile(followee.Nickname)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, profile)
+	}
+}
+
+func (fh *FollowHandler) FollowThreadHandler() echo.HandlerFunc {
+	return func(cntx echo.Context) error {
+		req := &followRequest{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		user, err := fh.userUcase.GetByNickname(req.Nickname)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		thread, err := fh.threadUcase.GetBySlugOrID(cntx.Param("slug_or_id"))
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		if err := fh.followUcase.FollowThread(user.Nickname, thread.ID); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, thread)
+	}
+}
+
+func (fh *FollowHandler) UnfollowThreadHandler() echo.HandlerFunc {
+	return func(cntx echo.Context) error {
+		req := &followRequest{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		user, err := fh.userUcase.GetByNickname(req.Nickname)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		thread, err := fh.threadUcase.GetBySlugOrID(cntx.Param("slug_or_id"))
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		if err := fh.followUcase.UnfollowThread(user.Nickname, thread.ID); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, thread)
+	}
+}
+
+func (fh *FollowHandler) FollowForumHandler() echo.HandlerFunc {
+	return func(cntx echo.Context) error {
+		req := &followRequest{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		user, err := fh.userUcase.GetByNickname(req.Nickname)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		forum, err := fh.forumUcase.GetBySlug(cntx.Param("slug"))
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		if err := fh.followUcase.FollowForum(user.Nickname, forum.Slug); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, forum)
+	}
+}
+
+func (fh *FollowHandler) UnfollowForumHandler() echo.HandlerFunc {
+	return func(cntx echo.Context) error {
+		req := &followRequest{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		user, err := fh.userUcase.GetByNickname(req.Nickname)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		forum, err := fh.forumUcase.GetBySlug(cntx.Param("slug"))
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		if err := fh.followUcase.UnfollowForum(user.Nickname, forum.Slug); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, forum)
+	}
+}
+
+func (fh *FollowHandler) GetFeedHandler() echo.HandlerFunc {
+	type Request struct {
+		Nickname string `query:"nickname"`
+		Since    uint64 `query:"since"`
+		Limit    uint64 `query:"limit"`
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		user, err := fh.userUcase.GetByNickname(req.Nickname)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		// Feed is always in reverse chronological order, since is the last seen post id
+		pgnt := &models.Pagination{
+			Limit: req.Limit,
+			Desc:  true,
+		}
+		posts, err := fh.postUcase.ListFeed(user.Nickname, req.Since, pgnt)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		if err := fh.attachmentUcase.FillPosts(posts); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, posts)
+	}
+}
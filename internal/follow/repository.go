@@ -0,0 +1,10 @@
+package follow
+
+type FollowRepository interface {
+	InsertUserFollow(follower string, followee string) error
+	DeleteUserFollow(follower string, followee string) error
+	InsertThreadFollow(nickname string, threadID uint64) error
+	DeleteThreadFollow(nickname string, threadID uint64) error
+	InsertForumFollow(nickname string, forumSlug string) error
+	DeleteForumFollow(nickname string, forumSlug string) error
+}
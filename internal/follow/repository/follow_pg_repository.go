@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/follow"
+)
+
+type FollowPgRepository struct {
+	dbConn *sql.DB
+}
+
+func NewFollowPgRepository(conn *sql.DB) follow.FollowRepository {
+	return &FollowPgRepository{
+		dbConn: conn,
+	}
+}
+
+func (fr *FollowPgRepository) exec(query string, args ...interface{}) error {
+	tx, err := fr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(query, args...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (fr *FollowPgRepository) InsertUserFollow(follower string, followee string) error {
+	return fr.exec(
+		`INSERT INTO user_follows(follower, followee)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`,
+		follower, followee)
+}
+
+func (fr *FollowPgRepository) DeleteUserFollow(follower string, followee string) error {
+	return fr.exec(
+		`DELETE FROM user_follows
+		WHERE follower = $1 AND followee = $2`,
+		follower, followee)
+}
+
+func (fr *FollowPgRepository) InsertThreadFollow(nickname string, threadID uint64) error {
+	return fr.exec(
+		`INSERT INTO thread_follows(nickname, thread)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`,
+		nickname, threadID)
+}
+
+func (fr *FollowPgRepository) DeleteThreadFollow(nickname string, threadID uint64) error {
+	return fr.exec(
+		`DELETE FROM thread_follows
+		WHERE nickname = $1 AND thread = $2`,
+		nickname, threadID)
+}
+
+func (fr *FollowPgRepository) InsertForumFollow(nickname string, forumSlug string) error {
+	return fr.exec(
+		`INSERT INTO forum_follows(nickname, forum)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`,
+		nickname, forumSlug)
+}
+
+func (fr *FollowPgRepository) DeleteForumFollow(nickname string, forumSlug string) error {
+	return fr.exec(
+		`DELETE FROM forum_follows
+		WHERE nickname = $1 AND forum = $2`,
+		nickname, forumSlug)
+}
@@ -0,0 +1,14 @@
+package follow
+
+import (
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+)
+
+type FollowUsecase interface {
+	FollowUser(follower string, followee string) *errors.Error
+	UnfollowUser(follower string, followee string) *errors.Error
+	FollowThread(nickname string, threadID uint64) *errors.Error
+	UnfollowThread(nickname string, threadID uint64) *errors.Error
+	FollowForum(nickname string, forumSlug string) *errors.Error
+	UnfollowForum(nickname string, forumSlug string) *errors.Error
+}
@@ -0,0 +1,66 @@
+package usecases
+
+import (
+	"strings"
+
+	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
+	"github.com/OlegGibadulin/tech-db-forum/internal/follow"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+)
+
+type FollowUsecase struct {
+	followRepo follow.FollowRepository
+}
+
+func NewFollowUsecase(repo follow.FollowRepository) follow.FollowUsecase {
+	return &FollowUsecase{
+		followRepo: repo,
+	}
+}
+
+func (fu *FollowUsecase) FollowUser(follower string, followee string) *errors.Error {
+	// Nicknames are case insensitive
+	if strings.EqualFold(follower, followee) {
+		return errors.BuildByMsg(CodeSelfFollowing, follower)
+	}
+
+	if err := fu.followRepo.InsertUserFollow(follower, followee); err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	return nil
+}
+
+func (fu *FollowUsecase) UnfollowUser(follower string, followee string) *errors.Error {
+	if err := fu.followRepo.DeleteUserFollow(follower, followee); err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	return nil
+}
+
+func (fu *FollowUsecase) FollowThread(nickname string, threadID uint64) *errors.Error {
+	if err := fu.followRepo.InsertThreadFollow(nickname, threadID); err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	return nil
+}
+
+func (fu *FollowUsecase) UnfollowThread(nickname string, threadID uint64) *errors.Error {
+	if err := fu.followRepo.DeleteThreadFollow(nickname, threadID); err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	return nil
+}
+
+func (fu *FollowUsecase) FollowForum(nickname string, forumSlug string) *errors.Error {
+	if err := fu.followRepo.InsertForumFollow(nickname, forumSlug); err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	return nil
+}
+
+func (fu *FollowUsecase) UnfollowForum(nickname string, forumSlug string) *errors.Error {
+	if err := fu.followRepo.DeleteForumFollow(nickname, forumSlug); err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	return nil
+}
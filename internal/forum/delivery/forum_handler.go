@@ -5,8 +5,10 @@ import (
 	"time"
 
 	"github.com/OlegGibadulin/tech-db-forum/internal/forum"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/conditional"
 	"github.com/OlegGibadulin/tech-db-forum/internal/models"
 	"github.com/OlegGibadulin/tech-db-forum/internal/mwares"
+	"github.com/OlegGibadulin/tech-db-forum/internal/poll"
 	"github.com/OlegGibadulin/tech-db-forum/internal/thread"
 	"github.com/OlegGibadulin/tech-db-forum/internal/user"
 	reader "github.com/OlegGibadulin/tech-db-forum/tools/request_reader"
@@ -17,21 +19,23 @@ type ForumHandler struct {
 	forumUcase  forum.ForumUsecase
 	userUcase   user.UserUsecase
 	threadUcase thread.ThreadUsecase
+	pollUcase   poll.PollUsecase
 }
 
 func NewForumHandler(forumUcase forum.ForumUsecase, userUcase user.UserUsecase,
-	threadUcase thread.ThreadUsecase) *ForumHandler {
+	threadUcase thread.ThreadUsecase, pollUcase poll.PollUsecase) *ForumHandler {
 	return &ForumHandler{
 		forumUcase:  forumUcase,
 		userUcase:   userUcase,
 		threadUcase: threadUcase,
+		pollUcase:   pollUcase,
 	}
 }
 
 func (fh *ForumHandler) Configure(e *echo.Echo, mw *mwares.MiddlewareManager) {
-	e.POST("/api/forum/create", fh.CreateForumHandler())
+	e.POST("/api/forum/create", fh.CreateForumHandler(), mw.Idempotency)
 	e.GET("/api/forum/:slug/details", fh.GetForumDetailesHandler())
-	e.POST("/api/forum/:forum/create", fh.CreateThreadHandler())
+	e.POST("/api/forum/:forum/create", fh.CreateThreadHandler(), mw.Idempotency)
 	e.GET("/api/forum/:slug/threads", fh.GetThreadsByForumHandler())
 	e.GET("/api/forum/:slug/users", fh.GetUsersByForumHandler())
 }
@@ -71,6 +75,11 @@ func (fh *ForumHandler) GetForumDetailesHandler() echo.HandlerFunc {
 			// logrus.Error(err.Message)
 			return cntx.JSON(err.HTTPCode, err.Response())
 		}
+
+		etag := conditional.ETag(forum.Version, forum.Updated)
+		if conditional.IsNotModified(cntx, etag, forum.Updated) {
+			return cntx.NoContent(http.StatusNotModified)
+		}
 		return cntx.JSON(http.StatusOK, forum)
 	}
 }
@@ -101,6 +110,14 @@ func (fh *ForumHandler) CreateThreadHandler() echo.HandlerFunc {
 		}
 		req.Author = user.Nickname
 
+		// Poll is inserted in the same transaction as thread, so thread isn't left without requested poll
+		if req.Poll != nil {
+			if err := fh.pollUcase.Check(req.Poll); err != nil {
+				// logrus.Error(err.Message)
+				return cntx.JSON(err.HTTPCode, err.Response())
+			}
+		}
+
 		if err := fh.threadUcase.Create(&req.Thread); err != nil {
 			// logrus.Error(err.Message)
 			return cntx.JSON(err.HTTPCode, err.Response())
@@ -111,7 +128,8 @@ func (fh *ForumHandler) CreateThreadHandler() echo.HandlerFunc {
 
 func (fh *ForumHandler) GetThreadsByForumHandler() echo.HandlerFunc {
 	type Request struct {
-		Since time.Time `query:"since"`
+		Since  time.Time `query:"since"`
+		Format string    `query:"format"`
 		models.Pagination
 	}
 
@@ -133,6 +151,13 @@ func (fh *ForumHandler) GetThreadsByForumHandler() echo.HandlerFunc {
 			// logrus.Error(err.Message)
 			return cntx.JSON(err.HTTPCode, err.Response())
 		}
+
+		if req.Format == models.FormatHTML {
+			if err := fh.threadUcase.RenderHTML(threads); err != nil {
+				// logrus.Error(err.Message)
+				return cntx.JSON(err.HTTPCode, err.Response())
+			}
+		}
 		return cntx.JSON(http.StatusOK, threads)
 	}
 }
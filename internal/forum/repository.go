@@ -6,4 +6,6 @@ type ForumRepository interface {
 	Insert(forum *models.Forum) error
 	SelectBySlug(slug string) (*models.Forum, error)
 	SelectByPostID(postID uint64) (*models.Forum, error)
+	SelectAllBySlugs(slugs []string) ([]*models.Forum, error)
+	SelectAllByUser(nickname string, since string, pgnt *models.Pagination) ([]*models.Forum, error)
 }
@@ -3,9 +3,12 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"strconv"
+	"strings"
 
 	"github.com/OlegGibadulin/tech-db-forum/internal/forum"
 	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/lib/pq"
 )
 
 type ForumPgRepository struct {
@@ -46,12 +49,13 @@ func (fr *ForumPgRepository) SelectBySlug(slug string) (*models.Forum, error) {
 	forum := &models.Forum{}
 
 	row := fr.dbConn.QueryRow(
-		`SELECT title, author, slug, posts, threads
+		`SELECT title, author, slug, posts, threads, version, updated
 		FROM forums
 		WHERE slug=$1`,
 		slug)
 
-	err := row.Scan(&forum.Title, &forum.User, &forum.Slug, &forum.Posts, &forum.Threads)
+	err := row.Scan(&forum.Title, &forum.User, &forum.Slug, &forum.Posts, &forum.Threads,
+		&forum.Version, &forum.Updated)
 	if err != nil {
 		return nil, err
 	}
@@ -74,3 +78,91 @@ func (fr *ForumPgRepository) SelectByPostID(postID uint64) (*models.Forum, error
 	}
 	return forum, nil
 }
+
+func (fr *ForumPgRepository) SelectAllBySlugs(slugs []string) ([]*models.Forum, error) {
+	rows, err := fr.dbConn.Query(
+		`SELECT title, author, slug, posts, threads, version, updated
+		FROM forums
+		WHERE slug = ANY($1::citext[])`,
+		pq.Array(slugs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var forums []*models.Forum
+	for rows.Next() {
+		forum := &models.Forum{}
+		err := rows.Scan(&forum.Title, &forum.User, &forum.Slug, &forum.Posts, &forum.Threads,
+			&forum.Version, &forum.Updated)
+		if err != nil {
+			return nil, err
+		}
+		forums = append(forums, forum)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return forums, nil
+}
+
+func (fr *ForumPgRepository) SelectAllByUser(nickname string, since string, pgnt *models.Pagination) ([]*models.Forum, error) {
+	var values []interface{}
+
+	selectQuery := `
+		SELECT f.title, f.author, f.slug, f.posts, f.threads
+		FROM forum_user AS fu
+		JOIN forums AS f ON f.slug=fu.forum AND fu.nickname=$1`
+	values = append(values, nickname)
+
+	var sortQuery string
+	if pgnt.Desc {
+		sortQuery = "ORDER BY f.slug DESC"
+	} else {
+		sortQuery = "ORDER BY f.slug"
+	}
+
+	var pgntQuery string
+	if pgnt.Limit != 0 {
+		pgntQuery = "LIMIT $2"
+		values = append(values, pgnt.Limit)
+	}
+
+	var filterQuery string
+	if since != "" {
+		ind := len(values) + 1
+		if pgnt.Desc {
+			filterQuery = "AND f.slug < $" + strconv.Itoa(ind)
+		} else {
+			filterQuery = "AND f.slug > $" + strconv.Itoa(ind)
+		}
+		values = append(values, since)
+	}
+
+	resultQuery := strings.Join([]string{
+		selectQuery,
+		filterQuery,
+		sortQuery,
+		pgntQuery,
+	}, " ")
+
+	rows, err := fr.dbConn.Query(resultQuery, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var forums []*models.Forum
+	for rows.Next() {
+		forum := &models.Forum{}
+		err := rows.Scan(&forum.Title, &forum.User, &forum.Slug, &forum.Posts, &forum.Threads)
+		if err != nil {
+			return nil, err
+		}
+		forums = append(forums, forum)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return forums, nil
+}
@@ -9,4 +9,6 @@ type ForumUsecase interface {
 	Create(forum *models.Forum) *errors.Error
 	GetBySlug(slug string) (*models.Forum, *errors.Error)
 	GetByPostID(postID uint64) (*models.Forum, *errors.Error)
+	ListBySlugs(slugs []string) ([]*models.Forum, *errors.Error)
+	ListByUser(nickname string, since string, pgnt *models.Pagination) ([]*models.Forum, *errors.Error)
 }
@@ -56,3 +56,28 @@ func (fu *ForumUsecase) GetByPostID(postID uint64) (*models.Forum, *errors.Error
 	}
 	return forum, nil
 }
+
+func (fu *ForumUsecase) ListBySlugs(slugs []string) ([]*models.Forum, *errors.Error) {
+	if len(slugs) == 0 {
+		return []*models.Forum{}, nil
+	}
+	forums, err := fu.forumRepo.SelectAllBySlugs(slugs)
+	if err != nil {
+		return nil, errors.New(CodeInternalError, err)
+	}
+	if len(forums) == 0 {
+		return []*models.Forum{}, nil
+	}
+	return forums, nil
+}
+
+func (fu *ForumUsecase) ListByUser(nickname string, since string, pgnt *models.Pagination) ([]*models.Forum, *errors.Error) {
+	forums, err := fu.forumRepo.SelectAllByUser(nickname, since, pgnt)
+	if err != nil {
+		return nil, errors.New(CodeInternalError, err)
+	}
+	if len(forums) == 0 {
+		return []*models.Forum{}, nil
+	}
+	return forums, nil
+}
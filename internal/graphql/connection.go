@@ -0,0 +1,354 @@
+package graphql
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+)
+
+const defaultPageSize = 20
+const maxPageSize = 100
+
+type connectionArgs struct {
+	First *int32
+	After *string
+	Desc  *bool
+}
+
+// pagination requests one extra row to know if there is next page
+func (ca connectionArgs) pagination() (*models.Pagination, int) {
+	first := defaultPageSize
+	if ca.First != nil && *ca.First > 0 {
+		first = int(*ca.First)
+	}
+	if first > maxPageSize {
+		first = maxPageSize
+	}
+
+	pgnt := &models.Pagination{
+		Limit: uint64(first + 1),
+		Desc:  ca.Desc != nil && *ca.Desc,
+	}
+	return pgnt, first
+}
+
+// Cursors are opaque for clients, inside they are since values of REST listings
+func encodeCursor(value string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(value))
+}
+
+func decodeCursor(cursor *string) (string, error) {
+	if cursor == nil {
+		return "", nil
+	}
+	value, err := base64.RawURLEncoding.DecodeString(*cursor)
+	if err != nil {
+		return "", newResolverError(errors.BuildByMsg(CodeWrongCursor, *cursor))
+	}
+	return string(value), nil
+}
+
+func decodeIDCursor(cursor *string) (uint64, error) {
+	value, err := decodeCursor(cursor)
+	if err != nil || value == "" {
+		return 0, err
+	}
+	id, parseErr := strconv.ParseUint(value, 10, 64)
+	if parseErr != nil {
+		return 0, newResolverError(errors.BuildByMsg(CodeWrongCursor, *cursor))
+	}
+	return id, nil
+}
+
+func encodeThreadCursor(thread *models.Thread) string {
+	return encodeCursor(thread.Created.Format(time.RFC3339Nano) + " " + idKey(thread.ID))
+}
+
+// Threads are listed since creation time inclusively, so thread id is kept to skip already seen threads
+func decodeThreadCursor(cursor *string) (time.Time, uint64, error) {
+	value, err := decodeCursor(cursor)
+	if err != nil || value == "" {
+		return time.Time{}, 0, err
+	}
+
+	parts := strings.Split(value, " ")
+	if len(parts) != 2 {
+		return time.Time{}, 0, newResolverError(errors.BuildByMsg(CodeWrongCursor, *cursor))
+	}
+	created, timeErr := time.Parse(time.RFC3339Nano, parts[0])
+	threadID, idErr := strconv.ParseUint(parts[1], 10, 64)
+	if timeErr != nil || idErr != nil {
+		return time.Time{}, 0, newResolverError(errors.BuildByMsg(CodeWrongCursor, *cursor))
+	}
+	return created, threadID, nil
+}
+
+type pageInfoResolver struct {
+	hasNextPage bool
+	endCursor   *string
+}
+
+func (pir *pageInfoResolver) HasNextPage() bool {
+	return pir.hasNextPage
+}
+
+func (pir *pageInfoResolver) EndCursor() *string {
+	return pir.endCursor
+}
+
+func newPageInfo(hasNextPage bool, cursors []string) *pageInfoResolver {
+	pageInfo := &pageInfoResolver{
+		hasNextPage: hasNextPage,
+	}
+	if len(cursors) != 0 {
+		pageInfo.endCursor = &cursors[len(cursors)-1]
+	}
+	return pageInfo
+}
+
+type userEdgeResolver struct {
+	cursor string
+	node   *userResolver
+}
+
+func (uer *userEdgeResolver) Cursor() string {
+	return uer.cursor
+}
+
+func (uer *userEdgeResolver) Node() *userResolver {
+	return uer.node
+}
+
+type userConnectionResolver struct {
+	edges    []*userEdgeResolver
+	pageInfo *pageInfoResolver
+}
+
+func (ucr *userConnectionResolver) Edges() []*userEdgeResolver {
+	return ucr.edges
+}
+
+func (ucr *userConnectionResolver) PageInfo() *pageInfoResolver {
+	return ucr.pageInfo
+}
+
+func (r *Resolver) newUserConnection(users []*models.User, first int) *userConnectionResolver {
+	hasNextPage := len(users) > first
+	if hasNextPage {
+		users = users[:first]
+	}
+
+	var cursors []string
+	edges := make([]*userEdgeResolver, 0, len(users))
+	for _, user := range users {
+		cursor := encodeCursor(user.Nickname)
+		cursors = append(cursors, cursor)
+		edges = append(edges, &userEdgeResolver{cursor, &userResolver{r, user}})
+	}
+	return &userConnectionResolver{edges, newPageInfo(hasNextPage, cursors)}
+}
+
+type forumEdgeResolver struct {
+	cursor string
+	node   *forumResolver
+}
+
+func (fer *forumEdgeResolver) Cursor() string {
+	return fer.cursor
+}
+
+func (fer *forumEdgeResolver) Node() *forumResolver {
+	return fer.node
+}
+
+type forumConnectionResolver struct {
+	edges    []*forumEdgeResolver
+	pageInfo *pageInfoResolver
+}
+
+func (fcr *forumConnectionResolver) Edges() []*forumEdgeResolver {
+	return fcr.edges
+}
+
+func (fcr *forumConnectionResolver) PageInfo() *pageInfoResolver {
+	return fcr.pageInfo
+}
+
+func (r *Resolver) newForumConnection(forums []*models.Forum, first int) *forumConnectionResolver {
+	hasNextPage := len(forums) > first
+	if hasNextPage {
+		forums = forums[:first]
+	}
+
+	var cursors []string
+	edges := make([]*forumEdgeResolver, 0, len(forums))
+	for _, forum := range forums {
+		cursor := encodeCursor(forum.Slug)
+		cursors = append(cursors, cursor)
+		edges = append(edges, &forumEdgeResolver{cursor, &forumResolver{r, forum}})
+	}
+	return &forumConnectionResolver{edges, newPageInfo(hasNextPage, cursors)}
+}
+
+type threadEdgeResolver struct {
+	cursor string
+	node   *threadResolver
+}
+
+func (ter *threadEdgeResolver) Cursor() string {
+	return ter.cursor
+}
+
+func (ter *threadEdgeResolver) Node() *threadResolver {
+	return ter.node
+}
+
+type threadConnectionResolver struct {
+	edges    []*threadEdgeResolver
+	pageInfo *pageInfoResolver
+}
+
+func (tcr *threadConnectionResolver) Edges() []*threadEdgeResolver {
+	return tcr.edges
+}
+
+func (tcr *threadConnectionResolver) PageInfo() *pageInfoResolver {
+	return tcr.pageInfo
+}
+
+type threadLister func(since time.Time, pgnt *models.Pagination) ([]*models.Thread, *errors.Error)
+
+func (r *Resolver) listThreadConnection(list threadLister, args connectionArgs) (*threadConnectionResolver, error) {
+	pgnt, first := args.pagination()
+	since, lastID, err := decodeThreadCursor(args.After)
+	if err != nil {
+		return nil, err
+	}
+
+	var threads []*models.Thread
+	for {
+		listed, customErr := list(since, pgnt)
+		if customErr != nil {
+			return nil, newResolverError(customErr)
+		}
+
+		// Threads created at the same time as the cursor one are ordered by id
+		skipped := 0
+		for skipped < len(listed) && listed[skipped].Created.Equal(since) &&
+			(pgnt.Desc && listed[skipped].ID >= lastID || !pgnt.Desc && listed[skipped].ID <= lastID) {
+			skipped++
+		}
+		threads = listed[skipped:]
+
+		// Page is refetched only if skipped threads left it incomplete
+		if len(threads) > first || uint64(len(listed)) < pgnt.Limit {
+			break
+		}
+		pgnt.Limit = uint64(first + 1 + skipped)
+	}
+
+	hasNextPage := len(threads) > first
+	if hasNextPage {
+		threads = threads[:first]
+	}
+
+	var cursors []string
+	edges := make([]*threadEdgeResolver, 0, len(threads))
+	for _, thread := range threads {
+		cursor := encodeThreadCursor(thread)
+		cursors = append(cursors, cursor)
+		edges = append(edges, &threadEdgeResolver{cursor, &threadResolver{r, thread}})
+	}
+	return &threadConnectionResolver{edges, newPageInfo(hasNextPage, cursors)}, nil
+}
+
+type postEdgeResolver struct {
+	cursor string
+	node   *postResolver
+}
+
+func (per *postEdgeResolver) Cursor() string {
+	return per.cursor
+}
+
+func (per *postEdgeResolver) Node() *postResolver {
+	return per.node
+}
+
+type postConnectionResolver struct {
+	edges    []*postEdgeResolver
+	pageInfo *pageInfoResolver
+}
+
+func (pcr *postConnectionResolver) Edges() []*postEdgeResolver {
+	return pcr.edges
+}
+
+func (pcr *postConnectionResolver) PageInfo() *pageInfoResolver {
+	return pcr.pageInfo
+}
+
+func (r *Resolver) newPostConnection(posts []*models.Post, first int) *postConnectionResolver {
+	hasNextPage := len(posts) > first
+	if hasNextPage {
+		posts = posts[:first]
+	}
+
+	var cursors []string
+	edges := make([]*postEdgeResolver, 0, len(posts))
+	for _, post := range posts {
+		cursor := encodeCursor(idKey(post.ID))
+		cursors = append(cursors, cursor)
+		edges = append(edges, &postEdgeResolver{cursor, &postResolver{r, post}})
+	}
+	return &postConnectionResolver{edges, newPageInfo(hasNextPage, cursors)}
+}
+
+type voteEdgeResolver struct {
+	cursor string
+	node   *voteResolver
+}
+
+func (ver *voteEdgeResolver) Cursor() string {
+	return ver.cursor
+}
+
+func (ver *voteEdgeResolver) Node() *voteResolver {
+	return ver.node
+}
+
+type voteConnectionResolver struct {
+	edges    []*voteEdgeResolver
+	pageInfo *pageInfoResolver
+}
+
+func (vcr *voteConnectionResolver) Edges() []*voteEdgeResolver {
+	return vcr.edges
+}
+
+func (vcr *voteConnectionResolver) PageInfo() *pageInfoResolver {
+	return vcr.pageInfo
+}
+
+// Votes of thread are paged by voter nickname, votes of user are paged by thread id
+func (r *Resolver) newVoteConnection(votes []*models.Vote, first int,
+	cursorOf func(vote *models.Vote) string) *voteConnectionResolver {
+	hasNextPage := len(votes) > first
+	if hasNextPage {
+		votes = votes[:first]
+	}
+
+	var cursors []string
+	edges := make([]*voteEdgeResolver, 0, len(votes))
+	for _, vote := range votes {
+		cursor := encodeCursor(cursorOf(vote))
+		cursors = append(cursors, cursor)
+		edges = append(edges, &voteEdgeResolver{cursor, &voteResolver{r, vote}})
+	}
+	return &voteConnectionResolver{edges, newPageInfo(hasNextPage, cursors)}
+}
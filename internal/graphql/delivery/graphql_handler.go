@@ -0,0 +1,69 @@
+package delivery
+
+import (
+	"net/http"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+	"github.com/labstack/echo/v4"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/forum"
+	"github.com/OlegGibadulin/tech-db-forum/internal/graphql"
+	"github.com/OlegGibadulin/tech-db-forum/internal/mwares"
+	"github.com/OlegGibadulin/tech-db-forum/internal/post"
+	"github.com/OlegGibadulin/tech-db-forum/internal/thread"
+	"github.com/OlegGibadulin/tech-db-forum/internal/user"
+	reader "github.com/OlegGibadulin/tech-db-forum/tools/request_reader"
+)
+
+type GraphQLHandler struct {
+	resolver *graphql.Resolver
+	schema   *graphqlgo.Schema
+}
+
+func NewGraphQLHandler(userUcase user.UserUsecase, forumUcase forum.ForumUsecase,
+	threadUcase thread.ThreadUsecase, postUcase post.PostUsecase,
+	maxPostsBatch int, maxDepth int, maxParallelism int) *GraphQLHandler {
+	resolver := graphql.NewResolver(userUcase, forumUcase, threadUcase, postUcase, maxPostsBatch)
+
+	var opts []graphqlgo.SchemaOpt
+	// Zero max parallelism leaves default of library, zero capacity would block every resolver
+	if maxParallelism > 0 {
+		opts = append(opts, graphqlgo.MaxParallelism(maxParallelism))
+	}
+	// Zero max depth means that query depth is not limited
+	if maxDepth != 0 {
+		opts = append(opts, graphqlgo.MaxDepth(maxDepth))
+	}
+
+	return &GraphQLHandler{
+		resolver: resolver,
+		schema:   graphqlgo.MustParseSchema(graphql.Schema, resolver, opts...),
+	}
+}
+
+func (gh *GraphQLHandler) Configure(e *echo.Echo, mw *mwares.MiddlewareManager) {
+	e.POST("/api/graphql", gh.QueryHandler())
+}
+
+func (gh *GraphQLHandler) QueryHandler() echo.HandlerFunc {
+	type Request struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		// Loaders cache entities, so every request gets its own
+		ctx := graphql.WithLoaders(cntx.Request().Context(), gh.resolver.NewLoaders())
+
+		// Errors of resolvers are reported inside response like GraphQL spec requires
+		resp := gh.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+		return cntx.JSON(http.StatusOK, resp)
+	}
+}
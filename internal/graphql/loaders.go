@@ -0,0 +1,168 @@
+package graphql
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/dataloader"
+)
+
+// Resolvers of sibling fields are run concurrently, so keys requested during wait are loaded together
+const loaderWait = time.Millisecond
+const loaderMaxBatch = 100
+
+type loadersKey struct{}
+
+// Loaders batch and cache entity lookups of one request to avoid query per list element
+type Loaders struct {
+	users   *dataloader.Loader
+	forums  *dataloader.Loader
+	threads *dataloader.Loader
+	posts   *dataloader.Loader
+}
+
+func (r *Resolver) NewLoaders() *Loaders {
+	return &Loaders{
+		users:   dataloader.NewLoader(r.batchUsers, loaderWait, loaderMaxBatch),
+		forums:  dataloader.NewLoader(r.batchForums, loaderWait, loaderMaxBatch),
+		threads: dataloader.NewLoader(r.batchThreads, loaderWait, loaderMaxBatch),
+		posts:   dataloader.NewLoader(r.batchPosts, loaderWait, loaderMaxBatch),
+	}
+}
+
+func WithLoaders(ctx context.Context, loaders *Loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, loaders)
+}
+
+func loadersFrom(ctx context.Context) *Loaders {
+	return ctx.Value(loadersKey{}).(*Loaders)
+}
+
+// Nicknames and slugs are case insensitive, so they are cached in lower case
+func caseKey(key string) string {
+	return strings.ToLower(key)
+}
+
+func idKey(id uint64) string {
+	return strconv.FormatUint(id, 10)
+}
+
+func (r *Resolver) batchUsers(keys []string) (map[string]interface{}, error) {
+	users, customErr := r.userUcase.ListByNicknames(keys)
+	if customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+
+	values := map[string]interface{}{}
+	for _, user := range users {
+		values[caseKey(user.Nickname)] = user
+	}
+	return values, nil
+}
+
+func (r *Resolver) batchForums(keys []string) (map[string]interface{}, error) {
+	forums, customErr := r.forumUcase.ListBySlugs(keys)
+	if customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+
+	values := map[string]interface{}{}
+	for _, forum := range forums {
+		values[caseKey(forum.Slug)] = forum
+	}
+	return values, nil
+}
+
+func (r *Resolver) batchThreads(keys []string) (map[string]interface{}, error) {
+	var threadIDs []uint64
+	for _, key := range keys {
+		threadID, _ := strconv.ParseUint(key, 10, 64)
+		threadIDs = append(threadIDs, threadID)
+	}
+
+	threads, customErr := r.threadUcase.ListByIDs(threadIDs)
+	if customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+
+	values := map[string]interface{}{}
+	for _, thread := range threads {
+		values[idKey(thread.ID)] = thread
+	}
+	return values, nil
+}
+
+func (r *Resolver) batchPosts(keys []string) (map[string]interface{}, error) {
+	var postIDs []uint64
+	for _, key := range keys {
+		postID, _ := strconv.ParseUint(key, 10, 64)
+		postIDs = append(postIDs, postID)
+	}
+
+	posts, customErr := r.postUcase.ListByIDs(postIDs)
+	if customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+
+	values := map[string]interface{}{}
+	for _, post := range posts {
+		values[idKey(post.ID)] = post
+	}
+	return values, nil
+}
+
+// Only entities with all columns selected may be primed, listings don't select versions
+func (l *Loaders) primeThreads(threads []*models.Thread) {
+	for _, thread := range threads {
+		l.threads.Prime(idKey(thread.ID), thread)
+	}
+}
+
+func (r *Resolver) loadUser(ctx context.Context, nickname string) (*userResolver, error) {
+	value, err := loadersFrom(ctx).users.Load(caseKey(nickname))
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, newResolverError(errors.BuildByMsg(CodeUserDoesNotExist, "nickname", nickname))
+	}
+	return &userResolver{r, value.(*models.User)}, nil
+}
+
+func (r *Resolver) loadForum(ctx context.Context, slug string) (*forumResolver, error) {
+	value, err := loadersFrom(ctx).forums.Load(caseKey(slug))
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, newResolverError(errors.BuildByMsg(CodeForumDoesNotExist, "slug", slug))
+	}
+	return &forumResolver{r, value.(*models.Forum)}, nil
+}
+
+func (r *Resolver) loadThread(ctx context.Context, threadID uint64) (*threadResolver, error) {
+	value, err := loadersFrom(ctx).threads.Load(idKey(threadID))
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, newResolverError(errors.BuildByMsg(CodeThreadDoesNotExist, "id", idKey(threadID)))
+	}
+	return &threadResolver{r, value.(*models.Thread)}, nil
+}
+
+func (r *Resolver) loadPost(ctx context.Context, postID uint64) (*postResolver, error) {
+	value, err := loadersFrom(ctx).posts.Load(idKey(postID))
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, newResolverError(errors.BuildByMsg(CodePostDoesNotExist, "id", idKey(postID)))
+	}
+	return &postResolver{r, value.(*models.Post)}, nil
+}
@@ -0,0 +1,295 @@
+package graphql
+
+import (
+	"context"
+	"time"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+
+	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/uniq"
+)
+
+// Mutations repeat checks of REST handlers, so both APIs behave the same way
+
+type userInput struct {
+	Nickname string
+	Fullname string
+	Email    string
+	About    *string
+}
+
+type userUpdateInput struct {
+	Fullname *string
+	Email    *string
+	About    *string
+	Version  *int32
+}
+
+type forumInput struct {
+	Slug  string
+	Title string
+	User  string
+}
+
+type threadInput struct {
+	Title   string
+	Author  string
+	Message string
+	Slug    *string
+	Created *graphqlgo.Time
+}
+
+type threadUpdateInput struct {
+	Title   *string
+	Message *string
+	Version *int32
+}
+
+type postInput struct {
+	Author  string
+	Message string
+	Parent  *graphqlgo.ID
+}
+
+type postUpdateInput struct {
+	Message *string
+	Version *int32
+}
+
+type voteInput struct {
+	Nickname string
+	Voice    int32
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func versionValue(value *int32) uint64 {
+	if value == nil || *value < 0 {
+		return 0
+	}
+	return uint64(*value)
+}
+
+func (r *Resolver) CreateUser(args struct{ Input userInput }) (*userResolver, error) {
+	user := &models.User{
+		Nickname: args.Input.Nickname,
+		Fullname: args.Input.Fullname,
+		Email:    args.Input.Email,
+		About:    stringValue(args.Input.About),
+	}
+
+	if customErr := r.userUcase.Create(user); customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+	return &userResolver{r, user}, nil
+}
+
+func (r *Resolver) UpdateUser(args struct {
+	Nickname string
+	Input    userUpdateInput
+}) (*userResolver, error) {
+	userData := &models.User{
+		Fullname:    stringValue(args.Input.Fullname),
+		Email:       stringValue(args.Input.Email),
+		About:       stringValue(args.Input.About),
+		EditVersion: versionValue(args.Input.Version),
+	}
+
+	user, customErr := r.userUcase.Update(args.Nickname, userData)
+	if customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+	return &userResolver{r, user}, nil
+}
+
+func (r *Resolver) CreateForum(args struct{ Input forumInput }) (*forumResolver, error) {
+	user, customErr := r.userUcase.GetByNickname(args.Input.User)
+	if customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+
+	forum := &models.Forum{
+		Slug:  args.Input.Slug,
+		Title: args.Input.Title,
+		User:  user.Nickname,
+	}
+
+	if customErr := r.forumUcase.Create(forum); customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+	return &forumResolver{r, forum}, nil
+}
+
+func (r *Resolver) CreateThread(args struct {
+	Forum string
+	Input threadInput
+}) (*threadResolver, error) {
+	forum, customErr := r.forumUcase.GetBySlug(args.Forum)
+	if customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+
+	user, customErr := r.userUcase.GetByNickname(args.Input.Author)
+	if customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+
+	thread := &models.Thread{
+		Title:   args.Input.Title,
+		Author:  user.Nickname,
+		Forum:   forum.Slug,
+		Message: args.Input.Message,
+		Slug:    stringValue(args.Input.Slug),
+		Created: time.Now(),
+	}
+	if args.Input.Created != nil {
+		thread.Created = args.Input.Created.Time
+	}
+
+	if customErr := r.threadUcase.Create(thread); customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+	return &threadResolver{r, thread}, nil
+}
+
+func (r *Resolver) UpdateThread(args struct {
+	SlugOrID string
+	Input    threadUpdateInput
+}) (*threadResolver, error) {
+	threadData := &models.Thread{
+		Title:       stringValue(args.Input.Title),
+		Message:     stringValue(args.Input.Message),
+		EditVersion: versionValue(args.Input.Version),
+	}
+
+	thread, customErr := r.threadUcase.Update(args.SlugOrID, threadData)
+	if customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+	return &threadResolver{r, thread}, nil
+}
+
+func (r *Resolver) VoteThread(args struct {
+	SlugOrID string
+	Input    voteInput
+}) (*threadResolver, error) {
+	user, customErr := r.userUcase.GetByNickname(args.Input.Nickname)
+	if customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+
+	vote := &models.Vote{
+		Nickname: user.Nickname,
+		Voice:    int(args.Input.Voice),
+	}
+
+	thread, customErr := r.threadUcase.Vote(args.SlugOrID, vote)
+	if customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+	return &threadResolver{r, thread}, nil
+}
+
+func (r *Resolver) CreatePosts(ctx context.Context, args struct {
+	Thread string
+	Posts  []postInput
+}) ([]*postResolver, error) {
+	// Zero max batch means that batch size is not limited
+	if r.maxBatch != 0 && len(args.Posts) > r.maxBatch {
+		return nil, newResolverError(errors.BuildByMsg(CodeBatchIsTooLarge, len(args.Posts), r.maxBatch))
+	}
+
+	thread, customErr := r.threadUcase.GetBySlugOrID(args.Thread)
+	if customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+
+	var nicknames []string
+	posts := make([]*models.Post, 0, len(args.Posts))
+	for _, input := range args.Posts {
+		post := &models.Post{
+			Author:  input.Author,
+			Message: input.Message,
+		}
+		if input.Parent != nil {
+			parentID, err := parseID(*input.Parent, CodePostDoesNotExist)
+			if err != nil {
+				return nil, err
+			}
+			post.Parent = parentID
+		}
+		posts = append(posts, post)
+		nicknames = append(nicknames, post.Author)
+	}
+
+	nicknames = uniq.RemoveDuplicates(nicknames)
+	if customErr := r.userUcase.CheckUsersExistence(nicknames); customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+
+	if customErr := r.postUcase.Create(posts, thread); customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+
+	loadersFrom(ctx).primeThreads([]*models.Thread{thread})
+	resolvers := make([]*postResolver, 0, len(posts))
+	for _, post := range posts {
+		resolvers = append(resolvers, &postResolver{r, post})
+	}
+	return resolvers, nil
+}
+
+func (r *Resolver) UpdatePost(args struct {
+	ID    graphqlgo.ID
+	Input postUpdateInput
+}) (*postResolver, error) {
+	postID, err := parseID(args.ID, CodePostDoesNotExist)
+	if err != nil {
+		return nil, err
+	}
+
+	postData := &models.Post{
+		Message:     stringValue(args.Input.Message),
+		EditVersion: versionValue(args.Input.Version),
+	}
+
+	post, customErr := r.postUcase.Update(postID, postData)
+	if customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+	return &postResolver{r, post}, nil
+}
+
+func (r *Resolver) VotePost(args struct {
+	ID    graphqlgo.ID
+	Input voteInput
+}) (*postResolver, error) {
+	postID, err := parseID(args.ID, CodePostDoesNotExist)
+	if err != nil {
+		return nil, err
+	}
+
+	user, customErr := r.userUcase.GetByNickname(args.Input.Nickname)
+	if customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+
+	vote := &models.Vote{
+		Nickname: user.Nickname,
+		Voice:    int(args.Input.Voice),
+	}
+
+	post, customErr := r.postUcase.Vote(postID, vote)
+	if customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+	return &postResolver{r, post}, nil
+}
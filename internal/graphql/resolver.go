@@ -0,0 +1,151 @@
+package graphql
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+
+	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
+	"github.com/OlegGibadulin/tech-db-forum/internal/forum"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/post"
+	"github.com/OlegGibadulin/tech-db-forum/internal/thread"
+	"github.com/OlegGibadulin/tech-db-forum/internal/user"
+)
+
+// Resolver is the root of schema, it is shared by all requests, so request state lives in Loaders
+type Resolver struct {
+	userUcase   user.UserUsecase
+	forumUcase  forum.ForumUsecase
+	threadUcase thread.ThreadUsecase
+	postUcase   post.PostUsecase
+	// maxBatch limits posts created by one mutation like in REST
+	maxBatch int
+}
+
+func NewResolver(userUcase user.UserUsecase, forumUcase forum.ForumUsecase,
+	threadUcase thread.ThreadUsecase, postUcase post.PostUsecase, maxBatch int) *Resolver {
+	return &Resolver{
+		userUcase:   userUcase,
+		forumUcase:  forumUcase,
+		threadUcase: threadUcase,
+		postUcase:   postUcase,
+		maxBatch:    maxBatch,
+	}
+}
+
+// resolverError keeps error code and http status of usecase error in GraphQL error extensions
+type resolverError struct {
+	err *errors.Error
+}
+
+func newResolverError(err *errors.Error) error {
+	return &resolverError{
+		err: err,
+	}
+}
+
+func (re *resolverError) Error() string {
+	if re.err.Message == "" {
+		return http.StatusText(re.err.HTTPCode)
+	}
+	return re.err.Message
+}
+
+func (re *resolverError) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{
+		"code":   re.err.Code,
+		"status": re.err.HTTPCode,
+	}
+	// Conflicts carry existing entity instead of message
+	if re.err.Body != nil {
+		extensions["body"] = re.err.Body
+	}
+	return extensions
+}
+
+func parseID(id graphqlgo.ID, code ErrorCode) (uint64, error) {
+	value, err := strconv.ParseUint(string(id), 10, 64)
+	if err != nil {
+		return 0, newResolverError(errors.BuildByMsg(code, "id", string(id)))
+	}
+	return value, nil
+}
+
+func formatID(id uint64) graphqlgo.ID {
+	return graphqlgo.ID(strconv.FormatUint(id, 10))
+}
+
+func (r *Resolver) User(ctx context.Context, args struct{ Nickname string }) (*userResolver, error) {
+	return r.loadUser(ctx, args.Nickname)
+}
+
+func (r *Resolver) Users(ctx context.Context, args struct{ Nicknames []string }) ([]*userResolver, error) {
+	var keys []string
+	for _, nickname := range args.Nicknames {
+		keys = append(keys, caseKey(nickname))
+	}
+
+	values, err := loadersFrom(ctx).users.LoadMany(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unknown users are returned as nulls on their positions
+	users := make([]*userResolver, len(values))
+	for i, value := range values {
+		if value != nil {
+			users[i] = &userResolver{r, value.(*models.User)}
+		}
+	}
+	return users, nil
+}
+
+func (r *Resolver) Forum(ctx context.Context, args struct{ Slug string }) (*forumResolver, error) {
+	return r.loadForum(ctx, args.Slug)
+}
+
+func (r *Resolver) Thread(ctx context.Context, args struct{ SlugOrID string }) (*threadResolver, error) {
+	thread, customErr := r.threadUcase.GetBySlugOrID(args.SlugOrID)
+	if customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+	loadersFrom(ctx).primeThreads([]*models.Thread{thread})
+	return &threadResolver{r, thread}, nil
+}
+
+func (r *Resolver) Post(ctx context.Context, args struct{ ID graphqlgo.ID }) (*postResolver, error) {
+	postID, err := parseID(args.ID, CodePostDoesNotExist)
+	if err != nil {
+		return nil, err
+	}
+	return r.loadPost(ctx, postID)
+}
+
+func (r *Resolver) Posts(ctx context.Context, args struct{ IDs []graphqlgo.ID }) ([]*postResolver, error) {
+	var keys []string
+	for _, id := range args.IDs {
+		postID, err := parseID(id, CodePostDoesNotExist)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, idKey(postID))
+	}
+
+	values, err := loadersFrom(ctx).posts.LoadMany(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unknown posts are returned as nulls on their positions
+	posts := make([]*postResolver, len(values))
+	for i, value := range values {
+		if value != nil {
+			posts[i] = &postResolver{r, value.(*models.Post)}
+		}
+	}
+	return posts, nil
+}
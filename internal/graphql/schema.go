@@ -0,0 +1,198 @@
+package graphql
+
+// Counters and voices fit into GraphQL Int, ids are passed as ID to keep them opaque for clients
+const Schema = `
+schema {
+	query: Query
+	mutation: Mutation
+}
+
+scalar Time
+
+type Query {
+	user(nickname: String!): User
+	users(nicknames: [String!]!): [User]!
+	forum(slug: String!): Forum
+	thread(slugOrId: String!): Thread
+	post(id: ID!): Post
+	posts(ids: [ID!]!): [Post]!
+}
+
+type Mutation {
+	createUser(input: UserInput!): User!
+	updateUser(nickname: String!, input: UserUpdateInput!): User!
+	createForum(input: ForumInput!): Forum!
+	createThread(forum: String!, input: ThreadInput!): Thread!
+	updateThread(slugOrId: String!, input: ThreadUpdateInput!): Thread!
+	voteThread(slugOrId: String!, input: VoteInput!): Thread!
+	createPosts(thread: String!, posts: [PostInput!]!): [Post!]!
+	updatePost(id: ID!, input: PostUpdateInput!): Post!
+	votePost(id: ID!, input: VoteInput!): Post!
+}
+
+type User {
+	nickname: String!
+	fullname: String!
+	email: String!
+	about: String!
+	version: Int!
+	threads(first: Int, after: String, desc: Boolean): ThreadConnection!
+	posts(first: Int, after: String, desc: Boolean): PostConnection!
+	forums(first: Int, after: String, desc: Boolean): ForumConnection!
+	votes(first: Int, after: String, desc: Boolean): VoteConnection!
+}
+
+type Forum {
+	slug: String!
+	title: String!
+	user: User!
+	postCount: Int!
+	threadCount: Int!
+	threads(first: Int, after: String, desc: Boolean): ThreadConnection!
+	users(first: Int, after: String, desc: Boolean): UserConnection!
+}
+
+type Thread {
+	id: ID!
+	slug: String
+	title: String!
+	message: String!
+	votes: Int!
+	created: Time!
+	version: Int!
+	author: User!
+	forum: Forum!
+	posts(first: Int, after: String, desc: Boolean, sort: PostSort = FLAT): PostConnection!
+	voters(first: Int, after: String, desc: Boolean): VoteConnection!
+}
+
+type Post {
+	id: ID!
+	message: String!
+	isEdited: Boolean!
+	created: Time!
+	score: Int!
+	version: Int!
+	author: User!
+	parent: Post
+	thread: Thread!
+	forum: Forum!
+}
+
+type Vote {
+	voice: Int!
+	user: User!
+	thread: Thread
+	post: Post
+}
+
+enum PostSort {
+	FLAT
+	TREE
+}
+
+type PageInfo {
+	hasNextPage: Boolean!
+	endCursor: String
+}
+
+type UserConnection {
+	edges: [UserEdge!]!
+	pageInfo: PageInfo!
+}
+
+type UserEdge {
+	cursor: String!
+	node: User!
+}
+
+type ForumConnection {
+	edges: [ForumEdge!]!
+	pageInfo: PageInfo!
+}
+
+type ForumEdge {
+	cursor: String!
+	node: Forum!
+}
+
+type ThreadConnection {
+	edges: [ThreadEdge!]!
+	pageInfo: PageInfo!
+}
+
+type ThreadEdge {
+	cursor: String!
+	node: Thread!
+}
+
+type PostConnection {
+	edges: [PostEdge!]!
+	pageInfo: PageInfo!
+}
+
+type PostEdge {
+	cursor: String!
+	node: Post!
+}
+
+type VoteConnection {
+	edges: [VoteEdge!]!
+	pageInfo: PageInfo!
+}
+
+type VoteEdge {
+	cursor: String!
+	node: Vote!
+}
+
+input UserInput {
+	nickname: String!
+	fullname: String!
+	email: String!
+	about: String
+}
+
+input UserUpdateInput {
+	fullname: String
+	email: String
+	about: String
+	version: Int
+}
+
+input ForumInput {
+	slug: String!
+	title: String!
+	user: String!
+}
+
+input ThreadInput {
+	title: String!
+	author: String!
+	message: String!
+	slug: String
+	created: Time
+}
+
+input ThreadUpdateInput {
+	title: String
+	message: String
+	version: Int
+}
+
+input PostInput {
+	author: String!
+	message: String!
+	parent: ID
+}
+
+input PostUpdateInput {
+	message: String
+	version: Int
+}
+
+input VoteInput {
+	nickname: String!
+	voice: Int!
+}
+`
@@ -0,0 +1,318 @@
+package graphql
+
+import (
+	"context"
+	"time"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+)
+
+type userResolver struct {
+	root *Resolver
+	user *models.User
+}
+
+func (ur *userResolver) Nickname() string {
+	return ur.user.Nickname
+}
+
+func (ur *userResolver) Fullname() string {
+	return ur.user.Fullname
+}
+
+func (ur *userResolver) Email() string {
+	return ur.user.Email
+}
+
+func (ur *userResolver) About() string {
+	return ur.user.About
+}
+
+// Listings don't select versions, so version of listed entity is loaded separately
+func (ur *userResolver) Version(ctx context.Context) (int32, error) {
+	if ur.user.EditVersion != 0 {
+		return int32(ur.user.EditVersion), nil
+	}
+	user, err := ur.root.loadUser(ctx, ur.user.Nickname)
+	if err != nil {
+		return 0, err
+	}
+	return int32(user.user.EditVersion), nil
+}
+
+func (ur *userResolver) Threads(args connectionArgs) (*threadConnectionResolver, error) {
+	list := func(since time.Time, pgnt *models.Pagination) ([]*models.Thread, *errors.Error) {
+		return ur.root.threadUcase.ListByAuthor(ur.user.Nickname, since, pgnt)
+	}
+	return ur.root.listThreadConnection(list, args)
+}
+
+func (ur *userResolver) Posts(ctx context.Context, args connectionArgs) (*postConnectionResolver, error) {
+	pgnt, first := args.pagination()
+	since, err := decodeIDCursor(args.After)
+	if err != nil {
+		return nil, err
+	}
+
+	posts, customErr := ur.root.postUcase.ListByAuthor(ur.user.Nickname, since, pgnt)
+	if customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+	return ur.root.newPostConnection(posts, first), nil
+}
+
+func (ur *userResolver) Forums(args connectionArgs) (*forumConnectionResolver, error) {
+	pgnt, first := args.pagination()
+	since, err := decodeCursor(args.After)
+	if err != nil {
+		return nil, err
+	}
+
+	forums, customErr := ur.root.forumUcase.ListByUser(ur.user.Nickname, since, pgnt)
+	if customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+	return ur.root.newForumConnection(forums, first), nil
+}
+
+func (ur *userResolver) Votes(args connectionArgs) (*voteConnectionResolver, error) {
+	pgnt, first := args.pagination()
+	since, err := decodeIDCursor(args.After)
+	if err != nil {
+		return nil, err
+	}
+
+	votes, customErr := ur.root.threadUcase.ListVotesByNickname(ur.user.Nickname, since, pgnt)
+	if customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+	cursorOf := func(vote *models.Vote) string {
+		return idKey(vote.Thread)
+	}
+	return ur.root.newVoteConnection(votes, first, cursorOf), nil
+}
+
+type forumResolver struct {
+	root  *Resolver
+	forum *models.Forum
+}
+
+func (fr *forumResolver) Slug() string {
+	return fr.forum.Slug
+}
+
+func (fr *forumResolver) Title() string {
+	return fr.forum.Title
+}
+
+func (fr *forumResolver) User(ctx context.Context) (*userResolver, error) {
+	return fr.root.loadUser(ctx, fr.forum.User)
+}
+
+func (fr *forumResolver) PostCount() int32 {
+	return int32(fr.forum.Posts)
+}
+
+func (fr *forumResolver) ThreadCount() int32 {
+	return int32(fr.forum.Threads)
+}
+
+func (fr *forumResolver) Threads(args connectionArgs) (*threadConnectionResolver, error) {
+	list := func(since time.Time, pgnt *models.Pagination) ([]*models.Thread, *errors.Error) {
+		return fr.root.threadUcase.ListByForum(fr.forum.Slug, since, pgnt)
+	}
+	return fr.root.listThreadConnection(list, args)
+}
+
+func (fr *forumResolver) Users(args connectionArgs) (*userConnectionResolver, error) {
+	pgnt, first := args.pagination()
+	since, err := decodeCursor(args.After)
+	if err != nil {
+		return nil, err
+	}
+
+	users, customErr := fr.root.userUcase.ListByForum(fr.forum.Slug, since, pgnt)
+	if customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+	return fr.root.newUserConnection(users, first), nil
+}
+
+type threadResolver struct {
+	root   *Resolver
+	thread *models.Thread
+}
+
+func (tr *threadResolver) ID() graphqlgo.ID {
+	return formatID(tr.thread.ID)
+}
+
+func (tr *threadResolver) Slug() *string {
+	if tr.thread.Slug == "" {
+		return nil
+	}
+	return &tr.thread.Slug
+}
+
+func (tr *threadResolver) Title() string {
+	return tr.thread.Title
+}
+
+func (tr *threadResolver) Message() string {
+	return tr.thread.Message
+}
+
+func (tr *threadResolver) Votes() int32 {
+	return int32(tr.thread.Votes)
+}
+
+func (tr *threadResolver) Created() graphqlgo.Time {
+	return graphqlgo.Time{Time: tr.thread.Created}
+}
+
+func (tr *threadResolver) Version(ctx context.Context) (int32, error) {
+	if tr.thread.EditVersion != 0 {
+		return int32(tr.thread.EditVersion), nil
+	}
+	thread, err := tr.root.loadThread(ctx, tr.thread.ID)
+	if err != nil {
+		return 0, err
+	}
+	return int32(thread.thread.EditVersion), nil
+}
+
+func (tr *threadResolver) Author(ctx context.Context) (*userResolver, error) {
+	return tr.root.loadUser(ctx, tr.thread.Author)
+}
+
+func (tr *threadResolver) Forum(ctx context.Context) (*forumResolver, error) {
+	return tr.root.loadForum(ctx, tr.thread.Forum)
+}
+
+func (tr *threadResolver) Posts(ctx context.Context, args struct {
+	First *int32
+	After *string
+	Desc  *bool
+	Sort  string
+}) (*postConnectionResolver, error) {
+	pgnt, first := connectionArgs{args.First, args.After, args.Desc}.pagination()
+	// Parent tree pages count root posts only, so it can't be used for connection
+	if args.Sort == "TREE" {
+		pgnt.Sort = models.Tree
+	} else {
+		pgnt.Sort = models.Flat
+	}
+
+	since, err := decodeIDCursor(args.After)
+	if err != nil {
+		return nil, err
+	}
+
+	posts, customErr := tr.root.postUcase.ListByThread(tr.thread.ID, since, pgnt)
+	if customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+	return tr.root.newPostConnection(posts, first), nil
+}
+
+func (tr *threadResolver) Voters(args connectionArgs) (*voteConnectionResolver, error) {
+	pgnt, first := args.pagination()
+	since, err := decodeCursor(args.After)
+	if err != nil {
+		return nil, err
+	}
+
+	votes, customErr := tr.root.threadUcase.ListVotes(tr.thread.ID, since, pgnt)
+	if customErr != nil {
+		return nil, newResolverError(customErr)
+	}
+	cursorOf := func(vote *models.Vote) string {
+		return vote.Nickname
+	}
+	return tr.root.newVoteConnection(votes, first, cursorOf), nil
+}
+
+type postResolver struct {
+	root *Resolver
+	post *models.Post
+}
+
+func (pr *postResolver) ID() graphqlgo.ID {
+	return formatID(pr.post.ID)
+}
+
+func (pr *postResolver) Message() string {
+	return pr.post.Message
+}
+
+func (pr *postResolver) IsEdited() bool {
+	return pr.post.IsEdited
+}
+
+func (pr *postResolver) Created() graphqlgo.Time {
+	return graphqlgo.Time{Time: pr.post.Created}
+}
+
+func (pr *postResolver) Score() int32 {
+	return int32(pr.post.Score)
+}
+
+func (pr *postResolver) Version(ctx context.Context) (int32, error) {
+	if pr.post.EditVersion != 0 {
+		return int32(pr.post.EditVersion), nil
+	}
+	post, err := pr.root.loadPost(ctx, pr.post.ID)
+	if err != nil {
+		return 0, err
+	}
+	return int32(post.post.EditVersion), nil
+}
+
+func (pr *postResolver) Author(ctx context.Context) (*userResolver, error) {
+	return pr.root.loadUser(ctx, pr.post.Author)
+}
+
+func (pr *postResolver) Parent(ctx context.Context) (*postResolver, error) {
+	if pr.post.Parent == 0 {
+		return nil, nil
+	}
+	return pr.root.loadPost(ctx, pr.post.Parent)
+}
+
+func (pr *postResolver) Thread(ctx context.Context) (*threadResolver, error) {
+	return pr.root.loadThread(ctx, pr.post.Thread)
+}
+
+func (pr *postResolver) Forum(ctx context.Context) (*forumResolver, error) {
+	return pr.root.loadForum(ctx, pr.post.Forum)
+}
+
+type voteResolver struct {
+	root *Resolver
+	vote *models.Vote
+}
+
+func (vr *voteResolver) Voice() int32 {
+	return int32(vr.vote.Voice)
+}
+
+func (vr *voteResolver) User(ctx context.Context) (*userResolver, error) {
+	return vr.root.loadUser(ctx, vr.vote.Nickname)
+}
+
+func (vr *voteResolver) Thread(ctx context.Context) (*threadResolver, error) {
+	if vr.vote.Thread == 0 {
+		return nil, nil
+	}
+	return vr.root.loadThread(ctx, vr.vote.Thread)
+}
+
+func (vr *voteResolver) Post(ctx context.Context) (*postResolver, error) {
+	if vr.vote.Post == 0 {
+		return nil, nil
+	}
+	return vr.root.loadPost(ctx, vr.vote.Post)
+}
@@ -0,0 +1,85 @@
+package conditional
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	headerETag        = "ETag"
+	headerIfNoneMatch = "If-None-Match"
+	headerIfMatch     = "If-Match"
+)
+
+// ETag builds strong entity tag from entity version and time of its last update,
+// so tags stay unique even if versions start over after database reset
+func ETag(version uint64, updated time.Time, extra ...int64) string {
+	parts := []string{
+		strconv.FormatUint(version, 10),
+		strconv.FormatInt(updated.UnixNano(), 36),
+	}
+	for _, part := range extra {
+		parts = append(parts, strconv.FormatInt(part, 10))
+	}
+	return `"` + strings.Join(parts, "-") + `"`
+}
+
+func matchesAny(header string, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNotModified sets validators of response and checks If-None-Match and If-Modified-Since headers,
+// zero modified time means that entity has no Last-Modified validator
+func IsNotModified(cntx echo.Context, etag string, modified time.Time) bool {
+	header := cntx.Response().Header()
+	header.Set(headerETag, etag)
+	if !modified.IsZero() {
+		header.Set(echo.HeaderLastModified, modified.UTC().Format(http.TimeFormat))
+	}
+
+	req := cntx.Request()
+	if ifNoneMatch := req.Header.Get(headerIfNoneMatch); ifNoneMatch != "" {
+		return matchesAny(ifNoneMatch, etag, true)
+	}
+
+	// If-Modified-Since is ignored when If-None-Match is passed
+	ifModifiedSince := req.Header.Get(echo.HeaderIfModifiedSince)
+	if ifModifiedSince == "" || modified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	return !modified.Truncate(time.Second).After(since)
+}
+
+// HasPrecondition reports whether request is conditional update
+func HasPrecondition(cntx echo.Context) bool {
+	return cntx.Request().Header.Get(headerIfMatch) != ""
+}
+
+// MatchesPrecondition checks If-Match header with strong comparison
+func MatchesPrecondition(cntx echo.Context, etag string) bool {
+	ifMatch := cntx.Request().Header.Get(headerIfMatch)
+	if ifMatch == "" {
+		return true
+	}
+	return matchesAny(ifMatch, etag, false)
+}
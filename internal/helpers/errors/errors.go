@@ -120,4 +120,186 @@ var Errors = map[ErrorCode]*Error{
 		HTTPCode: http.StatusNotFound,
 		Message:  "Can't find post with %s %s",
 	},
+	CodeWrongVoice: {
+		Code:     CodeWrongVoice,
+		HTTPCode: http.StatusBadRequest,
+		Message:  "Voice must be -1 or 1, or 0 to retract the vote, got %d",
+	},
+	CodeSelfFollowing: {
+		Code:     CodeSelfFollowing,
+		HTTPCode: http.StatusBadRequest,
+		Message:  "User %s can't follow themselves",
+	},
+	CodeWrongDigestFrequency: {
+		Code:     CodeWrongDigestFrequency,
+		HTTPCode: http.StatusBadRequest,
+		Message:  "Digest frequency must be never, daily or weekly, got %s",
+	},
+	CodeWrongNickname: {
+		Code:     CodeWrongNickname,
+		HTTPCode: http.StatusBadRequest,
+		Message:  "Nickname %s must consist of latin letters, digits, dots and underscores",
+	},
+	CodeNicknameIsReserved: {
+		Code:     CodeNicknameIsReserved,
+		HTTPCode: http.StatusConflict,
+		Message:  "Nickname %s was released recently and is reserved until %s",
+	},
+	CodeNicknameIsForbidden: {
+		Code:     CodeNicknameIsForbidden,
+		HTTPCode: http.StatusConflict,
+		Message:  "Nickname %s is reserved for system use",
+	},
+	CodeUserIsBanned: {
+		Code:     CodeUserIsBanned,
+		HTTPCode: http.StatusForbidden,
+		Message:  "User %s is banned: %s",
+	},
+	CodeUserIsMuted: {
+		Code:     CodeUserIsMuted,
+		HTTPCode: http.StatusForbidden,
+		Message:  "User %s is muted in forum %s: %s",
+	},
+	CodeSanctionDoesNotExist: {
+		Code:     CodeSanctionDoesNotExist,
+		HTTPCode: http.StatusNotFound,
+		Message:  "Can't find sanction with %s %s",
+	},
+	CodeSanctionIsExpired: {
+		Code:     CodeSanctionIsExpired,
+		HTTPCode: http.StatusBadRequest,
+		Message:  "Sanction expiration time %s is in the past",
+	},
+	CodeWrongModerationPattern: {
+		Code:     CodeWrongModerationPattern,
+		HTTPCode: http.StatusBadRequest,
+		Message:  "Pattern %s is not a valid regular expression",
+	},
+	CodeWrongModerationAction: {
+		Code:     CodeWrongModerationAction,
+		HTTPCode: http.StatusBadRequest,
+		Message:  "Moderation action must be approve or reject, got %s",
+	},
+	CodeReportAlreadyExists: {
+		Code:     CodeReportAlreadyExists,
+		HTTPCode: http.StatusConflict,
+	},
+	CodeReportDoesNotExist: {
+		Code:     CodeReportDoesNotExist,
+		HTTPCode: http.StatusNotFound,
+		Message:  "Can't find open reports for post with id %s",
+	},
+	CodeTooManyRequests: {
+		Code:     CodeTooManyRequests,
+		HTTPCode: http.StatusTooManyRequests,
+		Message:  "Rate limit exceeded, retry after %s seconds",
+	},
+	CodeBatchIsTooLarge: {
+		Code:     CodeBatchIsTooLarge,
+		HTTPCode: http.StatusRequestEntityTooLarge,
+		Message:  "Batch of %d posts exceeds the limit of %d posts",
+	},
+	CodeIdempotencyKeyIsReused: {
+		Code:     CodeIdempotencyKeyIsReused,
+		HTTPCode: http.StatusUnprocessableEntity,
+		Message:  "Idempotency key %s was already used with another request",
+	},
+	CodeIdempotencyKeyIsInProgress: {
+		Code:     CodeIdempotencyKeyIsInProgress,
+		HTTPCode: http.StatusConflict,
+		Message:  "Request with idempotency key %s is still in progress",
+	},
+	CodePreconditionFailed: {
+		Code:     CodePreconditionFailed,
+		HTTPCode: http.StatusPreconditionFailed,
+		Message:  "Entity was modified, its current tag is %s",
+	},
+	CodeVersionConflict: {
+		Code:     CodeVersionConflict,
+		HTTPCode: http.StatusConflict,
+	},
+	CodeWrongCursor: {
+		Code:     CodeWrongCursor,
+		HTTPCode: http.StatusBadRequest,
+		Message:  "Cursor %s is malformed",
+	},
+	CodeWrongArchive: {
+		Code:     CodeWrongArchive,
+		HTTPCode: http.StatusBadRequest,
+		Message:  "Archive is malformed: %s",
+	},
+	CodeDatabaseIsNotEmpty: {
+		Code:     CodeDatabaseIsNotEmpty,
+		HTTPCode: http.StatusConflict,
+		Message:  "Database is not empty, archive can be restored only into empty one",
+	},
+	CodeAttachmentIsTooLarge: {
+		Code:     CodeAttachmentIsTooLarge,
+		HTTPCode: http.StatusRequestEntityTooLarge,
+		Message:  "Attachment exceeds the limit of %d bytes",
+	},
+	CodeWrongAttachmentType: {
+		Code:     CodeWrongAttachmentType,
+		HTTPCode: http.StatusUnsupportedMediaType,
+		Message:  "Attachments of type %s are not allowed",
+	},
+	CodeAttachmentDoesNotExist: {
+		Code:     CodeAttachmentDoesNotExist,
+		HTTPCode: http.StatusNotFound,
+		Message:  "Can't find attachment with %s %s",
+	},
+	CodeThumbnailDoesNotExist: {
+		Code:     CodeThumbnailDoesNotExist,
+		HTTPCode: http.StatusNotFound,
+		Message:  "Attachment with id %d has no thumbnail",
+	},
+	CodeWrongPoll: {
+		Code:     CodeWrongPoll,
+		HTTPCode: http.StatusBadRequest,
+		Message:  "Poll is malformed: %s",
+	},
+	CodePollDoesNotExist: {
+		Code:     CodePollDoesNotExist,
+		HTTPCode: http.StatusNotFound,
+		Message:  "Can't find poll in thread with id %d",
+	},
+	CodePollIsClosed: {
+		Code:     CodePollIsClosed,
+		HTTPCode: http.StatusConflict,
+		Message:  "Poll in thread with id %d was closed at %s",
+	},
+	CodeWrongBallot: {
+		Code:     CodeWrongBallot,
+		HTTPCode: http.StatusBadRequest,
+		Message:  "Ballot is malformed: %s",
+	},
+	CodeBallotAlreadyExists: {
+		Code:     CodeBallotAlreadyExists,
+		HTTPCode: http.StatusConflict,
+	},
+	CodeWrongDraft: {
+		Code:     CodeWrongDraft,
+		HTTPCode: http.StatusBadRequest,
+		Message:  "Draft is malformed: %s",
+	},
+	CodeDraftDoesNotExist: {
+		Code:     CodeDraftDoesNotExist,
+		HTTPCode: http.StatusNotFound,
+		Message:  "Can't find draft with id %d of user %s",
+	},
+	CodeAdminAccessDenied: {
+		Code:     CodeAdminAccessDenied,
+		HTTPCode: http.StatusForbidden,
+		Message:  "Admin token is missing or wrong",
+	},
+	CodeWrongModerationRules: {
+		Code:     CodeWrongModerationRules,
+		HTTPCode: http.StatusBadRequest,
+		Message:  "Moderation rules are malformed: %s",
+	},
+	CodeWrongReport: {
+		Code:     CodeWrongReport,
+		HTTPCode: http.StatusBadRequest,
+		Message:  "Report is malformed: %s",
+	},
 }
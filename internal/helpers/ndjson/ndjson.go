@@ -0,0 +1,67 @@
+package ndjson
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const MIMEApplicationNDJSON = "application/x-ndjson"
+
+// Rows are flushed in small groups, flush per row would cost a write call for every post
+const flushEvery = 64
+
+// IsAccepted checks if client asked for newline delimited JSON instead of one array
+func IsAccepted(cntx echo.Context) bool {
+	return strings.Contains(cntx.Request().Header.Get(echo.HeaderAccept), MIMEApplicationNDJSON)
+}
+
+// Writer writes every value as separate JSON line, response status is sent with the first line,
+// so errors before it can still be answered in usual way
+type Writer struct {
+	cntx    echo.Context
+	encoder *json.Encoder
+	written int
+	started bool
+}
+
+func NewWriter(cntx echo.Context) *Writer {
+	return &Writer{
+		cntx:    cntx,
+		encoder: json.NewEncoder(cntx.Response()),
+	}
+}
+
+func (w *Writer) Started() bool {
+	return w.started
+}
+
+func (w *Writer) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+	w.cntx.Response().Header().Set(echo.HeaderContentType, MIMEApplicationNDJSON)
+	w.cntx.Response().WriteHeader(http.StatusOK)
+}
+
+func (w *Writer) Encode(value interface{}) error {
+	w.start()
+	if err := w.encoder.Encode(value); err != nil {
+		return err
+	}
+
+	w.written++
+	if w.written%flushEvery == 0 {
+		w.cntx.Response().Flush()
+	}
+	return nil
+}
+
+// Close sends status of empty listing and the rest of buffered rows
+func (w *Writer) Close() {
+	w.start()
+	w.cntx.Response().Flush()
+}
@@ -0,0 +1,32 @@
+package placeholders
+
+import (
+	"fmt"
+	"strings"
+)
+
+// List builds parenthesized list of count numbered placeholders starting from first one
+func List(first int, count int) string {
+	/*
+		($2, $3, ...)
+	*/
+	var values []string
+	for i := 0; i < count; i++ {
+		values = append(values, fmt.Sprintf("$%d", first+i))
+	}
+	return fmt.Sprintf("(%s)", strings.Join(values, ", "))
+}
+
+// Rows builds VALUES clause of rowsCount rows with valuesCount placeholders each,
+// placeholders are numbered from $1 row by row
+func Rows(rowsCount int, valuesCount int) string {
+	/*
+		VALUES ($1, $2, ...),
+		...
+	*/
+	var rows []string
+	for i := 0; i < rowsCount; i++ {
+		rows = append(rows, List(i*valuesCount+1, valuesCount))
+	}
+	return fmt.Sprintf("VALUES %s", strings.Join(rows, ", "))
+}
@@ -0,0 +1,205 @@
+package syndication
+
+import (
+	"encoding/xml"
+	"hash/fnv"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	MIMEApplicationAtom = "application/atom+xml; charset=utf-8"
+	MIMEApplicationRSS  = "application/rss+xml; charset=utf-8"
+)
+
+// Tag date only makes entry ids unique in time for the authority, it must never change
+const tagDate = "2020"
+
+// Feed is built once and rendered as Atom or RSS 2.0
+type Feed struct {
+	ID       string
+	Title    string
+	Subtitle string
+	Link     string
+	Self     string
+	Updated  time.Time
+	Entries  []*Entry
+}
+
+type Entry struct {
+	ID        string
+	Title     string
+	Link      string
+	Author    string
+	Content   string
+	Published time.Time
+	Updated   time.Time
+}
+
+// TagURI builds permanent id of entity, it doesn't change when entity is renamed or moved.
+// Authority is host of base URL, so it is the same for all instances of the forum
+func TagURI(baseURL string, specific string) string {
+	authority := "localhost"
+	if parsed, err := url.Parse(baseURL); err == nil && parsed.Hostname() != "" {
+		authority = parsed.Hostname()
+	}
+	return "tag:" + authority + "," + tagDate + ":" + specific
+}
+
+// Add appends entry and moves update time of feed
+func (f *Feed) Add(entry *Entry) {
+	if entry.Updated.IsZero() {
+		entry.Updated = entry.Published
+	}
+	if entry.Updated.After(f.Updated) {
+		f.Updated = entry.Updated
+	}
+	f.Entries = append(f.Entries, entry)
+}
+
+// Revision identifies rendered entries, it changes if any entry is added, removed or edited
+// even if update time of the whole feed stays the same
+func (f *Feed) Revision() uint64 {
+	hash := fnv.New64a()
+	for _, entry := range f.Entries {
+		for _, part := range []string{entry.ID, entry.Title, entry.Content,
+			strconv.FormatInt(entry.Updated.UnixNano(), 36)} {
+			hash.Write([]byte(part))
+			hash.Write([]byte{0})
+		}
+	}
+	return hash.Sum64()
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published"`
+	Author    *atomPerson `xml:"author"`
+	Link      *atomLink   `xml:"link"`
+	Content   *atomText   `xml:"content"`
+}
+
+type atomFeed struct {
+	XMLName  xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string       `xml:"id"`
+	Title    string       `xml:"title"`
+	Subtitle string       `xml:"subtitle,omitempty"`
+	Updated  string       `xml:"updated"`
+	Links    []*atomLink  `xml:"link"`
+	Entries  []*atomEntry `xml:"entry"`
+}
+
+// Atom renders feed by RFC 4287
+func (f *Feed) Atom() ([]byte, error) {
+	feed := &atomFeed{
+		ID:       f.ID,
+		Title:    f.Title,
+		Subtitle: f.Subtitle,
+		Updated:  f.Updated.UTC().Format(time.RFC3339),
+		Links: []*atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: f.Self},
+			{Rel: "alternate", Type: "application/json", Href: f.Link},
+		},
+	}
+	for _, entry := range f.Entries {
+		feed.Entries = append(feed.Entries, &atomEntry{
+			ID:        entry.ID,
+			Title:     entry.Title,
+			Updated:   entry.Updated.UTC().Format(time.RFC3339),
+			Published: entry.Published.UTC().Format(time.RFC3339),
+			Author:    &atomPerson{Name: entry.Author},
+			Link:      &atomLink{Rel: "alternate", Type: "application/json", Href: entry.Link},
+			Content:   &atomText{Type: "text", Body: entry.Content},
+		})
+	}
+	return marshal(feed)
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	Creator     string   `xml:"dc:creator"`
+	GUID        *rssGUID `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+}
+
+type rssChannel struct {
+	Title         string     `xml:"title"`
+	Link          string     `xml:"link"`
+	Description   string     `xml:"description"`
+	LastBuildDate string     `xml:"lastBuildDate"`
+	Self          *atomLink  `xml:"atom:link"`
+	Items         []*rssItem `xml:"item"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name    `xml:"rss"`
+	Version string      `xml:"version,attr"`
+	Atom    string      `xml:"xmlns:atom,attr"`
+	DC      string      `xml:"xmlns:dc,attr"`
+	Channel *rssChannel `xml:"channel"`
+}
+
+// RSS renders feed by RSS 2.0, authors are names without emails, so they go to dc:creator
+func (f *Feed) RSS() ([]byte, error) {
+	description := f.Subtitle
+	if description == "" {
+		description = f.Title
+	}
+
+	channel := &rssChannel{
+		Title:         f.Title,
+		Link:          f.Link,
+		Description:   description,
+		LastBuildDate: f.Updated.UTC().Format(time.RFC1123Z),
+		Self:          &atomLink{Rel: "self", Type: "application/rss+xml", Href: f.Self},
+	}
+	for _, entry := range f.Entries {
+		channel.Items = append(channel.Items, &rssItem{
+			Title:       entry.Title,
+			Link:        entry.Link,
+			Description: entry.Content,
+			Creator:     entry.Author,
+			GUID:        &rssGUID{IsPermaLink: false, Value: entry.ID},
+			PubDate:     entry.Published.UTC().Format(time.RFC1123Z),
+		})
+	}
+	return marshal(&rssFeed{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		DC:      "http://purl.org/dc/elements/1.1/",
+		Channel: channel,
+	})
+}
+
+func marshal(feed interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
@@ -0,0 +1,644 @@
+package importer
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+)
+
+const (
+	kindThread = "thread"
+	kindPost   = "post"
+)
+
+type entry struct {
+	line   *line
+	record *Record
+}
+
+type threadRef struct {
+	id    uint64
+	forum string
+}
+
+type postRef struct {
+	id     uint64
+	thread uint64
+	path   []int64
+}
+
+type importedPost struct {
+	post *models.Post
+	path []int64
+}
+
+type importedID struct {
+	kind       string
+	externalID ExternalID
+	id         uint64
+}
+
+// batch checks records of several dump lines against each other and against database,
+// records referencing something missing are rejected instead of failing whole batch
+type batch struct {
+	tx     *sql.Tx
+	source string
+
+	users   []*entry
+	forums  []*entry
+	threads []*entry
+	posts   []*entry
+	votes   []*entry
+
+	// Nicknames and slugs are case insensitive, so canonical ones are kept by lower case
+	nicknames  map[string]string
+	slugs      map[string]string
+	threadRefs map[ExternalID]*threadRef
+	postRefs   map[ExternalID]*postRef
+
+	newUsers     []*models.User
+	newForums    []*models.Forum
+	newThreads   []*models.Thread
+	newPosts     []*importedPost
+	newVotes     []*models.Vote
+	newPostVotes []*models.Vote
+	newIDs       []*importedID
+
+	imported int
+	rejected []*Rejection
+}
+
+func newBatch(tx *sql.Tx, source string) *batch {
+	return &batch{
+		tx:         tx,
+		source:     source,
+		nicknames:  map[string]string{},
+		slugs:      map[string]string{},
+		threadRefs: map[ExternalID]*threadRef{},
+		postRefs:   map[ExternalID]*postRef{},
+	}
+}
+
+func (b *batch) add(l *line) {
+	record := &Record{}
+	if err := json.Unmarshal([]byte(l.text), record); err != nil {
+		b.reject(l, "Malformed record: "+err.Error())
+		return
+	}
+
+	e := &entry{l, record}
+	switch record.Type {
+	case TypeUser:
+		b.users = append(b.users, e)
+	case TypeForum:
+		b.forums = append(b.forums, e)
+	case TypeThread:
+		b.threads = append(b.threads, e)
+	case TypePost:
+		b.posts = append(b.posts, e)
+	case TypeVote:
+		b.votes = append(b.votes, e)
+	default:
+		b.reject(l, fmt.Sprintf("Unknown record type %q", record.Type))
+	}
+}
+
+func (b *batch) reject(l *line, reason string) {
+	b.rejected = append(b.rejected, &Rejection{
+		Line:   l.number,
+		Reason: reason,
+		Record: l.text,
+	})
+}
+
+// load runs steps in order of references, so every record may refer to records of previous steps
+func (b *batch) load() error {
+	steps := []func() error{
+		b.resolveUsers,
+		b.resolveForums,
+		b.resolveThreads,
+		b.resolvePosts,
+		b.resolveVotes,
+		b.copyRows,
+		b.updateCounters,
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *batch) queryStrings(query string, args ...interface{}) ([]string, error) {
+	rows, err := b.tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// lookupNames adds canonical names found by query to names
+func (b *batch) lookupNames(names map[string]string, query string, wanted []string) error {
+	var missing []string
+	for _, name := range wanted {
+		if _, has := names[strings.ToLower(name)]; !has && name != "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	found, err := b.queryStrings(query, pq.Array(missing))
+	if err != nil {
+		return err
+	}
+	for _, name := range found {
+		names[strings.ToLower(name)] = name
+	}
+	return nil
+}
+
+func (b *batch) nextIDs(sequence string, count int) ([]uint64, error) {
+	if count == 0 {
+		return nil, nil
+	}
+
+	rows, err := b.tx.Query(`SELECT nextval($1::regclass) FROM generate_series(1, $2)`, sequence, count)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uint64
+	for rows.Next() {
+		var id uint64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (b *batch) resolveUsers() error {
+	var nicknames, emails []string
+	for _, e := range b.users {
+		nicknames = append(nicknames, e.record.Nickname)
+		emails = append(emails, e.record.Email)
+	}
+	for _, e := range b.forums {
+		nicknames = append(nicknames, e.record.User)
+	}
+	for _, e := range b.threads {
+		nicknames = append(nicknames, e.record.Author)
+	}
+	for _, e := range b.posts {
+		nicknames = append(nicknames, e.record.Author)
+	}
+	for _, e := range b.votes {
+		nicknames = append(nicknames, e.record.Nickname)
+	}
+
+	// Nicknames of new users found here already exist
+	err := b.lookupNames(b.nicknames,
+		`SELECT nickname FROM users WHERE nickname = ANY($1::citext[])`,
+		nicknames)
+	if err != nil {
+		return err
+	}
+
+	takenEmails := map[string]bool{}
+	if len(emails) != 0 {
+		found, err := b.queryStrings(`SELECT email FROM users WHERE email = ANY($1::citext[])`, pq.Array(emails))
+		if err != nil {
+			return err
+		}
+		for _, email := range found {
+			takenEmails[strings.ToLower(email)] = true
+		}
+	}
+
+	for _, e := range b.users {
+		r := e.record
+		nicknameKey := strings.ToLower(r.Nickname)
+		emailKey := strings.ToLower(r.Email)
+
+		switch {
+		case r.Nickname == "" || r.Fullname == "" || r.Email == "":
+			b.reject(e.line, "User must have nickname, fullname and email")
+		case strings.EqualFold(r.Nickname, models.GhostNickname):
+			b.reject(e.line, fmt.Sprintf("Nickname %s is reserved for system use", r.Nickname))
+		case b.nicknames[nicknameKey] != "":
+			b.reject(e.line, fmt.Sprintf("User with nickname %s already exists", r.Nickname))
+		case takenEmails[emailKey]:
+			b.reject(e.line, fmt.Sprintf("User with email %s already exists", r.Email))
+		default:
+			b.nicknames[nicknameKey] = r.Nickname
+			takenEmails[emailKey] = true
+			b.newUsers = append(b.newUsers, &models.User{
+				Nickname: r.Nickname,
+				Fullname: r.Fullname,
+				Email:    r.Email,
+				About:    r.About,
+			})
+			b.imported++
+		}
+	}
+	return nil
+}
+
+func (b *batch) resolveForums() error {
+	var slugs []string
+	for _, e := range b.forums {
+		slugs = append(slugs, e.record.Slug)
+	}
+	for _, e := range b.threads {
+		slugs = append(slugs, e.record.Forum)
+	}
+
+	// Slugs of new forums found here already exist
+	err := b.lookupNames(b.slugs,
+		`SELECT slug FROM forums WHERE slug = ANY($1::citext[])`,
+		slugs)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range b.forums {
+		r := e.record
+		slugKey := strings.ToLower(r.Slug)
+		user, hasUser := b.nicknames[strings.ToLower(r.User)]
+
+		switch {
+		case r.Slug == "" || r.Title == "":
+			b.reject(e.line, "Forum must have slug and title")
+		case b.slugs[slugKey] != "":
+			b.reject(e.line, fmt.Sprintf("Forum with slug %s already exists", r.Slug))
+		case !hasUser:
+			b.reject(e.line, fmt.Sprintf("Can't find user with nickname %s", r.User))
+		default:
+			b.slugs[slugKey] = r.Slug
+			b.newForums = append(b.newForums, &models.Forum{
+				Slug:  r.Slug,
+				Title: r.Title,
+				User:  user,
+			})
+			b.imported++
+		}
+	}
+	return nil
+}
+
+func (b *batch) lookupThreads(externalIDs []string) error {
+	if len(externalIDs) == 0 {
+		return nil
+	}
+
+	rows, err := b.tx.Query(
+		`SELECT i.external_id, t.id, t.forum
+		FROM import_ids AS i
+		JOIN threads AS t ON t.id = i.id
+		WHERE i.source=$1
+		AND i.kind=$2
+		AND i.external_id = ANY($3)`,
+		b.source, kindThread, pq.Array(externalIDs))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var externalID ExternalID
+		ref := &threadRef{}
+		if err := rows.Scan(&externalID, &ref.id, &ref.forum); err != nil {
+			return err
+		}
+		b.threadRefs[externalID] = ref
+	}
+	return rows.Err()
+}
+
+func (b *batch) resolveThreads() error {
+	var externalIDs, slugs []string
+	for _, e := range b.threads {
+		externalIDs = append(externalIDs, string(e.record.ID))
+		if e.record.Slug != "" {
+			slugs = append(slugs, e.record.Slug)
+		}
+	}
+	for _, e := range b.posts {
+		externalIDs = append(externalIDs, string(e.record.Thread))
+	}
+	for _, e := range b.votes {
+		if e.record.Thread != "" {
+			externalIDs = append(externalIDs, string(e.record.Thread))
+		}
+	}
+
+	// Threads of this batch found here were imported before
+	if err := b.lookupThreads(externalIDs); err != nil {
+		return err
+	}
+
+	takenSlugs := map[string]bool{}
+	if len(slugs) != 0 {
+		found, err := b.queryStrings(`SELECT slug FROM threads WHERE slug = ANY($1::citext[])`, pq.Array(slugs))
+		if err != nil {
+			return err
+		}
+		for _, slug := range found {
+			takenSlugs[strings.ToLower(slug)] = true
+		}
+	}
+
+	ids, err := b.nextIDs("threads_id_seq", len(b.threads))
+	if err != nil {
+		return err
+	}
+
+	for i, e := range b.threads {
+		r := e.record
+		slugKey := strings.ToLower(r.Slug)
+		forum, hasForum := b.slugs[strings.ToLower(r.Forum)]
+		author, hasAuthor := b.nicknames[strings.ToLower(r.Author)]
+
+		switch {
+		case r.ID == "" || r.Title == "" || r.Message == "":
+			b.reject(e.line, "Thread must have id, title and message")
+		case b.threadRefs[r.ID] != nil:
+			b.reject(e.line, fmt.Sprintf("Thread %s is already imported", r.ID))
+		case r.Slug != "" && takenSlugs[slugKey]:
+			b.reject(e.line, fmt.Sprintf("Thread with slug %s already exists", r.Slug))
+		case !hasForum:
+			b.reject(e.line, fmt.Sprintf("Can't find forum with slug %s", r.Forum))
+		case !hasAuthor:
+			b.reject(e.line, fmt.Sprintf("Can't find user with nickname %s", r.Author))
+		default:
+			if r.Slug != "" {
+				takenSlugs[slugKey] = true
+			}
+			created := r.Created
+			if created.IsZero() {
+				created = time.Now()
+			}
+
+			b.threadRefs[r.ID] = &threadRef{ids[i], forum}
+			b.newThreads = append(b.newThreads, &models.Thread{
+				ID:      ids[i],
+				Slug:    r.Slug,
+				Title:   r.Title,
+				Author:  author,
+				Forum:   forum,
+				Message: r.Message,
+				Created: created,
+			})
+			b.newIDs = append(b.newIDs, &importedID{kindThread, r.ID, ids[i]})
+			b.imported++
+		}
+	}
+	return nil
+}
+
+func (b *batch) lookupPosts(externalIDs []string) error {
+	if len(externalIDs) == 0 {
+		return nil
+	}
+
+	rows, err := b.tx.Query(
+		`SELECT i.external_id, p.id, p.thread, p.path
+		FROM import_ids AS i
+		JOIN posts AS p ON p.id = i.id
+		WHERE i.source=$1
+		AND i.kind=$2
+		AND i.external_id = ANY($3)`,
+		b.source, kindPost, pq.Array(externalIDs))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var externalID ExternalID
+		ref := &postRef{}
+		if err := rows.Scan(&externalID, &ref.id, &ref.thread, pq.Array(&ref.path)); err != nil {
+			return err
+		}
+		b.postRefs[externalID] = ref
+	}
+	return rows.Err()
+}
+
+func (b *batch) resolvePosts() error {
+	var externalIDs []string
+	for _, e := range b.posts {
+		externalIDs = append(externalIDs, string(e.record.ID))
+		if e.record.hasParent() {
+			externalIDs = append(externalIDs, string(e.record.Parent))
+		}
+	}
+	for _, e := range b.votes {
+		if e.record.Post != "" {
+			externalIDs = append(externalIDs, string(e.record.Post))
+		}
+	}
+
+	// Posts of this batch found here were imported before
+	if err := b.lookupPosts(externalIDs); err != nil {
+		return err
+	}
+
+	ids, err := b.nextIDs("posts_id_seq", len(b.posts))
+	if err != nil {
+		return err
+	}
+
+	for i, e := range b.posts {
+		r := e.record
+		thread := b.threadRefs[r.Thread]
+		author, hasAuthor := b.nicknames[strings.ToLower(r.Author)]
+
+		// Parent has to be imported before its replies, path of reply continues its path
+		var parent *postRef
+		if r.hasParent() {
+			parent = b.postRefs[r.Parent]
+		}
+
+		switch {
+		case r.ID == "" || r.Message == "":
+			b.reject(e.line, "Post must have id and message")
+		case b.postRefs[r.ID] != nil:
+			b.reject(e.line, fmt.Sprintf("Post %s is already imported", r.ID))
+		case thread == nil:
+			b.reject(e.line, fmt.Sprintf("Can't find thread %s", r.Thread))
+		case !hasAuthor:
+			b.reject(e.line, fmt.Sprintf("Can't find user with nickname %s", r.Author))
+		case r.hasParent() && (parent == nil || parent.thread != thread.id):
+			b.reject(e.line, fmt.Sprintf("Can't find parent post %s in thread %s", r.Parent, r.Thread))
+		default:
+			post := &models.Post{
+				ID:       ids[i],
+				Author:   author,
+				Message:  r.Message,
+				IsEdited: r.IsEdited,
+				Forum:    thread.forum,
+				Thread:   thread.id,
+				Created:  r.Created,
+			}
+			if post.Created.IsZero() {
+				post.Created = time.Now()
+			}
+
+			var path []int64
+			if parent != nil {
+				post.Parent = parent.id
+				path = append(path, parent.path...)
+			}
+			path = append(path, int64(post.ID))
+
+			b.postRefs[r.ID] = &postRef{post.ID, thread.id, path}
+			b.newPosts = append(b.newPosts, &importedPost{post, path})
+			b.newIDs = append(b.newIDs, &importedID{kindPost, r.ID, post.ID})
+			b.imported++
+		}
+	}
+	return nil
+}
+
+// lookupVotes returns keys of votes which were given already
+func (b *batch) lookupVotes(table string, column string, nicknames []string, ids []int64) (map[string]bool, error) {
+	taken := map[string]bool{}
+	if len(nicknames) == 0 {
+		return taken, nil
+	}
+
+	query := fmt.Sprintf(
+		`SELECT v.nickname, v.%[2]s
+		FROM %[1]s AS v
+		JOIN unnest($1::citext[], $2::integer[]) AS k(nickname, id)
+		ON v.nickname = k.nickname AND v.%[2]s = k.id`,
+		table, column)
+	rows, err := b.tx.Query(query, pq.Array(nicknames), pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var nickname string
+		var id uint64
+		if err := rows.Scan(&nickname, &id); err != nil {
+			return nil, err
+		}
+		taken[voteKey(nickname, id)] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return taken, nil
+}
+
+func voteKey(nickname string, id uint64) string {
+	return fmt.Sprintf("%s %d", strings.ToLower(nickname), id)
+}
+
+func (b *batch) resolveVotes() error {
+	var threadNicknames, postNicknames []string
+	var threadIDs, postIDs []int64
+	for _, e := range b.votes {
+		r := e.record
+		if thread := b.threadRefs[r.Thread]; thread != nil {
+			threadNicknames = append(threadNicknames, r.Nickname)
+			threadIDs = append(threadIDs, int64(thread.id))
+		}
+		if post := b.postRefs[r.Post]; post != nil {
+			postNicknames = append(postNicknames, r.Nickname)
+			postIDs = append(postIDs, int64(post.id))
+		}
+	}
+
+	takenThreadVotes, err := b.lookupVotes("votes", "thread", threadNicknames, threadIDs)
+	if err != nil {
+		return err
+	}
+	takenPostVotes, err := b.lookupVotes("post_votes", "post", postNicknames, postIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range b.votes {
+		r := e.record
+		nickname, hasNickname := b.nicknames[strings.ToLower(r.Nickname)]
+
+		switch {
+		case r.Voice != models.VoiceUp && r.Voice != models.VoiceDown:
+			b.reject(e.line, fmt.Sprintf("Voice %d is wrong, it must be 1 or -1", r.Voice))
+		case (r.Thread == "") == (r.Post == ""):
+			b.reject(e.line, "Vote must be given either to thread or to post")
+		case !hasNickname:
+			b.reject(e.line, fmt.Sprintf("Can't find user with nickname %s", r.Nickname))
+		case r.Thread != "":
+			thread := b.threadRefs[r.Thread]
+			if thread == nil {
+				b.reject(e.line, fmt.Sprintf("Can't find thread %s", r.Thread))
+				continue
+			}
+			key := voteKey(nickname, thread.id)
+			if takenThreadVotes[key] {
+				b.reject(e.line, fmt.Sprintf("User %s already voted for thread %s", nickname, r.Thread))
+				continue
+			}
+			takenThreadVotes[key] = true
+
+			b.newVotes = append(b.newVotes, &models.Vote{
+				Nickname: nickname,
+				Voice:    r.Voice,
+				Thread:   thread.id,
+			})
+			b.imported++
+		default:
+			post := b.postRefs[r.Post]
+			if post == nil {
+				b.reject(e.line, fmt.Sprintf("Can't find post %s", r.Post))
+				continue
+			}
+			key := voteKey(nickname, post.id)
+			if takenPostVotes[key] {
+				b.reject(e.line, fmt.Sprintf("User %s already voted for post %s", nickname, r.Post))
+				continue
+			}
+			takenPostVotes[key] = true
+
+			b.newPostVotes = append(b.newPostVotes, &models.Vote{
+				Nickname: nickname,
+				Voice:    r.Voice,
+				Post:     post.id,
+			})
+			b.imported++
+		}
+	}
+	return nil
+}
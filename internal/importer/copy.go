@@ -0,0 +1,234 @@
+package importer
+
+import (
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+func (b *batch) copyIn(table string, columns []string, rows [][]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	stmt, err := b.tx.Prepare(pq.CopyIn(table, columns...))
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if _, err := stmt.Exec(row...); err != nil {
+			stmt.Close()
+			return err
+		}
+	}
+	if _, err := stmt.Exec(); err != nil {
+		stmt.Close()
+		return err
+	}
+	return stmt.Close()
+}
+
+// copyInWithoutTriggers skips triggers on insert into table, paths are built and counters are updated
+// for the whole batch by importer. Triggers check setting local to transaction, so tables aren't
+// altered and locked and other sessions keep writing to them while batch is loaded
+func (b *batch) copyInWithoutTriggers(table string, columns []string, rows [][]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	if _, err := b.tx.Exec(`SET LOCAL forum.bulk_load = 'on'`); err != nil {
+		return err
+	}
+	if err := b.copyIn(table, columns, rows); err != nil {
+		return err
+	}
+	_, err := b.tx.Exec(`SET LOCAL forum.bulk_load = 'off'`)
+	return err
+}
+
+func (b *batch) copyRows() error {
+	var users [][]interface{}
+	for _, user := range b.newUsers {
+		users = append(users, []interface{}{user.Nickname, user.Fullname, user.Email, user.About})
+	}
+	if err := b.copyIn("users", []string{"nickname", "fullname", "email", "about"}, users); err != nil {
+		return err
+	}
+
+	var forums [][]interface{}
+	for _, forum := range b.newForums {
+		forums = append(forums, []interface{}{forum.Slug, forum.Title, forum.User})
+	}
+	if err := b.copyIn("forums", []string{"slug", "title", "author"}, forums); err != nil {
+		return err
+	}
+
+	var threads [][]interface{}
+	for _, thread := range b.newThreads {
+		threads = append(threads, []interface{}{thread.ID, thread.Title, thread.Author, thread.Message,
+			thread.Created, thread.Forum, thread.Slug})
+	}
+	err := b.copyInWithoutTriggers("threads",
+		[]string{"id", "title", "author", "message", "created", "forum", "slug"}, threads)
+	if err != nil {
+		return err
+	}
+
+	var posts [][]interface{}
+	for _, imported := range b.newPosts {
+		post := imported.post
+		posts = append(posts, []interface{}{post.ID, post.Parent, post.Author, post.Message, post.IsEdited,
+			post.Forum, post.Thread, post.Created, pq.Array(imported.path)})
+	}
+	err = b.copyInWithoutTriggers("posts",
+		[]string{"id", "parent", "author", "message", "isedited", "forum", "thread", "created", "path"}, posts)
+	if err != nil {
+		return err
+	}
+
+	var votes [][]interface{}
+	for _, vote := range b.newVotes {
+		votes = append(votes, []interface{}{vote.Nickname, vote.Thread, vote.Voice})
+	}
+	if err := b.copyInWithoutTriggers("votes", []string{"nickname", "thread", "voice"}, votes); err != nil {
+		return err
+	}
+
+	var postVotes [][]interface{}
+	for _, vote := range b.newPostVotes {
+		postVotes = append(postVotes, []interface{}{vote.Nickname, vote.Post, vote.Voice})
+	}
+	if err := b.copyInWithoutTriggers("post_votes", []string{"nickname", "post", "voice"}, postVotes); err != nil {
+		return err
+	}
+
+	var ids [][]interface{}
+	for _, id := range b.newIDs {
+		ids = append(ids, []interface{}{b.source, id.kind, string(id.externalID), id.id})
+	}
+	return b.copyIn("import_ids", []string{"source", "kind", "external_id", "id"}, ids)
+}
+
+type forumCounters struct {
+	threads int64
+	posts   int64
+}
+
+// updateCounters does once per batch what skipped row triggers do for every row
+func (b *batch) updateCounters() error {
+	var forumUserNicknames, forumUserForums []string
+	forumUsers := map[string]bool{}
+	addForumUser := func(nickname string, forum string) {
+		key := strings.ToLower(nickname + " " + forum)
+		if !forumUsers[key] {
+			forumUsers[key] = true
+			forumUserNicknames = append(forumUserNicknames, nickname)
+			forumUserForums = append(forumUserForums, forum)
+		}
+	}
+
+	counters := map[string]*forumCounters{}
+	counterOf := func(forum string) *forumCounters {
+		key := strings.ToLower(forum)
+		if counters[key] == nil {
+			counters[key] = &forumCounters{}
+		}
+		return counters[key]
+	}
+
+	for _, thread := range b.newThreads {
+		addForumUser(thread.Author, thread.Forum)
+		counterOf(thread.Forum).threads++
+	}
+	for _, imported := range b.newPosts {
+		addForumUser(imported.post.Author, imported.post.Forum)
+		counterOf(imported.post.Forum).posts++
+	}
+
+	if len(forumUsers) != 0 {
+		_, err := b.tx.Exec(
+			`INSERT INTO forum_user(nickname, forum)
+			SELECT nickname, forum FROM unnest($1::citext[], $2::citext[]) AS k(nickname, forum)
+			ON CONFLICT DO NOTHING`,
+			pq.Array(forumUserNicknames), pq.Array(forumUserForums))
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(counters) != 0 {
+		var slugs []string
+		var threads, posts []int64
+		for slug, counter := range counters {
+			slugs = append(slugs, slug)
+			threads = append(threads, counter.threads)
+			posts = append(posts, counter.posts)
+		}
+
+		_, err := b.tx.Exec(
+			`UPDATE forums
+			SET threads = forums.threads + c.threads, posts = forums.posts + c.posts
+			FROM unnest($1::citext[], $2::integer[], $3::integer[]) AS c(slug, threads, posts)
+			WHERE forums.slug = c.slug`,
+			pq.Array(slugs), pq.Array(threads), pq.Array(posts))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Listings of posts are cached by version of thread posts, posts imported into existing threads change them
+	var postThreads []int64
+	postThreadsSeen := map[int64]bool{}
+	for _, imported := range b.newPosts {
+		thread := int64(imported.post.Thread)
+		if !postThreadsSeen[thread] {
+			postThreadsSeen[thread] = true
+			postThreads = append(postThreads, thread)
+		}
+	}
+	if len(postThreads) != 0 {
+		_, err := b.tx.Exec(
+			`UPDATE threads
+			SET posts_version = posts_version + 1, posts_updated = now()
+			WHERE id = ANY($1::integer[])`,
+			pq.Array(postThreads))
+		if err != nil {
+			return err
+		}
+	}
+
+	threadVotes := map[int64]int64{}
+	for _, vote := range b.newVotes {
+		threadVotes[int64(vote.Thread)] += int64(vote.Voice)
+	}
+	if err := b.addVotes("threads", "votes", threadVotes); err != nil {
+		return err
+	}
+
+	postScores := map[int64]int64{}
+	for _, vote := range b.newPostVotes {
+		postScores[int64(vote.Post)] += int64(vote.Voice)
+	}
+	return b.addVotes("posts", "score", postScores)
+}
+
+// addVotes runs with triggers enabled, so versions of threads and posts voted for are bumped
+func (b *batch) addVotes(table string, column string, sums map[int64]int64) error {
+	if len(sums) == 0 {
+		return nil
+	}
+
+	var ids, values []int64
+	for id, value := range sums {
+		ids = append(ids, id)
+		values = append(values, value)
+	}
+
+	_, err := b.tx.Exec(
+		`UPDATE `+table+`
+		SET `+column+` = `+table+`.`+column+` + c.value
+		FROM unnest($1::integer[], $2::integer[]) AS c(id, value)
+		WHERE `+table+`.id = c.id`,
+		pq.Array(ids), pq.Array(values))
+	return err
+}
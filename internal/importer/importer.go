@@ -0,0 +1,142 @@
+package importer
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+const DefaultBatchSize = 10000
+
+// Long posts don't fit into default buffer of scanner
+const maxLineSize = 16 * 1024 * 1024
+
+type line struct {
+	number int
+	text   string
+}
+
+// Importer loads JSON-lines dump of old forum. Dump is split into batches and every batch is committed
+// together with its last line, so import started again with the same source continues after it
+type Importer struct {
+	dbConn    *sql.DB
+	source    string
+	batchSize int
+}
+
+func NewImporter(dbConn *sql.DB, source string, batchSize int) *Importer {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	return &Importer{
+		dbConn:    dbConn,
+		source:    source,
+		batchSize: batchSize,
+	}
+}
+
+// Run imports dump, rejected records are written to rejects and progress is reported after every batch
+func (im *Importer) Run(dump io.Reader, rejects io.Writer, report func(progress *Progress)) (*Progress, error) {
+	progress, err := im.loadProgress()
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(dump)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	encoder := json.NewEncoder(rejects)
+
+	var lines []*line
+	number := 0
+	for scanner.Scan() {
+		number++
+		if number <= progress.Line || strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		lines = append(lines, &line{number, scanner.Text()})
+		if len(lines) < im.batchSize {
+			continue
+		}
+		if err := im.importBatch(lines, progress, encoder); err != nil {
+			return progress, err
+		}
+		report(progress)
+		lines = nil
+	}
+	if err := scanner.Err(); err != nil {
+		return progress, err
+	}
+
+	if len(lines) != 0 {
+		if err := im.importBatch(lines, progress, encoder); err != nil {
+			return progress, err
+		}
+		report(progress)
+	}
+	return progress, nil
+}
+
+func (im *Importer) loadProgress() (*Progress, error) {
+	progress := &Progress{}
+	row := im.dbConn.QueryRow(
+		`SELECT line, imported, rejected
+		FROM import_checkpoints
+		WHERE source=$1`,
+		im.source)
+
+	err := row.Scan(&progress.Line, &progress.Imported, &progress.Rejected)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return progress, nil
+}
+
+func (im *Importer) importBatch(lines []*line, progress *Progress, rejects *json.Encoder) error {
+	tx, err := im.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	b := newBatch(tx, im.source)
+	for _, l := range lines {
+		b.add(l)
+	}
+
+	if err := b.load(); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	batchProgress := &Progress{
+		Line:     lines[len(lines)-1].number,
+		Imported: progress.Imported + b.imported,
+		Rejected: progress.Rejected + len(b.rejected),
+	}
+	_, err = tx.Exec(
+		`INSERT INTO import_checkpoints(source, line, imported, rejected)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (source) DO UPDATE
+		SET line=$2, imported=$3, rejected=$4, updated=now()`,
+		im.source, batchProgress.Line, batchProgress.Imported, batchProgress.Rejected)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	*progress = *batchProgress
+
+	// Rejections are written after commit, so batch retried after failure doesn't repeat them
+	for _, rejection := range b.rejected {
+		if err := rejects.Encode(rejection); err != nil {
+			return err
+		}
+	}
+	return nil
+}
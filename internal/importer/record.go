@@ -0,0 +1,82 @@
+package importer
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	TypeUser   = "user"
+	TypeForum  = "forum"
+	TypeThread = "thread"
+	TypePost   = "post"
+	TypeVote   = "vote"
+)
+
+// ExternalID is id of thread or post in old forum, dumps have them both as numbers and strings
+type ExternalID string
+
+func (id *ExternalID) UnmarshalJSON(b []byte) error {
+	var str string
+	if err := json.Unmarshal(b, &str); err == nil {
+		*id = ExternalID(str)
+		return nil
+	}
+
+	var number json.Number
+	if err := json.Unmarshal(b, &number); err != nil {
+		return err
+	}
+	*id = ExternalID(number.String())
+	return nil
+}
+
+// Record is one line of dump, type tells which fields are used.
+// Users and forums are referenced by nickname and slug, threads and posts by their external ids
+type Record struct {
+	Type string     `json:"type"`
+	ID   ExternalID `json:"id"`
+
+	// User
+	Nickname string `json:"nickname"`
+	Fullname string `json:"fullname"`
+	Email    string `json:"email"`
+	About    string `json:"about"`
+
+	// Forum, slug is used by threads too
+	Slug  string `json:"slug"`
+	Title string `json:"title"`
+	User  string `json:"user"`
+
+	// Thread and post
+	Forum    string     `json:"forum"`
+	Author   string     `json:"author"`
+	Message  string     `json:"message"`
+	Created  time.Time  `json:"created"`
+	IsEdited bool       `json:"isEdited"`
+	Thread   ExternalID `json:"thread"`
+	Parent   ExternalID `json:"parent"`
+
+	// Vote is given by nickname either to thread or to post
+	Post  ExternalID `json:"post"`
+	Voice int        `json:"voice"`
+}
+
+// hasParent treats both missing parent and zero one as root post, old forums use zero for it
+func (r *Record) hasParent() bool {
+	return r.Parent != "" && r.Parent != "0"
+}
+
+// Rejection is written for every record which was not imported
+type Rejection struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+	Record string `json:"record"`
+}
+
+// Progress is saved with every batch, so it covers all runs of import of the same source
+type Progress struct {
+	Line     int
+	Imported int
+	Rejected int
+}
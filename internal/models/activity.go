@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+type Activity struct {
+	Type    string    `json:"type"`
+	ID      uint64    `json:"id"`
+	Title   string    `json:"title,omitempty"`
+	Message string    `json:"message"`
+	Forum   string    `json:"forum"`
+	Thread  uint64    `json:"thread"`
+	Created time.Time `json:"created"`
+}
+
+const ActivityPost = "post"
+const ActivityThread = "thread"
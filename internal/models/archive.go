@@ -0,0 +1,25 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const ArchiveFormat = "tech-db-forum"
+
+// ArchiveVersion is bumped on every change of tables, archives of other versions are not restored
+const ArchiveVersion = 6
+
+// ArchiveHeader is the first line of archive, the rest of lines are rows
+type ArchiveHeader struct {
+	Format   string    `json:"format"`
+	Version  int       `json:"version"`
+	Exported time.Time `json:"exported"`
+	Forum    string    `json:"forum,omitempty"`
+}
+
+// ArchiveRow is row of table as it is stored, including counters and versions
+type ArchiveRow struct {
+	Table string          `json:"table"`
+	Row   json.RawMessage `json:"row"`
+}
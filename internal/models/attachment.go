@@ -0,0 +1,27 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+type Attachment struct {
+	ID           uint64    `json:"id"`
+	Post         uint64    `json:"post"`
+	Filename     string    `json:"filename"`
+	ContentType  string    `json:"contentType"`
+	Size         int64     `json:"size"`
+	URL          string    `json:"url"`
+	Thumbnail    string    `json:"thumbnail,omitempty"`
+	Created      time.Time `json:"created"`
+	StorageKey   string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+}
+
+// SetURLs fills links to download attachment and its thumbnail
+func (a *Attachment) SetURLs() {
+	a.URL = fmt.Sprintf("/api/attachment/%d", a.ID)
+	if a.ThumbnailKey != "" {
+		a.Thumbnail = fmt.Sprintf("/api/attachment/%d/thumbnail", a.ID)
+	}
+}
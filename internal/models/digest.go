@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+type DigestSettings struct {
+	Nickname  string    `json:"nickname"`
+	Frequency string    `json:"frequency" validate:"oneof=never daily weekly"`
+	LastSent  time.Time `json:"lastSent"`
+}
+
+// Digest continues after post with published time Since and id SincePost
+type Digest struct {
+	User      *User
+	Since     time.Time
+	SincePost uint64
+	Posts     []*Post
+}
+
+const DigestNever = "never"
+const DigestDaily = "daily"
+const DigestWeekly = "weekly"
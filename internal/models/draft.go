@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// Draft of post has thread and optional parent, draft of thread has forum, title and optional slug.
+// Draft with publish time is published by scheduler and removed, failed one is kept with the reason
+type Draft struct {
+	ID           uint64     `json:"id"`
+	Author       string     `json:"author"`
+	Forum        string     `json:"forum,omitempty"`
+	Thread       uint64     `json:"thread,omitempty"`
+	Parent       uint64     `json:"parent,omitempty"`
+	Title        string     `json:"title,omitempty"`
+	Slug         string     `json:"slug,omitempty"`
+	Message      string     `json:"message"`
+	PublishAt    *time.Time `json:"publishAt,omitempty"`
+	PublishError string     `json:"publishError,omitempty"`
+	// Attempts counts failed publications of scheduled draft, it is reset by every update
+	Attempts int `json:"-"`
+	// Publishing is set right before content is created, claimed draft with it was interrupted
+	Publishing bool      `json:"-"`
+	Created    time.Time `json:"created"`
+	Updated    time.Time `json:"updated"`
+}
+
+// IsThread reports whether draft becomes thread rather than post
+func (d *Draft) IsThread() bool {
+	return d.Thread == 0
+}
@@ -0,0 +1,4 @@
+package models
+
+// FormatHTML is value of format query parameter asking to render messages from Markdown to HTML
+const FormatHTML = "html"
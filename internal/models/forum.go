@@ -1,9 +1,16 @@
 package models
 
+import (
+	"time"
+)
+
 type Forum struct {
 	Title   string `json:"title" validate:"required"`
 	User    string `json:"user" validate:"required,gte=3,lte=32"`
 	Slug    string `json:"slug" validate:"required,gte=3,lte=64"`
 	Posts   uint64 `json:"posts" validate:"eq=0"`
 	Threads uint64 `json:"threads" validate:"eq=0"`
+	// Version is bumped on every update, also by counters
+	Version uint64    `json:"-"`
+	Updated time.Time `json:"-"`
 }
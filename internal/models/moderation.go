@@ -0,0 +1,35 @@
+package models
+
+type ModerationRules struct {
+	Forum       string   `json:"forum"`
+	BannedWords []string `json:"banned_words"`
+	Patterns    []string `json:"patterns"`
+	// Zero means that links are not limited
+	MaxLinks int `json:"max_links" validate:"gte=0"`
+	// Content of users with fewer approved threads and posts is held for moderation
+	Premoderated int `json:"premoderated" validate:"gte=0"`
+}
+
+type ModerationQueue struct {
+	Threads []*Thread `json:"threads"`
+	Posts   []*Post   `json:"posts"`
+}
+
+type ModerationDecision struct {
+	Action  string   `json:"action" validate:"required"`
+	Threads []uint64 `json:"threads"`
+	Posts   []uint64 `json:"posts"`
+}
+
+type ModerationResult struct {
+	Threads int64 `json:"threads"`
+	Posts   int64 `json:"posts"`
+}
+
+const StatusApproved = "approved"
+const StatusPending = "pending"
+const StatusRejected = "rejected"
+const StatusHidden = "hidden"
+
+const ModerationApprove = "approve"
+const ModerationReject = "reject"
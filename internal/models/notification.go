@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+type Notification struct {
+	ID       uint64    `json:"id"`
+	Nickname string    `json:"nickname"`
+	Type     string    `json:"type"`
+	Author   string    `json:"author"`
+	Post     uint64    `json:"post"`
+	Thread   uint64    `json:"thread"`
+	IsRead   bool      `json:"isRead"`
+	Created  time.Time `json:"created"`
+}
+
+type Notifications struct {
+	Unread        uint64          `json:"unread"`
+	Notifications []*Notification `json:"notifications"`
+}
+
+const NotificationReply = "reply"
+const NotificationMention = "mention"
+const NotificationThread = "thread"
@@ -5,3 +5,10 @@ type Pagination struct {
 	Desc  bool   `query:"desc"`
 	Sort  string `query:"sort"`
 }
+
+// LimitTo replaces missing or too large limit with max one, zero max leaves limit as is
+func (p *Pagination) LimitTo(max uint64) {
+	if max != 0 && (p.Limit == 0 || p.Limit > max) {
+		p.Limit = max
+	}
+}
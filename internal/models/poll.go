@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// Poll is closed when closing time comes, it isn't stored as flag, so cached polls stay valid
+type Poll struct {
+	Thread   uint64        `json:"thread"`
+	Question string        `json:"question"`
+	Options  []*PollOption `json:"options"`
+	Multiple bool          `json:"multiple"`
+	Closes   *time.Time    `json:"closes,omitempty"`
+	Voters   int64         `json:"voters"`
+	Created  time.Time     `json:"created"`
+}
+
+type PollOption struct {
+	ID    uint64 `json:"id"`
+	Text  string `json:"text"`
+	Votes int64  `json:"votes"`
+}
+
+// Ballot holds ids of chosen options, single choice poll takes exactly one of them
+type Ballot struct {
+	Thread   uint64    `json:"thread"`
+	Nickname string    `json:"nickname" validate:"required,gte=3,lte=32"`
+	Options  []uint64  `json:"options"`
+	Created  time.Time `json:"created"`
+}
+
+// IsClosed reports whether poll stopped taking ballots by the time
+func (p *Poll) IsClosed(now time.Time) bool {
+	return p.Closes != nil && !now.Before(*p.Closes)
+}
@@ -13,8 +13,21 @@ type Post struct {
 	Forum    string    `json:"forum" validate:"gte=3,lte=64"`
 	Thread   uint64    `json:"thread" validate:"gte=0"`
 	Created  time.Time `json:"created"`
+	Score    int64     `json:"score"`
+	Status   string    `json:"status,omitempty"`
+	Version  uint64    `json:"-"`
+	Updated  time.Time `json:"-"`
+	// EditVersion is bumped only by edits of message, votes don't change it
+	EditVersion uint64 `json:"-"`
+	// Published is time post became visible, it differs from created for held posts
+	Published time.Time `json:"-"`
+	// MessageHTML is rendered from Markdown only on request, see FormatHTML
+	MessageHTML string `json:"messageHtml,omitempty"`
+	// Attachments are filled only in post details and listings
+	Attachments []*Attachment `json:"attachments,omitempty"`
 }
 
 const Flat = "flat"
 const Tree = "tree"
 const ParentTree = "parent_tree"
+const Score = "score"
@@ -0,0 +1,8 @@
+package models
+
+type Profile struct {
+	User
+	Reputation int64 `json:"reputation"`
+	Followers  int64 `json:"followers"`
+	Following  int64 `json:"following"`
+}
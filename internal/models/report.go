@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+type Report struct {
+	ID       uint64    `json:"id"`
+	Post     uint64    `json:"post"`
+	Nickname string    `json:"nickname" validate:"required,gte=3,lte=32"`
+	Reason   string    `json:"reason" validate:"required"`
+	Status   string    `json:"status"`
+	Created  time.Time `json:"created"`
+}
+
+type ReportedPost struct {
+	Post         *Post     `json:"post"`
+	Reports      int64     `json:"reports"`
+	LastReported time.Time `json:"lastReported"`
+}
+
+const ReportOpen = "open"
+const ReportResolved = "resolved"
+const ReportDismissed = "dismissed"
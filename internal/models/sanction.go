@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// Sanction without forum is a global ban, otherwise it is a mute in forum
+type Sanction struct {
+	ID       uint64     `json:"id"`
+	Nickname string     `json:"nickname" validate:"required,gte=3,lte=32"`
+	Forum    string     `json:"forum,omitempty" validate:"omitempty,gte=3,lte=64"`
+	Reason   string     `json:"reason" validate:"required"`
+	Created  time.Time  `json:"created"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	Lifted   *time.Time `json:"lifted,omitempty"`
+}
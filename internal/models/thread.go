@@ -13,4 +13,16 @@ type Thread struct {
 	Votes   int64     `json:"votes"`
 	Slug    string    `json:"slug" validate:"omitempty,gte=3,lte=64"`
 	Created time.Time `json:"created"`
+	Status  string    `json:"status,omitempty"`
+	Version uint64    `json:"-"`
+	Updated time.Time `json:"-"`
+	// EditVersion is bumped only by edits of title and message, votes don't change it
+	EditVersion uint64 `json:"-"`
+	// PostsVersion is bumped on every insert and update of thread posts
+	PostsVersion uint64    `json:"-"`
+	PostsUpdated time.Time `json:"-"`
+	// MessageHTML is rendered from Markdown only on request, see FormatHTML
+	MessageHTML string `json:"messageHtml,omitempty"`
+	// Poll is created with thread and is shown only in thread details
+	Poll *Poll `json:"poll,omitempty"`
 }
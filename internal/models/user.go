@@ -1,8 +1,48 @@
 package models
 
+import (
+	"time"
+)
+
 type User struct {
-	Nickname string `json:"nickname" validate:"gte=3,lte=32"`
-	Fullname string `json:"fullname" validate:"required,gte=3,lte=32"`
-	Email    string `json:"email" validate:"required,email,lte=64"`
-	About    string `json:"about"`
+	Nickname string    `json:"nickname" validate:"gte=3,lte=32"`
+	Fullname string    `json:"fullname" validate:"required,gte=3,lte=32"`
+	Email    string    `json:"email" validate:"required,email,lte=64"`
+	About    string    `json:"about"`
+	Version  uint64    `json:"-"`
+	Updated  time.Time `json:"-"`
+	// EditVersion is bumped only by edits of profile, updates fail if it is stale
+	EditVersion uint64 `json:"-"`
+}
+
+type NicknameRedirect struct {
+	Nickname string    `json:"nickname"`
+	Owner    string    `json:"owner"`
+	Released time.Time `json:"released"`
+}
+
+type UserExport struct {
+	Version   int       `json:"version"`
+	Exported  time.Time `json:"exported"`
+	Profile   *Profile  `json:"profile"`
+	Threads   []*Thread `json:"threads"`
+	Posts     []*Post   `json:"posts"`
+	Votes     []*Vote   `json:"votes"`
+	PostVotes []*Vote   `json:"postVotes"`
 }
+
+const UserExportVersion = 1
+
+type UserDeletion struct {
+	ID       uint64    `json:"id"`
+	Nickname string    `json:"nickname"`
+	Threads  uint64    `json:"threads"`
+	Posts    uint64    `json:"posts"`
+	Votes    uint64    `json:"votes"`
+	Deleted  time.Time `json:"deleted"`
+}
+
+// Content of deleted users is reassigned to ghost user to keep discussions intact
+const GhostNickname = "deleted"
+const GhostFullname = "Deleted user"
+const GhostEmail = "deleted@forum.invalid"
@@ -3,4 +3,10 @@ package models
 type Vote struct {
 	Nickname string `json:"nickname" validate:"required,gte=3,lte=32"`
 	Voice    int    `json:"voice"`
+	Thread   uint64 `json:"thread,omitempty"`
+	Post     uint64 `json:"post,omitempty"`
 }
+
+const VoiceUp = 1
+const VoiceDown = -1
+const VoiceRetract = 0
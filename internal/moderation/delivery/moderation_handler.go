@@ -0,0 +1,133 @@
+package delivery
+
+import (
+	"net/http"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/forum"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/moderation"
+	"github.com/OlegGibadulin/tech-db-forum/internal/mwares"
+	reader "github.com/OlegGibadulin/tech-db-forum/tools/request_reader"
+	"github.com/labstack/echo/v4"
+)
+
+type ModerationHandler struct {
+	moderationUcase moderation.ModerationUsecase
+	forumUcase      forum.ForumUsecase
+}
+
+func NewModerationHandler(moderationUcase moderation.ModerationUsecase, forumUcase forum.ForumUsecase) *ModerationHandler {
+	return &ModerationHandler{
+		moderationUcase: moderationUcase,
+		forumUcase:      forumUcase,
+	}
+}
+
+func (mh *ModerationHandler) Configure(e *echo.Echo, mw *mwares.MiddlewareManager) {
+	// Changing rules and resolving held content is left to moderators
+	e.GET("/api/forum/:slug/moderation/rules", mh.GetRulesHandler())
+	e.POST("/api/forum/:slug/moderation/rules", mh.UpdateRulesHandler(), mw.AdminOnly)
+	e.GET("/api/forum/:slug/moderation/queue", mh.GetQueueHandler())
+	e.POST("/api/forum/:slug/moderation/queue", mh.ResolveQueueHandler(), mw.AdminOnly)
+}
+
+func (mh *ModerationHandler) GetRulesHandler() echo.HandlerFunc {
+	return func(cntx echo.Context) error {
+		forum, err := mh.forumUcase.GetBySlug(cntx.Param("slug"))
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		rules, err := mh.moderationUcase.GetRules(forum.Slug)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, rules)
+	}
+}
+
+func (mh *ModerationHandler) UpdateRulesHandler() echo.HandlerFunc {
+	type Request struct {
+		models.ModerationRules
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		forum, err := mh.forumUcase.GetBySlug(cntx.Param("slug"))
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		req.Forum = forum.Slug
+
+		if err := mh.moderationUcase.SetRules(&req.ModerationRules); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, req.ModerationRules)
+	}
+}
+
+func (mh *ModerationHandler) GetQueueHandler() echo.HandlerFunc {
+	type Request struct {
+		SinceThread uint64 `query:"since_thread"`
+		SincePost   uint64 `query:"since_post"`
+		models.Pagination
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		forum, err := mh.forumUcase.GetBySlug(cntx.Param("slug"))
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		queue, err := mh.moderationUcase.ListQueue(forum.Slug, req.SinceThread, req.SincePost,
+			&req.Pagination)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, queue)
+	}
+}
+
+func (mh *ModerationHandler) ResolveQueueHandler() echo.HandlerFunc {
+	type Request struct {
+		models.ModerationDecision
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		forum, err := mh.forumUcase.GetBySlug(cntx.Param("slug"))
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		result, err := mh.moderationUcase.Resolve(forum.Slug, &req.ModerationDecision)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, result)
+	}
+}
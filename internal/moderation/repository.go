@@ -0,0 +1,15 @@
+package moderation
+
+import (
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+)
+
+type ModerationRepository interface {
+	UpsertRules(rules *models.ModerationRules) error
+	UpdateThreadsStatus(forumSlug string, ids []uint64, status string) (int64, error)
+	UpdatePostsStatus(forumSlug string, ids []uint64, status string) ([]*models.Post, error)
+	SelectRulesByForum(forumSlug string) (*models.ModerationRules, error)
+	SelectApprovedCountByAuthor(nickname string) (int, error)
+	SelectPendingThreads(forumSlug string, since uint64, pgnt *models.Pagination) ([]*models.Thread, error)
+	SelectPendingPosts(forumSlug string, since uint64, pgnt *models.Pagination) ([]*models.Post, error)
+}
@@ -0,0 +1,297 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/placeholders"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/moderation"
+	"github.com/lib/pq"
+)
+
+type ModerationPgRepository struct {
+	dbConn *sql.DB
+}
+
+func NewModerationPgRepository(conn *sql.DB) moderation.ModerationRepository {
+	return &ModerationPgRepository{
+		dbConn: conn,
+	}
+}
+
+func (mr *ModerationPgRepository) UpsertRules(rules *models.ModerationRules) error {
+	tx, err := mr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO moderation_rules(forum, banned_words, patterns, max_links, premoderated)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (forum) DO UPDATE
+		SET banned_words = $2, patterns = $3, max_links = $4, premoderated = $5`,
+		rules.Forum, pq.Array(rules.BannedWords), pq.Array(rules.Patterns),
+		rules.MaxLinks, rules.Premoderated)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (mr *ModerationPgRepository) UpdateThreadsStatus(forumSlug string, ids []uint64, status string) (int64, error) {
+	tx, err := mr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	var values []interface{}
+
+	// Only pending threads can be resolved
+	updateQuery := `
+		UPDATE threads
+		SET status = $1
+		WHERE forum=$2 AND status='pending'`
+	values = append(values, status, forumSlug)
+
+	filterQuery := "AND id IN " + placeholders.List(len(values)+1, len(ids))
+	for _, id := range ids {
+		values = append(values, id)
+	}
+
+	resultQuery := strings.Join([]string{
+		updateQuery,
+		filterQuery,
+	}, " ")
+
+	res, err := tx.Exec(resultQuery, values...)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (mr *ModerationPgRepository) UpdatePostsStatus(forumSlug string, ids []uint64, status string) ([]*models.Post, error) {
+	tx, err := mr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var values []interface{}
+
+	// Only pending posts can be resolved
+	updateQuery := `
+		UPDATE posts
+		SET status = $1
+		WHERE forum=$2 AND status='pending'`
+	values = append(values, status, forumSlug)
+
+	filterQuery := "AND id IN " + placeholders.List(len(values)+1, len(ids))
+	for _, id := range ids {
+		values = append(values, id)
+	}
+
+	returnQuery := "RETURNING id, parent, author, message, isedited, forum, thread, created, score, status"
+
+	resultQuery := strings.Join([]string{
+		updateQuery,
+		filterQuery,
+		returnQuery,
+	}, " ")
+
+	rows, err := tx.Query(resultQuery, values...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []*models.Post
+	for rows.Next() {
+		post := &models.Post{}
+		err := rows.Scan(&post.ID, &post.Parent, &post.Author, &post.Message, &post.IsEdited,
+			&post.Forum, &post.Thread, &post.Created, &post.Score, &post.Status)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+func (mr *ModerationPgRepository) SelectRulesByForum(forumSlug string) (*models.ModerationRules, error) {
+	rules := &models.ModerationRules{}
+
+	row := mr.dbConn.QueryRow(
+		`SELECT forum, banned_words, patterns, max_links, premoderated
+		FROM moderation_rules
+		WHERE forum=$1`,
+		forumSlug)
+
+	err := row.Scan(&rules.Forum, pq.Array(&rules.BannedWords), pq.Array(&rules.Patterns),
+		&rules.MaxLinks, &rules.Premoderated)
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (mr *ModerationPgRepository) SelectApprovedCountByAuthor(nickname string) (int, error) {
+	var count int
+
+	row := mr.dbConn.QueryRow(
+		`SELECT
+		(SELECT COUNT(*) FROM threads WHERE author=$1 AND status='approved') +
+		(SELECT COUNT(*) FROM posts WHERE author=$1 AND status='approved')`,
+		nickname)
+
+	if err := row.Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (mr *ModerationPgRepository) SelectPendingThreads(forumSlug string, since uint64, pgnt *models.Pagination) ([]*models.Thread, error) {
+	var values []interface{}
+
+	selectQuery := `
+		SELECT id, title, author, message, created, forum, votes, slug, status
+		FROM threads
+		WHERE forum=$1 AND status='pending'`
+	values = append(values, forumSlug)
+
+	var sortQuery string
+	if pgnt.Desc {
+		sortQuery = "ORDER BY id DESC"
+	} else {
+		sortQuery = "ORDER BY id"
+	}
+
+	var filterQuery string
+	if since != 0 {
+		ind := len(values) + 1
+		if pgnt.Desc {
+			filterQuery = "AND id < $" + strconv.Itoa(ind)
+		} else {
+			filterQuery = "AND id > $" + strconv.Itoa(ind)
+		}
+		values = append(values, since)
+	}
+
+	var pgntQuery string
+	if pgnt.Limit != 0 {
+		pgntQuery = "LIMIT $" + strconv.Itoa(len(values)+1)
+		values = append(values, pgnt.Limit)
+	}
+
+	resultQuery := strings.Join([]string{
+		selectQuery,
+		filterQuery,
+		sortQuery,
+		pgntQuery,
+	}, " ")
+
+	rows, err := mr.dbConn.Query(resultQuery, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var threads []*models.Thread
+	for rows.Next() {
+		thread := &models.Thread{}
+		err := rows.Scan(&thread.ID, &thread.Title, &thread.Author, &thread.Message, &thread.Created,
+			&thread.Forum, &thread.Votes, &thread.Slug, &thread.Status)
+		if err != nil {
+			return nil, err
+		}
+		threads = append(threads, thread)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return threads, nil
+}
+
+func (mr *ModerationPgRepository) SelectPendingPosts(forumSlug string, since uint64, pgnt *models.Pagination) ([]*models.Post, error) {
+	var values []interface{}
+
+	selectQuery := `
+		SELECT id, parent, author, message, isedited, forum, thread, created, score, status
+		FROM posts
+		WHERE forum=$1 AND status='pending'`
+	values = append(values, forumSlug)
+
+	var sortQuery string
+	if pgnt.Desc {
+		sortQuery = "ORDER BY id DESC"
+	} else {
+		sortQuery = "ORDER BY id"
+	}
+
+	var filterQuery string
+	if since != 0 {
+		ind := len(values) + 1
+		if pgnt.Desc {
+			filterQuery = "AND id < $" + strconv.Itoa(ind)
+		} else {
+			filterQuery = "AND id > $" + strconv.Itoa(ind)
+		}
+		values = append(values, since)
+	}
+
+	var pgntQuery string
+	if pgnt.Limit != 0 {
+		pgntQuery = "LIMIT $" + strconv.Itoa(len(values)+1)
+		values = append(values, pgnt.Limit)
+	}
+
+	resultQuery := strings.Join([]string{
+		selectQuery,
+		filterQuery,
+		sortQuery,
+		pgntQuery,
+	}, " ")
+
+	rows, err := mr.dbConn.Query(resultQuery, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []*models.Post
+	for rows.Next() {
+		post := &models.Post{}
+		err := rows.Scan(&post.ID, &post.Parent, &post.Author, &post.Message, &post.IsEdited,
+			&post.Forum, &post.Thread, &post.Created, &post.Score, &post.Status)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
@@ -0,0 +1,16 @@
+package moderation
+
+import (
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+)
+
+type ModerationUsecase interface {
+	SetRules(rules *models.ModerationRules) *errors.Error
+	GetRules(forumSlug string) (*models.ModerationRules, *errors.Error)
+	CheckThread(thread *models.Thread) *errors.Error
+	CheckPosts(posts []*models.Post, forumSlug string) *errors.Error
+	ListQueue(forumSlug string, sinceThread uint64, sincePost uint64,
+		pgnt *models.Pagination) (*models.ModerationQueue, *errors.Error)
+	Resolve(forumSlug string, decision *models.ModerationDecision) (*models.ModerationResult, *errors.Error)
+}
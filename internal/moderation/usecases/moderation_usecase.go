@@ -0,0 +1,278 @@
+package usecases
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+	"sync"
+
+	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/moderation"
+	"github.com/OlegGibadulin/tech-db-forum/internal/notification"
+	"github.com/sirupsen/logrus"
+)
+
+var linkRegexp = regexp.MustCompile(`(?i)(?:https?://|www\.)\S+`)
+
+type ModerationUsecase struct {
+	moderationRepo    moderation.ModerationRepository
+	notificationUcase notification.NotificationUsecase
+
+	// Rules are changed only by SetRules, so compiled filters are kept until it is called for forum
+	mu      sync.RWMutex
+	filters map[string]*filter
+}
+
+func NewModerationUsecase(repo moderation.ModerationRepository,
+	notificationUcase notification.NotificationUsecase) moderation.ModerationUsecase {
+	return &ModerationUsecase{
+		moderationRepo:    repo,
+		notificationUcase: notificationUcase,
+		filters:           map[string]*filter{},
+	}
+}
+
+// filter is compiled form of forum rules
+type filter struct {
+	rules    *models.ModerationRules
+	patterns []*regexp.Regexp
+}
+
+func compileFilter(rules *models.ModerationRules) (*filter, *errors.Error) {
+	f := &filter{
+		rules: rules,
+	}
+
+	var words []string
+	for _, word := range rules.BannedWords {
+		if word != "" {
+			words = append(words, regexp.QuoteMeta(word))
+		}
+	}
+	if len(words) != 0 {
+		pattern := `(?i)\b(?:` + strings.Join(words, "|") + `)\b`
+		f.patterns = append(f.patterns, regexp.MustCompile(pattern))
+	}
+
+	for _, pattern := range rules.Patterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.BuildByMsg(CodeWrongModerationPattern, pattern)
+		}
+		f.patterns = append(f.patterns, compiled)
+	}
+	return f, nil
+}
+
+func (f *filter) matches(text string) bool {
+	for _, pattern := range f.patterns {
+		if pattern.MatchString(text) {
+			return true
+		}
+	}
+	// Zero max links means that links are not limited
+	if f.rules.MaxLinks != 0 && len(linkRegexp.FindAllString(text, -1)) > f.rules.MaxLinks {
+		return true
+	}
+	return false
+}
+
+func (mu *ModerationUsecase) SetRules(rules *models.ModerationRules) *errors.Error {
+	// Request validation is disabled, so limits are checked here
+	if rules.MaxLinks < 0 {
+		return errors.BuildByMsg(CodeWrongModerationRules, "max_links must not be negative")
+	}
+	if rules.Premoderated < 0 {
+		return errors.BuildByMsg(CodeWrongModerationRules, "premoderated must not be negative")
+	}
+	if _, customErr := compileFilter(rules); customErr != nil {
+		return customErr
+	}
+	if rules.BannedWords == nil {
+		rules.BannedWords = []string{}
+	}
+	if rules.Patterns == nil {
+		rules.Patterns = []string{}
+	}
+
+	if err := mu.moderationRepo.UpsertRules(rules); err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+
+	mu.mu.Lock()
+	delete(mu.filters, strings.ToLower(rules.Forum))
+	mu.mu.Unlock()
+	return nil
+}
+
+func (mu *ModerationUsecase) GetRules(forumSlug string) (*models.ModerationRules, *errors.Error) {
+	rules, err := mu.moderationRepo.SelectRulesByForum(forumSlug)
+	switch {
+	case err == sql.ErrNoRows:
+		// Forum without rules is not moderated
+		return &models.ModerationRules{
+			Forum:       forumSlug,
+			BannedWords: []string{},
+			Patterns:    []string{},
+		}, nil
+	case err != nil:
+		return nil, errors.New(CodeInternalError, err)
+	}
+	return rules, nil
+}
+
+// getFilter returns compiled rules of forum, they are compiled once and cached
+func (mu *ModerationUsecase) getFilter(forumSlug string) (*filter, *errors.Error) {
+	key := strings.ToLower(forumSlug)
+	mu.mu.RLock()
+	f, ok := mu.filters[key]
+	mu.mu.RUnlock()
+	if ok {
+		return f, nil
+	}
+
+	rules, customErr := mu.GetRules(forumSlug)
+	if customErr != nil {
+		return nil, customErr
+	}
+	f, customErr = compileFilter(rules)
+	if customErr != nil {
+		return nil, customErr
+	}
+
+	mu.mu.Lock()
+	mu.filters[key] = f
+	mu.mu.Unlock()
+	return f, nil
+}
+
+func (mu *ModerationUsecase) isPremoderated(rules *models.ModerationRules, nickname string,
+	counts map[string]int) (bool, *errors.Error) {
+	if rules.Premoderated == 0 {
+		return false, nil
+	}
+
+	count, ok := counts[nickname]
+	if !ok {
+		var err error
+		count, err = mu.moderationRepo.SelectApprovedCountByAuthor(nickname)
+		if err != nil {
+			return false, errors.New(CodeInternalError, err)
+		}
+		counts[nickname] = count
+	}
+	return count < rules.Premoderated, nil
+}
+
+func (mu *ModerationUsecase) CheckThread(thread *models.Thread) *errors.Error {
+	f, customErr := mu.getFilter(thread.Forum)
+	if customErr != nil {
+		return customErr
+	}
+
+	isPremoderated, customErr := mu.isPremoderated(f.rules, thread.Author, map[string]int{})
+	if customErr != nil {
+		return customErr
+	}
+
+	if isPremoderated || f.matches(thread.Title) || f.matches(thread.Message) {
+		thread.Status = models.StatusPending
+	} else {
+		thread.Status = models.StatusApproved
+	}
+	return nil
+}
+
+func (mu *ModerationUsecase) CheckPosts(posts []*models.Post, forumSlug string) *errors.Error {
+	f, customErr := mu.getFilter(forumSlug)
+	if customErr != nil {
+		return customErr
+	}
+
+	counts := map[string]int{}
+	for _, post := range posts {
+		isPremoderated, customErr := mu.isPremoderated(f.rules, post.Author, counts)
+		if customErr != nil {
+			return customErr
+		}
+
+		if isPremoderated || f.matches(post.Message) {
+			post.Status = models.StatusPending
+		} else {
+			post.Status = models.StatusApproved
+		}
+	}
+	return nil
+}
+
+// ListQueue lists pending threads first and fills rest of the limit with pending posts,
+// so pages are continued with ids of last thread and post of previous page
+func (mu *ModerationUsecase) ListQueue(forumSlug string, sinceThread uint64, sincePost uint64,
+	pgnt *models.Pagination) (*models.ModerationQueue, *errors.Error) {
+	threads, err := mu.moderationRepo.SelectPendingThreads(forumSlug, sinceThread, pgnt)
+	if err != nil {
+		return nil, errors.New(CodeInternalError, err)
+	}
+	if len(threads) == 0 {
+		threads = []*models.Thread{}
+	}
+
+	posts := []*models.Post{}
+	if pgnt.Limit == 0 || uint64(len(threads)) < pgnt.Limit {
+		postsPgnt := *pgnt
+		if pgnt.Limit != 0 {
+			postsPgnt.Limit = pgnt.Limit - uint64(len(threads))
+		}
+		posts, err = mu.moderationRepo.SelectPendingPosts(forumSlug, sincePost, &postsPgnt)
+		if err != nil {
+			return nil, errors.New(CodeInternalError, err)
+		}
+		if len(posts) == 0 {
+			posts = []*models.Post{}
+		}
+	}
+
+	return &models.ModerationQueue{
+		Threads: threads,
+		Posts:   posts,
+	}, nil
+}
+
+func (mu *ModerationUsecase) Resolve(forumSlug string, decision *models.ModerationDecision) (*models.ModerationResult, *errors.Error) {
+	var status string
+	switch decision.Action {
+	case models.ModerationApprove:
+		status = models.StatusApproved
+	case models.ModerationReject:
+		status = models.StatusRejected
+	default:
+		return nil, errors.BuildByMsg(CodeWrongModerationAction, decision.Action)
+	}
+
+	result := &models.ModerationResult{}
+	if len(decision.Threads) != 0 {
+		count, err := mu.moderationRepo.UpdateThreadsStatus(forumSlug, decision.Threads, status)
+		if err != nil {
+			return nil, errors.New(CodeInternalError, err)
+		}
+		result.Threads = count
+	}
+
+	if len(decision.Posts) != 0 {
+		posts, err := mu.moderationRepo.UpdatePostsStatus(forumSlug, decision.Posts, status)
+		if err != nil {
+			return nil, errors.New(CodeInternalError, err)
+		}
+		result.Posts = int64(len(posts))
+
+		// Nobody was notified about held posts, so it is done on approval
+		if status == models.StatusApproved {
+			if customErr := mu.notificationUcase.NotifyAboutPosts(posts); customErr != nil {
+				logrus.Warn(customErr.Message)
+			}
+		}
+	}
+	return result, nil
+}
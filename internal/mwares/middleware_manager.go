@@ -1,16 +1,41 @@
 package mwares
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/idempotency"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/ratelimit"
 	"github.com/labstack/echo/v4"
 	"github.com/sirupsen/logrus"
 )
 
-type MiddlewareManager struct{}
+type MiddlewareManager struct {
+	rateLimiter      *ratelimit.Limiter
+	userHeader       string
+	idempotencyStore idempotency.Store
+	idempotencyTTL   time.Duration
+	adminToken       string
+}
 
-func NewMiddlewareManager() *MiddlewareManager {
-	return &MiddlewareManager{}
+func NewMiddlewareManager(rateLimiter *ratelimit.Limiter, userHeader string,
+	idempotencyStore idempotency.Store, idempotencyTTL time.Duration, adminToken string) *MiddlewareManager {
+	return &MiddlewareManager{
+		rateLimiter:      rateLimiter,
+		userHeader:       userHeader,
+		idempotencyStore: idempotencyStore,
+		idempotencyTTL:   idempotencyTTL,
+		adminToken:       adminToken,
+	}
 }
 
 func (m *MiddlewareManager) PanicRecovering(next echo.HandlerFunc) echo.HandlerFunc {
@@ -37,3 +62,134 @@ func (m *MiddlewareManager) AccessLog(next echo.HandlerFunc) echo.HandlerFunc {
 		return err
 	}
 }
+
+// AdminOnly passes requests with admin token in Authorization header as bearer token.
+// Admin endpoints are closed if token isn't set in config, subcommands still work
+func (m *MiddlewareManager) AdminOnly(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(cntx echo.Context) error {
+		token := strings.TrimPrefix(cntx.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+		if m.adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(m.adminToken)) != 1 {
+			customErr := errors.BuildByMsg(CodeAdminAccessDenied)
+			return cntx.JSON(customErr.HTTPCode, customErr.Response())
+		}
+		return next(cntx)
+	}
+}
+
+func durationToSeconds(d time.Duration) string {
+	return strconv.Itoa(int((d + time.Second - 1) / time.Second))
+}
+
+// RateLimiting limits requests of every client IP and of user passed by upstream in user header
+func (m *MiddlewareManager) RateLimiting(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(cntx echo.Context) error {
+		if m.rateLimiter == nil {
+			return next(cntx)
+		}
+
+		keys := []string{"ip:" + cntx.RealIP()}
+		if m.userHeader != "" {
+			if nickname := cntx.Request().Header.Get(m.userHeader); nickname != "" {
+				keys = append(keys, "user:"+nickname)
+			}
+		}
+
+		route := cntx.Request().Method + " " + cntx.Path()
+		res, err := m.rateLimiter.Allow(route, keys)
+		if err != nil {
+			// Broken store must not take down the whole API
+			logrus.Warn(err)
+			return next(cntx)
+		}
+		if res == nil {
+			return next(cntx)
+		}
+
+		header := cntx.Response().Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		header.Set("RateLimit-Reset", durationToSeconds(res.Reset))
+
+		if !res.Allowed {
+			retryAfter := durationToSeconds(res.RetryAfter)
+			header.Set("Retry-After", retryAfter)
+
+			customErr := errors.BuildByMsg(CodeTooManyRequests, retryAfter)
+			return cntx.JSON(customErr.HTTPCode, customErr.Response())
+		}
+		return next(cntx)
+	}
+}
+
+// responseRecorder copies response body so it can be stored
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	rr.body.Write(b)
+	return rr.ResponseWriter.Write(b)
+}
+
+// Idempotency replays the first response to requests with the same Idempotency-Key header
+func (m *MiddlewareManager) Idempotency(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(cntx echo.Context) error {
+		key := cntx.Request().Header.Get("Idempotency-Key")
+		if key == "" || m.idempotencyStore == nil {
+			return next(cntx)
+		}
+
+		body, err := ioutil.ReadAll(cntx.Request().Body)
+		if err != nil {
+			customErr := errors.New(CodeBadRequest, err)
+			return cntx.JSON(customErr.HTTPCode, customErr.Response())
+		}
+		cntx.Request().Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(cntx.Request().Method + " " + cntx.Request().URL.RequestURI() + "\n"))
+		hash.Write(body)
+		fingerprint := hex.EncodeToString(hash.Sum(nil))
+
+		record, isReserved, err := m.idempotencyStore.Reserve(key, fingerprint, time.Now().Add(m.idempotencyTTL))
+		if err != nil {
+			logrus.Warn(err)
+			return next(cntx)
+		}
+
+		if !isReserved {
+			if record.Fingerprint != fingerprint {
+				customErr := errors.BuildByMsg(CodeIdempotencyKeyIsReused, key)
+				return cntx.JSON(customErr.HTTPCode, customErr.Response())
+			}
+			if !record.IsCompleted() {
+				customErr := errors.BuildByMsg(CodeIdempotencyKeyIsInProgress, key)
+				return cntx.JSON(customErr.HTTPCode, customErr.Response())
+			}
+			cntx.Response().Header().Set("Idempotent-Replayed", "true")
+			return cntx.Blob(record.Status, record.ContentType, record.Body)
+		}
+
+		recorder := &responseRecorder{
+			ResponseWriter: cntx.Response().Writer,
+		}
+		cntx.Response().Writer = recorder
+
+		// Failed requests are not stored so they can be retried with the same key
+		err = next(cntx)
+		status := cntx.Response().Status
+		if err != nil || status >= http.StatusInternalServerError {
+			if releaseErr := m.idempotencyStore.Release(key); releaseErr != nil {
+				logrus.Warn(releaseErr)
+			}
+			return err
+		}
+
+		contentType := cntx.Response().Header().Get(echo.HeaderContentType)
+		if err := m.idempotencyStore.Complete(key, status, contentType, recorder.body.Bytes()); err != nil {
+			logrus.Warn(err)
+		}
+		return nil
+	}
+}
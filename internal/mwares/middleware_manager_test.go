@@ -0,0 +1,25 @@
+package mwares
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDurationToSecondsRoundsUp(t *testing.T) {
+	tests := []struct {
+		d       time.Duration
+		seconds string
+	}{
+		{0, "0"},
+		{time.Nanosecond, "1"},
+		{400 * time.Millisecond, "1"},
+		{time.Second, "1"},
+		{1500 * time.Millisecond, "2"},
+	}
+
+	for _, test := range tests {
+		if got := durationToSeconds(test.d); got != test.seconds {
+			t.Errorf("%s: got %s, want %s", test.d, got, test.seconds)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package delivery
+
+import (
+	"net/http"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/mwares"
+	"github.com/OlegGibadulin/tech-db-forum/internal/notification"
+	"github.com/OlegGibadulin/tech-db-forum/internal/user"
+	reader "github.com/OlegGibadulin/tech-db-forum/tools/request_reader"
+	"github.com/labstack/echo/v4"
+)
+
+type NotificationHandler struct {
+	notificationUcase notification.NotificationUsecase
+	userUcase         user.UserUsecase
+}
+
+func NewNotificationHandler(notificationUcase notification.NotificationUsecase,
+	userUcase user.UserUsecase) *NotificationHandler {
+	return &NotificationHandler{
+		notificationUcase: notificationUcase,
+		userUcase:         userUcase,
+	}
+}
+
+func (nh *NotificationHandler) Configure(e *echo.Echo, mw *mwares.MiddlewareManager) {
+	e.GET("/api/user/:nickname/notifications", nh.GetNotificationsHandler())
+	e.POST("/api/user/:nickname/notifications/read", nh.ReadNotificationsHandler())
+}
+
+func (nh *NotificationHandler) GetNotificationsHandler() echo.HandlerFunc {
+	type Request struct {
+		Since  uint64 `query:"since"`
+		Unread bool   `query:"unread"`
+		models.Pagination
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		nickname := cntx.Param("nickname")
+		user, err := nh.userUcase.GetByNickname(nickname)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		notifications, err := nh.notificationUcase.ListByNickname(user.Nickname, req.Since,
+			req.Unread, &req.Pagination)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, notifications)
+	}
+}
+
+func (nh *NotificationHandler) ReadNotificationsHandler() echo.HandlerFunc {
+	type Request struct {
+		IDs []uint64 `json:"ids"`
+	}
+
+	type Response struct {
+		Unread uint64 `json:"unread"`
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		nickname := cntx.Param("nickname")
+		user, err := nh.userUcase.GetByNickname(nickname)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		if err := nh.notificationUcase.MarkAsRead(user.Nickname, req.IDs); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		res := &Response{}
+		if res.Unread, err = nh.notificationUcase.GetUnreadCount(user.Nickname); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, res)
+	}
+}
@@ -0,0 +1,11 @@
+package notification
+
+import "github.com/OlegGibadulin/tech-db-forum/internal/models"
+
+type NotificationRepository interface {
+	InsertByPosts(posts []*models.Post, mentions [][]string) error
+	InsertMentions(post *models.Post, mentions []string) error
+	UpdateAsRead(nickname string, ids []uint64) error
+	SelectUnreadCount(nickname string) (uint64, error)
+	SelectAllByNickname(nickname string, since uint64, unread bool, pgnt *models.Pagination) ([]*models.Notification, error)
+}
@@ -0,0 +1,259 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/placeholders"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/notification"
+	"github.com/lib/pq"
+)
+
+type NotificationPgRepository struct {
+	dbConn *sql.DB
+}
+
+func NewNotificationPgRepository(conn *sql.DB) notification.NotificationRepository {
+	return &NotificationPgRepository{
+		dbConn: conn,
+	}
+}
+
+func insertMentions(tx *sql.Tx, post *models.Post, mentions []string) error {
+	if len(mentions) == 0 {
+		return nil
+	}
+
+	values := []interface{}{
+		models.NotificationMention, post.Author, post.ID, post.Thread, post.Created,
+	}
+
+	insertQuery := `
+		INSERT INTO notifications(nickname, type, author, post, thread, created)
+		SELECT nickname, $1, $2, $3, $4, $5
+		FROM users
+		WHERE nickname<>$2 AND nickname IN`
+	conflictQuery := "ON CONFLICT (nickname, post) DO NOTHING"
+
+	filterQuery := placeholders.List(len(values)+1, len(mentions))
+	for _, mention := range mentions {
+		values = append(values, mention)
+	}
+
+	resultQuery := strings.Join([]string{
+		insertQuery,
+		filterQuery,
+		conflictQuery,
+	}, " ")
+
+	_, err := tx.Exec(resultQuery, values...)
+	return err
+}
+
+// InsertByPosts notifies authors of parent posts, mentioned users and thread followers by one statement.
+// Every user gets at most one notification per post, so reply wins over mention and mention over thread
+func (nr *NotificationPgRepository) InsertByPosts(posts []*models.Post, mentions [][]string) error {
+	postIDs := make([]int64, 0, len(posts))
+	var mentionPosts []int64
+	var mentionNicknames []string
+	for ind, post := range posts {
+		postIDs = append(postIDs, int64(post.ID))
+		for _, nickname := range mentions[ind] {
+			mentionPosts = append(mentionPosts, int64(post.ID))
+			mentionNicknames = append(mentionNicknames, nickname)
+		}
+	}
+
+	tx, err := nr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`WITH new_posts AS (
+			SELECT id, author, parent, thread, created
+			FROM posts
+			WHERE id = ANY($1::integer[])
+		), recipients AS (
+			SELECT parents.author AS nickname, new_posts.id AS post, 1 AS priority, $4::varchar AS type
+			FROM new_posts
+			JOIN posts AS parents ON parents.id=new_posts.parent
+			UNION ALL
+			SELECT users.nickname, mentions.post, 2, $5::varchar
+			FROM unnest($2::integer[], $3::citext[]) AS mentions(post, nickname)
+			JOIN users ON users.nickname=mentions.nickname
+			UNION ALL
+			SELECT thread_follows.nickname, new_posts.id, 3, $6::varchar
+			FROM new_posts
+			JOIN thread_follows ON thread_follows.thread=new_posts.thread
+		)
+		INSERT INTO notifications(nickname, type, author, post, thread, created)
+		SELECT DISTINCT ON (recipients.nickname, recipients.post)
+			recipients.nickname, recipients.type, new_posts.author, new_posts.id, new_posts.thread, new_posts.created
+		FROM recipients
+		JOIN new_posts ON new_posts.id=recipients.post
+		WHERE recipients.nickname<>new_posts.author
+		ORDER BY recipients.nickname, recipients.post, recipients.priority
+		ON CONFLICT (nickname, post) DO NOTHING`,
+		pq.Array(postIDs), pq.Array(mentionPosts), pq.Array(mentionNicknames),
+		models.NotificationReply, models.NotificationMention, models.NotificationThread)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (nr *NotificationPgRepository) InsertMentions(post *models.Post, mentions []string) error {
+	tx, err := nr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	if err := insertMentions(tx, post, mentions); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (nr *NotificationPgRepository) UpdateAsRead(nickname string, ids []uint64) error {
+	tx, err := nr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	var values []interface{}
+
+	updateQuery := `
+		UPDATE notifications
+		SET isread = TRUE
+		WHERE nickname=$1 AND NOT isread`
+	values = append(values, nickname)
+
+	// Mark all notifications if ids are not passed
+	var filterQuery string
+	if len(ids) != 0 {
+		filterQuery = "AND id IN " + placeholders.List(len(values)+1, len(ids))
+		for _, id := range ids {
+			values = append(values, id)
+		}
+	}
+
+	resultQuery := strings.Join([]string{
+		updateQuery,
+		filterQuery,
+	}, " ")
+
+	_, err = tx.Exec(resultQuery, values...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (nr *NotificationPgRepository) SelectUnreadCount(nickname string) (uint64, error) {
+	var count uint64
+
+	row := nr.dbConn.QueryRow(
+		`SELECT COUNT(*)
+		FROM notifications
+		WHERE nickname=$1 AND NOT isread`,
+		nickname)
+
+	err := row.Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (nr *NotificationPgRepository) SelectAllByNickname(
+	nickname string,
+	since uint64,
+	unread bool,
+	pgnt *models.Pagination) ([]*models.Notification, error) {
+
+	var values []interface{}
+
+	selectQuery := `
+		SELECT id, nickname, type, author, post, thread, isread, created
+		FROM notifications
+		WHERE nickname=$1`
+	values = append(values, nickname)
+
+	var sortQuery string
+	if pgnt.Desc {
+		sortQuery = "ORDER BY id DESC"
+	} else {
+		sortQuery = "ORDER BY id"
+	}
+
+	var pgntQuery string
+	if pgnt.Limit != 0 {
+		pgntQuery = "LIMIT $2"
+		values = append(values, pgnt.Limit)
+	}
+
+	var filterQuery string
+	if since != 0 {
+		ind := len(values) + 1
+		if pgnt.Desc {
+			filterQuery = "AND id < $" + strconv.Itoa(ind)
+		} else {
+			filterQuery = "AND id > $" + strconv.Itoa(ind)
+		}
+		values = append(values, since)
+	}
+
+	var unreadQuery string
+	if unread {
+		unreadQuery = "AND NOT isread"
+	}
+
+	resultQuery := strings.Join([]string{
+		selectQuery,
+		filterQuery,
+		unreadQuery,
+		sortQuery,
+		pgntQuery,
+	}, " ")
+
+	rows, err := nr.dbConn.Query(resultQuery, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*models.Notification
+	for rows.Next() {
+		notification := &models.Notification{}
+		err := rows.Scan(&notification.ID, &notification.Nickname, &notification.Type,
+			&notification.Author, &notification.Post, &notification.Thread,
+			&notification.IsRead, &notification.Created)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
@@ -0,0 +1,14 @@
+package notification
+
+import (
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+)
+
+type NotificationUsecase interface {
+	NotifyAboutPosts(posts []*models.Post) *errors.Error
+	NotifyAboutMentions(post *models.Post) *errors.Error
+	MarkAsRead(nickname string, ids []uint64) *errors.Error
+	GetUnreadCount(nickname string) (uint64, *errors.Error)
+	ListByNickname(nickname string, since uint64, unread bool, pgnt *models.Pagination) (*models.Notifications, *errors.Error)
+}
@@ -0,0 +1,88 @@
+package usecases
+
+import (
+	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/notification"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/mention"
+)
+
+type NotificationUsecase struct {
+	notificationRepo notification.NotificationRepository
+}
+
+func NewNotificationUsecase(repo notification.NotificationRepository) notification.NotificationUsecase {
+	return &NotificationUsecase{
+		notificationRepo: repo,
+	}
+}
+
+func (nu *NotificationUsecase) NotifyAboutPosts(posts []*models.Post) *errors.Error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	mentions := make([][]string, len(posts))
+	for ind, post := range posts {
+		mentions[ind] = mention.Parse(post.Message)
+	}
+
+	if err := nu.notificationRepo.InsertByPosts(posts, mentions); err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	return nil
+}
+
+func (nu *NotificationUsecase) NotifyAboutMentions(post *models.Post) *errors.Error {
+	// Already notified users are skipped by repository
+	mentions := mention.Parse(post.Message)
+	if len(mentions) == 0 {
+		return nil
+	}
+
+	if err := nu.notificationRepo.InsertMentions(post, mentions); err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	return nil
+}
+
+func (nu *NotificationUsecase) MarkAsRead(nickname string, ids []uint64) *errors.Error {
+	if err := nu.notificationRepo.UpdateAsRead(nickname, ids); err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	return nil
+}
+
+func (nu *NotificationUsecase) GetUnreadCount(nickname string) (uint64, *errors.Error) {
+	count, err := nu.notificationRepo.SelectUnreadCount(nickname)
+	if err != nil {
+		return 0, errors.New(CodeInternalError, err)
+	}
+	return count, nil
+}
+
+func (nu *NotificationUsecase) ListByNickname(
+	nickname string,
+	since uint64,
+	unread bool,
+	pgnt *models.Pagination) (*models.Notifications, *errors.Error) {
+
+	count, customErr := nu.GetUnreadCount(nickname)
+	if customErr != nil {
+		return nil, customErr
+	}
+
+	notifications, err := nu.notificationRepo.SelectAllByNickname(nickname, since, unread, pgnt)
+	if err != nil {
+		return nil, errors.New(CodeInternalError, err)
+	}
+	if len(notifications) == 0 {
+		notifications = []*models.Notification{}
+	}
+
+	return &models.Notifications{
+		Unread:        count,
+		Notifications: notifications,
+	}, nil
+}
@@ -0,0 +1,85 @@
+package delivery
+
+import (
+	"net/http"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/mwares"
+	"github.com/OlegGibadulin/tech-db-forum/internal/poll"
+	"github.com/OlegGibadulin/tech-db-forum/internal/thread"
+	"github.com/OlegGibadulin/tech-db-forum/internal/user"
+	reader "github.com/OlegGibadulin/tech-db-forum/tools/request_reader"
+	"github.com/labstack/echo/v4"
+)
+
+type PollHandler struct {
+	pollUcase   poll.PollUsecase
+	threadUcase thread.ThreadUsecase
+	userUcase   user.UserUsecase
+}
+
+func NewPollHandler(pollUcase poll.PollUsecase, threadUcase thread.ThreadUsecase,
+	userUcase user.UserUsecase) *PollHandler {
+	return &PollHandler{
+		pollUcase:   pollUcase,
+		threadUcase: threadUcase,
+		userUcase:   userUcase,
+	}
+}
+
+func (ph *PollHandler) Configure(e *echo.Echo, mw *mwares.MiddlewareManager) {
+	e.GET("/api/thread/:slug_or_id/poll", ph.GetPollHandler())
+	e.POST("/api/thread/:slug_or_id/poll/vote", ph.VotePollHandler())
+}
+
+func (ph *PollHandler) GetPollHandler() echo.HandlerFunc {
+	return func(cntx echo.Context) error {
+		threadID, err := ph.threadUcase.CheckThreadExistence(cntx.Param("slug_or_id"))
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		poll, err := ph.pollUcase.GetByThread(threadID)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, poll)
+	}
+}
+
+func (ph *PollHandler) VotePollHandler() echo.HandlerFunc {
+	type Request struct {
+		models.Ballot
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		user, err := ph.userUcase.GetByNickname(req.Nickname)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		req.Nickname = user.Nickname
+
+		thread, err := ph.threadUcase.GetBySlugOrID(cntx.Param("slug_or_id"))
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		req.Thread = thread.ID
+
+		poll, err := ph.pollUcase.Vote(&req.Ballot, thread.Forum)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, poll)
+	}
+}
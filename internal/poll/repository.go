@@ -0,0 +1,11 @@
+package poll
+
+import (
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+)
+
+type PollRepository interface {
+	InsertBallot(ballot *models.Ballot) error
+	SelectByThread(threadID uint64) (*models.Poll, error)
+	SelectBallot(threadID uint64, nickname string) (*models.Ballot, error)
+}
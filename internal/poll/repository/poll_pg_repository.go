@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/poll"
+	"github.com/lib/pq"
+)
+
+type PollPgRepository struct {
+	dbConn *sql.DB
+}
+
+func NewPollPgRepository(conn *sql.DB) poll.PollRepository {
+	return &PollPgRepository{
+		dbConn: conn,
+	}
+}
+
+func (pr *PollPgRepository) InsertBallot(ballot *models.Ballot) error {
+	tx, err := pr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int64, 0, len(ballot.Options))
+	for _, optionID := range ballot.Options {
+		ids = append(ids, int64(optionID))
+	}
+
+	row := tx.QueryRow(
+		`INSERT INTO poll_ballots(thread, nickname, options)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (thread, nickname) DO NOTHING
+		RETURNING created`,
+		ballot.Thread, ballot.Nickname, pq.Array(ids))
+
+	if err := row.Scan(&ballot.Created); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (pr *PollPgRepository) SelectByThread(threadID uint64) (*models.Poll, error) {
+	poll := &models.Poll{}
+	var closes sql.NullTime
+
+	row := pr.dbConn.QueryRow(
+		`SELECT thread, question, multiple, closes, voters, created
+		FROM polls
+		WHERE thread=$1`,
+		threadID)
+
+	err := row.Scan(&poll.Thread, &poll.Question, &poll.Multiple, &closes, &poll.Voters, &poll.Created)
+	if err != nil {
+		return nil, err
+	}
+	if closes.Valid {
+		poll.Closes = &closes.Time
+	}
+
+	rows, err := pr.dbConn.Query(
+		`SELECT id, text, votes
+		FROM poll_options
+		WHERE thread=$1
+		ORDER BY position`,
+		threadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		option := &models.PollOption{}
+		if err := rows.Scan(&option.ID, &option.Text, &option.Votes); err != nil {
+			return nil, err
+		}
+		poll.Options = append(poll.Options, option)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return poll, nil
+}
+
+func (pr *PollPgRepository) SelectBallot(threadID uint64, nickname string) (*models.Ballot, error) {
+	ballot := &models.Ballot{}
+	var ids []int64
+
+	row := pr.dbConn.QueryRow(
+		`SELECT thread, nickname, options, created
+		FROM poll_ballots
+		WHERE thread=$1 AND nickname=$2`,
+		threadID, nickname)
+
+	err := row.Scan(&ballot.Thread, &ballot.Nickname, pq.Array(&ids), &ballot.Created)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		ballot.Options = append(ballot.Options, uint64(id))
+	}
+	return ballot, nil
+}
@@ -0,0 +1,13 @@
+package poll
+
+import (
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+)
+
+type PollUsecase interface {
+	Check(poll *models.Poll) *errors.Error
+	GetByThread(threadID uint64) (*models.Poll, *errors.Error)
+	FillThread(thread *models.Thread) *errors.Error
+	Vote(ballot *models.Ballot, forumSlug string) (*models.Poll, *errors.Error)
+}
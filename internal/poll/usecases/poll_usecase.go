@@ -0,0 +1,145 @@
+package usecases
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/poll"
+	"github.com/OlegGibadulin/tech-db-forum/internal/sanction"
+)
+
+const (
+	minPollOptions = 2
+	maxPollOptions = 20
+)
+
+type PollUsecase struct {
+	pollRepo      poll.PollRepository
+	sanctionUcase sanction.SanctionUsecase
+}
+
+func NewPollUsecase(repo poll.PollRepository, sanctionUcase sanction.SanctionUsecase) poll.PollUsecase {
+	return &PollUsecase{
+		pollRepo:      repo,
+		sanctionUcase: sanctionUcase,
+	}
+}
+
+// Check validates poll before thread is created, poll is inserted together with its thread
+func (pu *PollUsecase) Check(poll *models.Poll) *errors.Error {
+	poll.Question = strings.TrimSpace(poll.Question)
+	if poll.Question == "" {
+		return errors.BuildByMsg(CodeWrongPoll, "question is empty")
+	}
+
+	if len(poll.Options) < minPollOptions || len(poll.Options) > maxPollOptions {
+		return errors.BuildByMsg(CodeWrongPoll,
+			fmt.Sprintf("poll must have from %d to %d options, got %d", minPollOptions, maxPollOptions, len(poll.Options)))
+	}
+	texts := map[string]bool{}
+	for _, option := range poll.Options {
+		if option == nil {
+			return errors.BuildByMsg(CodeWrongPoll, "option is empty")
+		}
+		option.Text = strings.TrimSpace(option.Text)
+		if option.Text == "" {
+			return errors.BuildByMsg(CodeWrongPoll, "option is empty")
+		}
+		if texts[option.Text] {
+			return errors.BuildByMsg(CodeWrongPoll, "option "+option.Text+" is repeated")
+		}
+		texts[option.Text] = true
+	}
+
+	if poll.IsClosed(time.Now()) {
+		return errors.BuildByMsg(CodeWrongPoll, "closing time "+poll.Closes.Format(time.RFC3339)+" is in the past")
+	}
+	return nil
+}
+
+func (pu *PollUsecase) GetByThread(threadID uint64) (*models.Poll, *errors.Error) {
+	poll, err := pu.pollRepo.SelectByThread(threadID)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, errors.BuildByMsg(CodePollDoesNotExist, threadID)
+	case err != nil:
+		return nil, errors.New(CodeInternalError, err)
+	}
+	return poll, nil
+}
+
+// FillThread sets poll of thread, thread without poll is left as it is
+func (pu *PollUsecase) FillThread(thread *models.Thread) *errors.Error {
+	poll, customErr := pu.GetByThread(thread.ID)
+	if customErr != nil {
+		if customErr.Code == CodePollDoesNotExist {
+			return nil
+		}
+		return customErr
+	}
+	thread.Poll = poll
+	return nil
+}
+
+// Vote casts ballot in poll of thread from forum, banned and muted users can't vote like in threads
+func (pu *PollUsecase) Vote(ballot *models.Ballot, forumSlug string) (*models.Poll, *errors.Error) {
+	if customErr := pu.sanctionUcase.CheckUsers([]string{ballot.Nickname}, forumSlug); customErr != nil {
+		return nil, customErr
+	}
+
+	poll, customErr := pu.GetByThread(ballot.Thread)
+	if customErr != nil {
+		return nil, customErr
+	}
+	if poll.IsClosed(time.Now()) {
+		return nil, errors.BuildByMsg(CodePollIsClosed, poll.Thread, poll.Closes.Format(time.RFC3339))
+	}
+
+	if customErr := checkBallot(poll, ballot); customErr != nil {
+		return nil, customErr
+	}
+
+	// Ballot isn't inserted if user has already voted, even concurrently
+	err := pu.pollRepo.InsertBallot(ballot)
+	switch {
+	case err == sql.ErrNoRows:
+		anotherBallot, err := pu.pollRepo.SelectBallot(ballot.Thread, ballot.Nickname)
+		if err != nil {
+			return nil, errors.New(CodeInternalError, err)
+		}
+		return nil, errors.BuildByBody(CodeBallotAlreadyExists, anotherBallot)
+	case err != nil:
+		return nil, errors.New(CodeInternalError, err)
+	}
+	return pu.GetByThread(ballot.Thread)
+}
+
+func checkBallot(poll *models.Poll, ballot *models.Ballot) *errors.Error {
+	if len(ballot.Options) == 0 {
+		return errors.BuildByMsg(CodeWrongBallot, "no option is chosen")
+	}
+	if !poll.Multiple && len(ballot.Options) > 1 {
+		return errors.BuildByMsg(CodeWrongBallot, "only one option can be chosen")
+	}
+
+	pollOptions := map[uint64]bool{}
+	for _, option := range poll.Options {
+		pollOptions[option.ID] = true
+	}
+	chosen := map[uint64]bool{}
+	for _, optionID := range ballot.Options {
+		if !pollOptions[optionID] {
+			return errors.BuildByMsg(CodeWrongBallot, fmt.Sprintf("option %d isn't in poll", optionID))
+		}
+		if chosen[optionID] {
+			return errors.BuildByMsg(CodeWrongBallot, fmt.Sprintf("option %d is chosen twice", optionID))
+		}
+		chosen[optionID] = true
+	}
+	return nil
+}
@@ -5,11 +5,15 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/OlegGibadulin/tech-db-forum/internal/attachment"
+	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
 	"github.com/OlegGibadulin/tech-db-forum/internal/forum"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/conditional"
 	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
 	"github.com/OlegGibadulin/tech-db-forum/internal/models"
 	"github.com/OlegGibadulin/tech-db-forum/internal/mwares"
 	"github.com/OlegGibadulin/tech-db-forum/internal/post"
+	"github.com/OlegGibadulin/tech-db-forum/internal/report"
 	"github.com/OlegGibadulin/tech-db-forum/internal/thread"
 	"github.com/OlegGibadulin/tech-db-forum/internal/user"
 	reader "github.com/OlegGibadulin/tech-db-forum/tools/request_reader"
@@ -17,25 +21,31 @@ import (
 )
 
 type PostHandler struct {
-	postUcase   post.PostUsecase
-	userUcase   user.UserUsecase
-	threadUcase thread.ThreadUsecase
-	forumUcase  forum.ForumUsecase
+	postUcase       post.PostUsecase
+	userUcase       user.UserUsecase
+	threadUcase     thread.ThreadUsecase
+	forumUcase      forum.ForumUsecase
+	reportUcase     report.ReportUsecase
+	attachmentUcase attachment.AttachmentUsecase
 }
 
-func NewPostHandler(postUcase post.PostUsecase, userUcase user.UserUsecase,
-	threadUcase thread.ThreadUsecase, forumUcase forum.ForumUsecase) *PostHandler {
+func NewPostHandler(postUcase post.PostUsecase, userUcase user.UserUsecase, threadUcase thread.ThreadUsecase,
+	forumUcase forum.ForumUsecase, reportUcase report.ReportUsecase,
+	attachmentUcase attachment.AttachmentUsecase) *PostHandler {
 	return &PostHandler{
-		postUcase:   postUcase,
-		userUcase:   userUcase,
-		threadUcase: threadUcase,
-		forumUcase:  forumUcase,
+		postUcase:       postUcase,
+		userUcase:       userUcase,
+		threadUcase:     threadUcase,
+		forumUcase:      forumUcase,
+		reportUcase:     reportUcase,
+		attachmentUcase: attachmentUcase,
 	}
 }
 
 func (ph *PostHandler) Configure(e *echo.Echo, mw *mwares.MiddlewareManager) {
 	e.GET("/api/post/:pid/details", ph.GetPostDetailesHandler())
 	e.POST("/api/post/:pid/details", ph.UpdatePostHandler())
+	e.POST("/api/post/:pid/vote", ph.VotePostHandler())
 }
 
 func (ph *PostHandler) UpdatePostHandler() echo.HandlerFunc {
@@ -50,26 +60,71 @@ func (ph *PostHandler) UpdatePostHandler() echo.HandlerFunc {
 			return cntx.JSON(err.HTTPCode, err.Response())
 		}
 
-		postID, _ := strconv.ParseUint(cntx.Param("pid"), 10, 64)
 		postData := &models.Post{
 			Message: req.Message,
 		}
 
+		postID, _ := strconv.ParseUint(cntx.Param("pid"), 10, 64)
+		if conditional.HasPrecondition(cntx) {
+			post, err := ph.postUcase.GetByID(postID)
+			if err != nil {
+				// logrus.Error(err.Message)
+				return cntx.JSON(err.HTTPCode, err.Response())
+			}
+
+			etag := conditional.ETag(post.Version, post.Updated)
+			if !conditional.MatchesPrecondition(cntx, etag) {
+				err := errors.BuildByMsg(CodePreconditionFailed, etag)
+				return cntx.JSON(err.HTTPCode, err.Response())
+			}
+			postData.EditVersion = post.EditVersion
+		}
+
 		post, err := ph.postUcase.Update(postID, postData)
 		if err != nil {
 			// logrus.Error(err.Message)
 			return cntx.JSON(err.HTTPCode, err.Response())
 		}
+
+		cntx.Response().Header().Set("ETag", conditional.ETag(post.Version, post.Updated))
+		return cntx.JSON(http.StatusOK, post)
+	}
+}
+
+func (ph *PostHandler) VotePostHandler() echo.HandlerFunc {
+	type Request struct {
+		models.Vote
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		if _, err := ph.userUcase.GetByNickname(req.Nickname); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		postID, _ := strconv.ParseUint(cntx.Param("pid"), 10, 64)
+		post, err := ph.postUcase.Vote(postID, &req.Vote)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
 		return cntx.JSON(http.StatusOK, post)
 	}
 }
 
 func (ph *PostHandler) GetPostDetailesHandler() echo.HandlerFunc {
 	type Response struct {
-		Post   *models.Post   `json:"post"`
-		Author *models.User   `json:"author"`
-		Thread *models.Thread `json:"thread"`
-		Forum  *models.Forum  `json:"forum"`
+		Post    *models.Post     `json:"post"`
+		Author  *models.User     `json:"author"`
+		Thread  *models.Thread   `json:"thread"`
+		Forum   *models.Forum    `json:"forum"`
+		Reports []*models.Report `json:"reports,omitempty"`
 	}
 
 	return func(cntx echo.Context) error {
@@ -84,6 +139,11 @@ func (ph *PostHandler) GetPostDetailesHandler() echo.HandlerFunc {
 			return cntx.JSON(err.HTTPCode, err.Response())
 		}
 
+		// Related entities have own versions, so only bare post gets tag for conditional updates
+		if cntx.QueryParam("related") == "" {
+			cntx.Response().Header().Set("ETag", conditional.ETag(res.Post.Version, res.Post.Updated))
+		}
+
 		for _, param := range related {
 			switch param {
 			case "user":
@@ -101,6 +161,29 @@ func (ph *PostHandler) GetPostDetailesHandler() echo.HandlerFunc {
 					// logrus.Error(err.Message)
 					return cntx.JSON(err.HTTPCode, err.Response())
 				}
+			case "reports":
+				if res.Reports, err = ph.reportUcase.ListByPost(postID); err != nil {
+					// logrus.Error(err.Message)
+					return cntx.JSON(err.HTTPCode, err.Response())
+				}
+			}
+		}
+
+		if err := ph.attachmentUcase.FillPosts([]*models.Post{res.Post}); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		if cntx.QueryParam("format") == models.FormatHTML {
+			if err := ph.postUcase.RenderHTML([]*models.Post{res.Post}); err != nil {
+				// logrus.Error(err.Message)
+				return cntx.JSON(err.HTTPCode, err.Response())
+			}
+			if res.Thread != nil {
+				if err := ph.threadUcase.RenderHTML([]*models.Thread{res.Thread}); err != nil {
+					// logrus.Error(err.Message)
+					return cntx.JSON(err.HTTPCode, err.Response())
+				}
 			}
 		}
 		return cntx.JSON(http.StatusOK, res)
@@ -4,11 +4,26 @@ import (
 	"github.com/OlegGibadulin/tech-db-forum/internal/models"
 )
 
+// PostSender receives listed posts one by one, so listing doesn't have to fit into memory
+type PostSender func(post *models.Post) error
+
 type PostRepository interface {
 	Insert(posts []*models.Post, thread *models.Thread) error
 	Update(post *models.Post) error
+	VoteByID(postID uint64, vote *models.Vote) error
+	DeleteVoteByID(postID uint64, nickname string) error
 	SelectByID(postID uint64) (*models.Post, error)
+	SelectAllByIDs(postIDs []uint64) ([]*models.Post, error)
 	SelectAllByThreadFlat(threadID uint64, since uint64, pgnt *models.Pagination) ([]*models.Post, error)
 	SelectAllByThreadTree(threadID uint64, since uint64, pgnt *models.Pagination) ([]*models.Post, error)
 	SelectAllByThreadParentTree(threadID uint64, since uint64, pgnt *models.Pagination) ([]*models.Post, error)
+	SelectAllByAuthor(nickname string, since uint64, pgnt *models.Pagination) ([]*models.Post, error)
+	SelectVotesByNickname(nickname string, since uint64, pgnt *models.Pagination) ([]*models.Vote, error)
+	SelectFeed(nickname string, since uint64, pgnt *models.Pagination) ([]*models.Post, error)
+	SelectAllByThreadScore(threadID uint64, since uint64, pgnt *models.Pagination) ([]*models.Post, error)
+	StreamAllByThreadFlat(threadID uint64, since uint64, pgnt *models.Pagination, send PostSender) error
+	StreamAllByThreadTree(threadID uint64, since uint64, pgnt *models.Pagination, send PostSender) error
+	StreamAllByThreadParentTree(threadID uint64, since uint64, pgnt *models.Pagination, send PostSender) error
+	StreamAllByThreadScore(threadID uint64, since uint64, pgnt *models.Pagination, send PostSender) error
+	StreamAllByAuthor(nickname string, since uint64, pgnt *models.Pagination, send PostSender) error
 }
@@ -8,8 +8,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/placeholders"
 	"github.com/OlegGibadulin/tech-db-forum/internal/models"
 	"github.com/OlegGibadulin/tech-db-forum/internal/post"
+	"github.com/lib/pq"
 )
 
 type PostPgRepository struct {
@@ -22,25 +24,6 @@ func NewPostPgRepository(conn *sql.DB) post.PostRepository {
 	}
 }
 
-func buildValuesQuery(rowsCount, valuesCount int) string {
-	/*
-		VALUES ($1, $2, ...),
-		...
-	*/
-	var valuesQueries []string
-	for i := 0; i < rowsCount; i++ {
-		var values []string
-		for j := 0; j < valuesCount; j++ {
-			value := i*valuesCount + j + 1
-			values = append(values, "$"+strconv.Itoa(value))
-		}
-		valuesQuery := fmt.Sprintf("(%s)", strings.Join(values, ", "))
-		valuesQueries = append(valuesQueries, valuesQuery)
-	}
-	joinedValues := strings.Join(valuesQueries, ", ")
-	return fmt.Sprintf("VALUES %s", joinedValues)
-}
-
 func (pr *PostPgRepository) Insert(posts []*models.Post, thread *models.Thread) error {
 	tx, err := pr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
 	if err != nil {
@@ -50,13 +33,14 @@ func (pr *PostPgRepository) Insert(posts []*models.Post, thread *models.Thread)
 	var values []interface{}
 	created := time.Now()
 
-	selectQuery := "INSERT INTO posts(parent, author, message, forum, thread, created)"
-	returnQuery := "RETURNING id, isedited, forum, thread, created"
+	selectQuery := "INSERT INTO posts(parent, author, message, forum, thread, created, status)"
+	returnQuery := "RETURNING id, isedited, forum, thread, created, version, edit_version"
 
 	for _, post := range posts {
-		values = append(values, post.Parent, post.Author, post.Message, thread.Forum, thread.ID, created)
+		values = append(values, post.Parent, post.Author, post.Message, thread.Forum, thread.ID, created,
+			post.Status)
 	}
-	valuesQuery := buildValuesQuery(len(posts), 6)
+	valuesQuery := placeholders.Rows(len(posts), 7)
 
 	resultQuery := strings.Join([]string{
 		selectQuery,
@@ -74,7 +58,7 @@ func (pr *PostPgRepository) Insert(posts []*models.Post, thread *models.Thread)
 	ind := 0
 	for rows.Next() {
 		err := rows.Scan(&posts[ind].ID, &posts[ind].IsEdited, &posts[ind].Forum,
-			&posts[ind].Thread, &posts[ind].Created)
+			&posts[ind].Thread, &posts[ind].Created, &posts[ind].Version, &posts[ind].EditVersion)
 		if err != nil {
 			tx.Rollback()
 			return err
@@ -98,11 +82,57 @@ func (pr *PostPgRepository) Update(post *models.Post) error {
 		return err
 	}
 
-	_, err = tx.Exec(
+	row := tx.QueryRow(
 		`UPDATE posts
 		SET message = $2
-		WHERE id = $1`,
-		post.ID, post.Message)
+		WHERE id = $1 AND edit_version = $3
+		RETURNING isedited, version, updated, edit_version`,
+		post.ID, post.Message, post.EditVersion)
+
+	err = row.Scan(&post.IsEdited, &post.Version, &post.Updated, &post.EditVersion)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (pr *PostPgRepository) VoteByID(postID uint64, vote *models.Vote) error {
+	tx, err := pr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO post_votes(nickname, post, voice)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (nickname, post) DO UPDATE SET voice = $3`,
+		vote.Nickname, postID, vote.Voice)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (pr *PostPgRepository) DeleteVoteByID(postID uint64, nickname string) error {
+	tx, err := pr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`DELETE FROM post_votes
+		WHERE nickname = $1 AND post = $2`,
+		nickname, postID)
 	if err != nil {
 		tx.Rollback()
 		return err
@@ -118,30 +148,97 @@ func (pr *PostPgRepository) SelectByID(postID uint64) (*models.Post, error) {
 	post := &models.Post{}
 
 	row := pr.dbConn.QueryRow(
-		`SELECT id, parent, author, message, isedited, forum, thread, created
+		`SELECT id, parent, author, message, isedited, forum, thread, created, score, version, updated, edit_version
 		FROM posts
 		WHERE id=$1`,
 		postID)
 
 	err := row.Scan(&post.ID, &post.Parent, &post.Author, &post.Message, &post.IsEdited,
-		&post.Forum, &post.Thread, &post.Created)
+		&post.Forum, &post.Thread, &post.Created, &post.Score, &post.Version, &post.Updated, &post.EditVersion)
 	if err != nil {
 		return nil, err
 	}
 	return post, nil
 }
 
-func (pr *PostPgRepository) SelectAllByThreadFlat(
+func (pr *PostPgRepository) SelectAllByIDs(postIDs []uint64) ([]*models.Post, error) {
+	ids := make([]int64, 0, len(postIDs))
+	for _, postID := range postIDs {
+		ids = append(ids, int64(postID))
+	}
+
+	rows, err := pr.dbConn.Query(
+		`SELECT id, parent, author, message, isedited, forum, thread, created, score, version, updated, edit_version
+		FROM posts
+		WHERE id = ANY($1)`,
+		pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []*models.Post
+	for rows.Next() {
+		post := &models.Post{}
+		err := rows.Scan(&post.ID, &post.Parent, &post.Author, &post.Message, &post.IsEdited,
+			&post.Forum, &post.Thread, &post.Created, &post.Score, &post.Version, &post.Updated, &post.EditVersion)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// selectPosts and streamPosts share listing queries, so streamed listing is the same as paged one
+func (pr *PostPgRepository) selectPosts(query string, values []interface{}) ([]*models.Post, error) {
+	var posts []*models.Post
+	err := pr.streamPosts(query, values, func(post *models.Post) error {
+		posts = append(posts, post)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+func (pr *PostPgRepository) streamPosts(query string, values []interface{}, send post.PostSender) error {
+	rows, err := pr.dbConn.Query(query, values...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		post := &models.Post{}
+		err := rows.Scan(&post.ID, &post.Parent, &post.Author, &post.Message, &post.IsEdited,
+			&post.Forum, &post.Thread, &post.Created, &post.Score)
+		if err != nil {
+			return err
+		}
+		if err := send(post); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func getSelectByThreadFlatQuery(
 	threadID uint64,
 	since uint64,
-	pgnt *models.Pagination) ([]*models.Post, error) {
+	pgnt *models.Pagination) (string, []interface{}) {
 
 	var values []interface{}
 
 	selectQuery := `
-		SELECT id, parent, author, message, isedited, forum, thread, created
+		SELECT id, parent, author, message, isedited, forum, thread, created, score
 		FROM posts
-		WHERE thread=$1`
+		WHERE thread=$1
+		AND status='approved'`
 	values = append(values, threadID)
 
 	var sortQuery string
@@ -175,39 +272,39 @@ func (pr *PostPgRepository) SelectAllByThreadFlat(
 		pgntQuery,
 	}, " ")
 
-	rows, err := pr.dbConn.Query(resultQuery, values...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var posts []*models.Post
-	for rows.Next() {
-		post := &models.Post{}
-		err := rows.Scan(&post.ID, &post.Parent, &post.Author, &post.Message, &post.IsEdited,
-			&post.Forum, &post.Thread, &post.Created)
-		if err != nil {
-			return nil, err
-		}
-		posts = append(posts, post)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return posts, nil
+	return resultQuery, values
 }
 
-func (pr *PostPgRepository) SelectAllByThreadTree(
+func (pr *PostPgRepository) SelectAllByThreadFlat(
 	threadID uint64,
 	since uint64,
 	pgnt *models.Pagination) ([]*models.Post, error) {
 
+	return pr.selectPosts(getSelectByThreadFlatQuery(threadID, since, pgnt))
+}
+
+func (pr *PostPgRepository) StreamAllByThreadFlat(
+	threadID uint64,
+	since uint64,
+	pgnt *models.Pagination,
+	send post.PostSender) error {
+
+	query, values := getSelectByThreadFlatQuery(threadID, since, pgnt)
+	return pr.streamPosts(query, values, send)
+}
+
+func getSelectByThreadTreeQuery(
+	threadID uint64,
+	since uint64,
+	pgnt *models.Pagination) (string, []interface{}) {
+
 	var values []interface{}
 
 	selectQuery := `
-		SELECT id, parent, author, message, isedited, forum, thread, created
+		SELECT id, parent, author, message, isedited, forum, thread, created, score
 		FROM posts
-		WHERE thread=$1`
+		WHERE thread=$1
+		AND status='approved'`
 	values = append(values, threadID)
 
 	var sortQuery string
@@ -249,26 +346,25 @@ func (pr *PostPgRepository) SelectAllByThreadTree(
 		pgntQuery,
 	}, " ")
 
-	rows, err := pr.dbConn.Query(resultQuery, values...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+	return resultQuery, values
+}
 
-	var posts []*models.Post
-	for rows.Next() {
-		post := &models.Post{}
-		err := rows.Scan(&post.ID, &post.Parent, &post.Author, &post.Message, &post.IsEdited,
-			&post.Forum, &post.Thread, &post.Created)
-		if err != nil {
-			return nil, err
-		}
-		posts = append(posts, post)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return posts, nil
+func (pr *PostPgRepository) SelectAllByThreadTree(
+	threadID uint64,
+	since uint64,
+	pgnt *models.Pagination) ([]*models.Post, error) {
+
+	return pr.selectPosts(getSelectByThreadTreeQuery(threadID, since, pgnt))
+}
+
+func (pr *PostPgRepository) StreamAllByThreadTree(
+	threadID uint64,
+	since uint64,
+	pgnt *models.Pagination,
+	send post.PostSender) error {
+
+	query, values := getSelectByThreadTreeQuery(threadID, since, pgnt)
+	return pr.streamPosts(query, values, send)
 }
 
 func getSelectParentsQuery(
@@ -282,7 +378,8 @@ func getSelectParentsQuery(
 		SELECT id
 		FROM posts
 		WHERE thread=$1
-		AND parent=0`
+		AND parent=0
+		AND status='approved'`
 	values = append(values, threadID)
 
 	var sortQuery string
@@ -325,17 +422,18 @@ func getSelectParentsQuery(
 	return resultQuery, values
 }
 
-func (pr *PostPgRepository) SelectAllByThreadParentTree(
+func getSelectByThreadParentTreeQuery(
 	threadID uint64,
 	since uint64,
-	pgnt *models.Pagination) ([]*models.Post, error) {
+	pgnt *models.Pagination) (string, []interface{}) {
 
 	subSelectQuery, values := getSelectParentsQuery(threadID, since, pgnt)
 
 	selectQuery := `
-		SELECT id, parent, author, message, isedited, forum, thread, created
+		SELECT id, parent, author, message, isedited, forum, thread, created, score
 		FROM posts
-		WHERE path[1] IN`
+		WHERE status='approved'
+		AND path[1] IN`
 
 	var sortQuery string
 	if pgnt.Desc {
@@ -350,6 +448,217 @@ func (pr *PostPgRepository) SelectAllByThreadParentTree(
 		sortQuery,
 	}, " ")
 
+	return resultQuery, values
+}
+
+func (pr *PostPgRepository) SelectAllByThreadParentTree(
+	threadID uint64,
+	since uint64,
+	pgnt *models.Pagination) ([]*models.Post, error) {
+
+	return pr.selectPosts(getSelectByThreadParentTreeQuery(threadID, since, pgnt))
+}
+
+func (pr *PostPgRepository) StreamAllByThreadParentTree(
+	threadID uint64,
+	since uint64,
+	pgnt *models.Pagination,
+	send post.PostSender) error {
+
+	query, values := getSelectByThreadParentTreeQuery(threadID, since, pgnt)
+	return pr.streamPosts(query, values, send)
+}
+
+func getSelectByThreadScoreQuery(
+	threadID uint64,
+	since uint64,
+	pgnt *models.Pagination) (string, []interface{}) {
+
+	var values []interface{}
+
+	selectQuery := `
+		SELECT id, parent, author, message, isedited, forum, thread, created, score
+		FROM posts
+		WHERE thread=$1
+		AND status='approved'`
+	values = append(values, threadID)
+
+	var sortQuery string
+	if pgnt.Desc {
+		sortQuery = "ORDER BY score DESC, id DESC"
+	} else {
+		sortQuery = "ORDER BY score, id"
+	}
+
+	var pgntQuery string
+	if pgnt.Limit != 0 {
+		pgntQuery = "LIMIT $2"
+		values = append(values, pgnt.Limit)
+	}
+
+	var filterQuery string
+	if since != 0 {
+		ind := len(values) + 1
+		subSelectQuery := fmt.Sprintf("(SELECT score, id FROM posts WHERE id=$%d)", ind)
+
+		var subFilterQuery string
+		if pgnt.Desc {
+			subFilterQuery = "AND (score, id) <"
+		} else {
+			subFilterQuery = "AND (score, id) >"
+		}
+
+		filterQuery = strings.Join([]string{
+			subFilterQuery,
+			subSelectQuery,
+		}, " ")
+		values = append(values, since)
+	}
+
+	resultQuery := strings.Join([]string{
+		selectQuery,
+		filterQuery,
+		sortQuery,
+		pgntQuery,
+	}, " ")
+
+	return resultQuery, values
+}
+
+func (pr *PostPgRepository) SelectAllByThreadScore(
+	threadID uint64,
+	since uint64,
+	pgnt *models.Pagination) ([]*models.Post, error) {
+
+	return pr.selectPosts(getSelectByThreadScoreQuery(threadID, since, pgnt))
+}
+
+func (pr *PostPgRepository) StreamAllByThreadScore(
+	threadID uint64,
+	since uint64,
+	pgnt *models.Pagination,
+	send post.PostSender) error {
+
+	query, values := getSelectByThreadScoreQuery(threadID, since, pgnt)
+	return pr.streamPosts(query, values, send)
+}
+
+func getSelectByAuthorQuery(
+	nickname string,
+	since uint64,
+	pgnt *models.Pagination) (string, []interface{}) {
+
+	var values []interface{}
+
+	selectQuery := `
+		SELECT id, parent, author, message, isedited, forum, thread, created, score
+		FROM posts
+		WHERE author=$1
+		AND status='approved'`
+	values = append(values, nickname)
+
+	var sortQuery string
+	if pgnt.Desc {
+		sortQuery = "ORDER BY id DESC"
+	} else {
+		sortQuery = "ORDER BY id"
+	}
+
+	var pgntQuery string
+	if pgnt.Limit != 0 {
+		pgntQuery = "LIMIT $2"
+		values = append(values, pgnt.Limit)
+	}
+
+	var filterQuery string
+	if since != 0 {
+		ind := len(values) + 1
+		if pgnt.Desc {
+			filterQuery = "AND id < $" + strconv.Itoa(ind)
+		} else {
+			filterQuery = "AND id > $" + strconv.Itoa(ind)
+		}
+		values = append(values, since)
+	}
+
+	resultQuery := strings.Join([]string{
+		selectQuery,
+		filterQuery,
+		sortQuery,
+		pgntQuery,
+	}, " ")
+
+	return resultQuery, values
+}
+
+func (pr *PostPgRepository) SelectAllByAuthor(
+	nickname string,
+	since uint64,
+	pgnt *models.Pagination) ([]*models.Post, error) {
+
+	return pr.selectPosts(getSelectByAuthorQuery(nickname, since, pgnt))
+}
+
+func (pr *PostPgRepository) StreamAllByAuthor(
+	nickname string,
+	since uint64,
+	pgnt *models.Pagination,
+	send post.PostSender) error {
+
+	query, values := getSelectByAuthorQuery(nickname, since, pgnt)
+	return pr.streamPosts(query, values, send)
+}
+
+func (pr *PostPgRepository) SelectFeed(
+	nickname string,
+	since uint64,
+	pgnt *models.Pagination) ([]*models.Post, error) {
+
+	var values []interface{}
+
+	// New posts of followed users and posts in followed threads except own ones
+	selectQuery := `
+		SELECT id, parent, author, message, isedited, forum, thread, created, score
+		FROM posts
+		WHERE author<>$1
+		AND status='approved'
+		AND (
+			author IN (SELECT followee FROM user_follows WHERE follower=$1)
+			OR thread IN (SELECT thread FROM thread_follows WHERE nickname=$1)
+		)`
+	values = append(values, nickname)
+
+	var sortQuery string
+	if pgnt.Desc {
+		sortQuery = "ORDER BY id DESC"
+	} else {
+		sortQuery = "ORDER BY id"
+	}
+
+	var pgntQuery string
+	if pgnt.Limit != 0 {
+		pgntQuery = "LIMIT $2"
+		values = append(values, pgnt.Limit)
+	}
+
+	var filterQuery string
+	if since != 0 {
+		ind := len(values) + 1
+		if pgnt.Desc {
+			filterQuery = "AND id < $" + strconv.Itoa(ind)
+		} else {
+			filterQuery = "AND id > $" + strconv.Itoa(ind)
+		}
+		values = append(values, since)
+	}
+
+	resultQuery := strings.Join([]string{
+		selectQuery,
+		filterQuery,
+		sortQuery,
+		pgntQuery,
+	}, " ")
+
 	rows, err := pr.dbConn.Query(resultQuery, values...)
 	if err != nil {
 		return nil, err
@@ -360,7 +669,7 @@ func (pr *PostPgRepository) SelectAllByThreadParentTree(
 	for rows.Next() {
 		post := &models.Post{}
 		err := rows.Scan(&post.ID, &post.Parent, &post.Author, &post.Message, &post.IsEdited,
-			&post.Forum, &post.Thread, &post.Created)
+			&post.Forum, &post.Thread, &post.Created, &post.Score)
 		if err != nil {
 			return nil, err
 		}
@@ -371,3 +680,68 @@ func (pr *PostPgRepository) SelectAllByThreadParentTree(
 	}
 	return posts, nil
 }
+
+func (pr *PostPgRepository) SelectVotesByNickname(
+	nickname string,
+	since uint64,
+	pgnt *models.Pagination) ([]*models.Vote, error) {
+
+	var values []interface{}
+
+	selectQuery := `
+		SELECT nickname, voice, post
+		FROM post_votes
+		WHERE nickname=$1`
+	values = append(values, nickname)
+
+	var sortQuery string
+	if pgnt.Desc {
+		sortQuery = "ORDER BY post DESC"
+	} else {
+		sortQuery = "ORDER BY post"
+	}
+
+	var pgntQuery string
+	if pgnt.Limit != 0 {
+		pgntQuery = "LIMIT $2"
+		values = append(values, pgnt.Limit)
+	}
+
+	var filterQuery string
+	if since != 0 {
+		ind := len(values) + 1
+		if pgnt.Desc {
+			filterQuery = "AND post < $" + strconv.Itoa(ind)
+		} else {
+			filterQuery = "AND post > $" + strconv.Itoa(ind)
+		}
+		values = append(values, since)
+	}
+
+	resultQuery := strings.Join([]string{
+		selectQuery,
+		filterQuery,
+		sortQuery,
+		pgntQuery,
+	}, " ")
+
+	rows, err := pr.dbConn.Query(resultQuery, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var votes []*models.Vote
+	for rows.Next() {
+		vote := &models.Vote{}
+		err := rows.Scan(&vote.Nickname, &vote.Voice, &vote.Post)
+		if err != nil {
+			return nil, err
+		}
+		votes = append(votes, vote)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return votes, nil
+}
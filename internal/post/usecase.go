@@ -8,6 +8,14 @@ import (
 type PostUsecase interface {
 	Create(posts []*models.Post, thread *models.Thread) *errors.Error
 	Update(postID uint64, postData *models.Post) (*models.Post, *errors.Error)
+	RenderHTML(posts []*models.Post) *errors.Error
 	GetByID(postID uint64) (*models.Post, *errors.Error)
+	ListByIDs(postIDs []uint64) ([]*models.Post, *errors.Error)
+	Vote(postID uint64, vote *models.Vote) (*models.Post, *errors.Error)
 	ListByThread(threadID uint64, since uint64, pgnt *models.Pagination) ([]*models.Post, *errors.Error)
+	ListByAuthor(nickname string, since uint64, pgnt *models.Pagination) ([]*models.Post, *errors.Error)
+	StreamByThread(threadID uint64, since uint64, pgnt *models.Pagination, send PostSender) *errors.Error
+	StreamByAuthor(nickname string, since uint64, pgnt *models.Pagination, send PostSender) *errors.Error
+	ListVotesByNickname(nickname string, since uint64, pgnt *models.Pagination) ([]*models.Vote, *errors.Error)
+	ListFeed(nickname string, since uint64, pgnt *models.Pagination) ([]*models.Post, *errors.Error)
 }
@@ -2,21 +2,37 @@ package usecases
 
 import (
 	"database/sql"
+	"fmt"
 	"strconv"
 
 	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
 	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
 	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/moderation"
+	"github.com/OlegGibadulin/tech-db-forum/internal/notification"
 	"github.com/OlegGibadulin/tech-db-forum/internal/post"
+	"github.com/OlegGibadulin/tech-db-forum/internal/sanction"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/markdown"
+	"github.com/sirupsen/logrus"
 )
 
 type PostUsecase struct {
-	postRepo post.PostRepository
+	postRepo          post.PostRepository
+	notificationUcase notification.NotificationUsecase
+	sanctionUcase     sanction.SanctionUsecase
+	moderationUcase   moderation.ModerationUsecase
+	renderer          *markdown.Renderer
 }
 
-func NewPostUsecase(repo post.PostRepository) post.PostUsecase {
+func NewPostUsecase(repo post.PostRepository, notificationUcase notification.NotificationUsecase,
+	sanctionUcase sanction.SanctionUsecase, moderationUcase moderation.ModerationUsecase,
+	renderer *markdown.Renderer) post.PostUsecase {
 	return &PostUsecase{
-		postRepo: repo,
+		postRepo:          repo,
+		notificationUcase: notificationUcase,
+		sanctionUcase:     sanctionUcase,
+		moderationUcase:   moderationUcase,
+		renderer:          renderer,
 	}
 }
 
@@ -24,6 +40,18 @@ func (pu *PostUsecase) Create(posts []*models.Post, thread *models.Thread) *erro
 	if len(posts) == 0 {
 		return nil
 	}
+
+	var nicknames []string
+	for _, post := range posts {
+		nicknames = append(nicknames, post.Author)
+	}
+	if customErr := pu.sanctionUcase.CheckUsers(nicknames, thread.Forum); customErr != nil {
+		return customErr
+	}
+	if customErr := pu.moderationUcase.CheckPosts(posts, thread.Forum); customErr != nil {
+		return customErr
+	}
+
 	err := pu.postRepo.Insert(posts, thread)
 	if err != nil {
 		if err.Error() == OnPostInsertExceptionMsgConflict {
@@ -31,6 +59,19 @@ func (pu *PostUsecase) Create(posts []*models.Post, thread *models.Thread) *erro
 		}
 		return errors.New(CodeInternalError, err)
 	}
+
+	// Held posts are notified about on approval
+	var approvedPosts []*models.Post
+	for _, post := range posts {
+		if post.Status == models.StatusApproved {
+			approvedPosts = append(approvedPosts, post)
+		}
+	}
+
+	// Posts are already created, so failed notifications must not fail the request
+	if customErr := pu.notificationUcase.NotifyAboutPosts(approvedPosts); customErr != nil {
+		logrus.Warn(customErr.Message)
+	}
 	return nil
 }
 
@@ -40,17 +81,53 @@ func (pu *PostUsecase) Update(postID uint64, postData *models.Post) (*models.Pos
 		return nil, customErr
 	}
 
-	if postData.Message != "" && postData.Message != post.Message {
+	isMessageChanged := postData.Message != "" && postData.Message != post.Message
+	if isMessageChanged {
 		post.Message = postData.Message
 		post.IsEdited = true
 	}
+	// Votes don't bump edit version, so only concurrent edit of message is a conflict
+	if postData.EditVersion != 0 {
+		post.EditVersion = postData.EditVersion
+	}
 
-	if err := pu.postRepo.Update(post); err != nil {
+	err := pu.postRepo.Update(post)
+	switch {
+	case err == sql.ErrNoRows:
+		currentPost, customErr := pu.GetByID(postID)
+		if customErr != nil {
+			return nil, customErr
+		}
+		return nil, errors.BuildByBody(CodeVersionConflict, currentPost)
+	case err != nil:
 		return nil, errors.New(CodeInternalError, err)
 	}
+
+	if isMessageChanged {
+		pu.renderer.Invalidate(postRenderKey(post.ID))
+		if customErr := pu.notificationUcase.NotifyAboutMentions(post); customErr != nil {
+			logrus.Warn(customErr.Message)
+		}
+	}
 	return post, nil
 }
 
+func postRenderKey(postID uint64) string {
+	return fmt.Sprintf("post:%d", postID)
+}
+
+// RenderHTML fills rendered messages of posts, cached HTML is reused until message is updated
+func (pu *PostUsecase) RenderHTML(posts []*models.Post) *errors.Error {
+	for _, post := range posts {
+		html, err := pu.renderer.RenderCached(postRenderKey(post.ID), post.Message)
+		if err != nil {
+			return errors.New(CodeInternalError, err)
+		}
+		post.MessageHTML = html
+	}
+	return nil
+}
+
 func (pu *PostUsecase) GetByID(postID uint64) (*models.Post, *errors.Error) {
 	post, err := pu.postRepo.SelectByID(postID)
 	switch {
@@ -62,6 +139,46 @@ func (pu *PostUsecase) GetByID(postID uint64) (*models.Post, *errors.Error) {
 	return post, nil
 }
 
+func (pu *PostUsecase) ListByIDs(postIDs []uint64) ([]*models.Post, *errors.Error) {
+	if len(postIDs) == 0 {
+		return []*models.Post{}, nil
+	}
+	posts, err := pu.postRepo.SelectAllByIDs(postIDs)
+	if err != nil {
+		return nil, errors.New(CodeInternalError, err)
+	}
+	if len(posts) == 0 {
+		return []*models.Post{}, nil
+	}
+	return posts, nil
+}
+
+func (pu *PostUsecase) Vote(postID uint64, vote *models.Vote) (*models.Post, *errors.Error) {
+	if vote.Voice != models.VoiceUp && vote.Voice != models.VoiceDown && vote.Voice != models.VoiceRetract {
+		return nil, errors.BuildByMsg(CodeWrongVoice, vote.Voice)
+	}
+
+	if _, customErr := pu.GetByID(postID); customErr != nil {
+		return nil, customErr
+	}
+
+	var err error
+	if vote.Voice == models.VoiceRetract {
+		err = pu.postRepo.DeleteVoteByID(postID, vote.Nickname)
+	} else {
+		err = pu.postRepo.VoteByID(postID, vote)
+	}
+	if err != nil {
+		return nil, errors.New(CodeInternalError, err)
+	}
+
+	post, customErr := pu.GetByID(postID)
+	if customErr != nil {
+		return nil, customErr
+	}
+	return post, nil
+}
+
 func (pu *PostUsecase) ListByThread(threadID uint64, since uint64, pgnt *models.Pagination) ([]*models.Post, *errors.Error) {
 	var posts []*models.Post
 	var err error
@@ -71,6 +188,8 @@ func (pu *PostUsecase) ListByThread(threadID uint64, since uint64, pgnt *models.
 		posts, err = pu.postRepo.SelectAllByThreadTree(threadID, since, pgnt)
 	case models.ParentTree:
 		posts, err = pu.postRepo.SelectAllByThreadParentTree(threadID, since, pgnt)
+	case models.Score:
+		posts, err = pu.postRepo.SelectAllByThreadScore(threadID, since, pgnt)
 	default:
 		posts, err = pu.postRepo.SelectAllByThreadFlat(threadID, since, pgnt)
 	}
@@ -83,3 +202,64 @@ func (pu *PostUsecase) ListByThread(threadID uint64, since uint64, pgnt *models.
 	}
 	return posts, nil
 }
+
+func (pu *PostUsecase) ListByAuthor(nickname string, since uint64, pgnt *models.Pagination) ([]*models.Post, *errors.Error) {
+	posts, err := pu.postRepo.SelectAllByAuthor(nickname, since, pgnt)
+	if err != nil {
+		return nil, errors.New(CodeInternalError, err)
+	}
+	if len(posts) == 0 {
+		return []*models.Post{}, nil
+	}
+	return posts, nil
+}
+
+func (pu *PostUsecase) StreamByThread(threadID uint64, since uint64, pgnt *models.Pagination,
+	send post.PostSender) *errors.Error {
+	var err error
+
+	switch pgnt.Sort {
+	case models.Tree:
+		err = pu.postRepo.StreamAllByThreadTree(threadID, since, pgnt, send)
+	case models.ParentTree:
+		err = pu.postRepo.StreamAllByThreadParentTree(threadID, since, pgnt, send)
+	case models.Score:
+		err = pu.postRepo.StreamAllByThreadScore(threadID, since, pgnt, send)
+	default:
+		err = pu.postRepo.StreamAllByThreadFlat(threadID, since, pgnt, send)
+	}
+	if err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	return nil
+}
+
+func (pu *PostUsecase) StreamByAuthor(nickname string, since uint64, pgnt *models.Pagination,
+	send post.PostSender) *errors.Error {
+	if err := pu.postRepo.StreamAllByAuthor(nickname, since, pgnt, send); err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	return nil
+}
+
+func (pu *PostUsecase) ListFeed(nickname string, since uint64, pgnt *models.Pagination) ([]*models.Post, *errors.Error) {
+	posts, err := pu.postRepo.SelectFeed(nickname, since, pgnt)
+	if err != nil {
+		return nil, errors.New(CodeInternalError, err)
+	}
+	if len(posts) == 0 {
+		return []*models.Post{}, nil
+	}
+	return posts, nil
+}
+
+func (pu *PostUsecase) ListVotesByNickname(nickname string, since uint64, pgnt *models.Pagination) ([]*models.Vote, *errors.Error) {
+	votes, err := pu.postRepo.SelectVotesByNickname(nickname, since, pgnt)
+	if err != nil {
+		return nil, errors.New(CodeInternalError, err)
+	}
+	if len(votes) == 0 {
+		return []*models.Vote{}, nil
+	}
+	return votes, nil
+}
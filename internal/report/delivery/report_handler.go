@@ -0,0 +1,137 @@
+package delivery
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/forum"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/mwares"
+	"github.com/OlegGibadulin/tech-db-forum/internal/post"
+	"github.com/OlegGibadulin/tech-db-forum/internal/report"
+	"github.com/OlegGibadulin/tech-db-forum/internal/user"
+	reader "github.com/OlegGibadulin/tech-db-forum/tools/request_reader"
+	"github.com/labstack/echo/v4"
+)
+
+type ReportHandler struct {
+	reportUcase report.ReportUsecase
+	userUcase   user.UserUsecase
+	postUcase   post.PostUsecase
+	forumUcase  forum.ForumUsecase
+}
+
+func NewReportHandler(reportUcase report.ReportUsecase, userUcase user.UserUsecase,
+	postUcase post.PostUsecase, forumUcase forum.ForumUsecase) *ReportHandler {
+	return &ReportHandler{
+		reportUcase: reportUcase,
+		userUcase:   userUcase,
+		postUcase:   postUcase,
+		forumUcase:  forumUcase,
+	}
+}
+
+func (rh *ReportHandler) Configure(e *echo.Echo, mw *mwares.MiddlewareManager) {
+	e.POST("/api/post/:pid/report", rh.CreateReportHandler(), mw.Idempotency)
+	// Reports are filed by anyone, but only moderators see and decide them
+	e.POST("/api/post/:pid/reports/resolve", rh.ResolveReportsHandler(), mw.AdminOnly)
+	e.POST("/api/post/:pid/reports/dismiss", rh.DismissReportsHandler(), mw.AdminOnly)
+	e.GET("/api/forum/:slug/reports", rh.GetReportsInboxHandler(), mw.AdminOnly)
+}
+
+func (rh *ReportHandler) CreateReportHandler() echo.HandlerFunc {
+	type Request struct {
+		models.Report
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		user, err := rh.userUcase.GetByNickname(req.Nickname)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		req.Nickname = user.Nickname
+
+		postID, _ := strconv.ParseUint(cntx.Param("pid"), 10, 64)
+		post, err := rh.postUcase.GetByID(postID)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		req.Post = post.ID
+
+		if err := rh.reportUcase.Create(&req.Report); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusCreated, req.Report)
+	}
+}
+
+func (rh *ReportHandler) ResolveReportsHandler() echo.HandlerFunc {
+	return func(cntx echo.Context) error {
+		postID, _ := strconv.ParseUint(cntx.Param("pid"), 10, 64)
+		if err := rh.reportUcase.Resolve(postID); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		post, err := rh.postUcase.GetByID(postID)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, post)
+	}
+}
+
+func (rh *ReportHandler) DismissReportsHandler() echo.HandlerFunc {
+	return func(cntx echo.Context) error {
+		postID, _ := strconv.ParseUint(cntx.Param("pid"), 10, 64)
+		if err := rh.reportUcase.Dismiss(postID); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		post, err := rh.postUcase.GetByID(postID)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, post)
+	}
+}
+
+func (rh *ReportHandler) GetReportsInboxHandler() echo.HandlerFunc {
+	type Request struct {
+		Since uint64 `query:"since"`
+		models.Pagination
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		forum, err := rh.forumUcase.GetBySlug(cntx.Param("slug"))
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		reportedPosts, err := rh.reportUcase.ListInbox(forum.Slug, req.Since, &req.Pagination)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, reportedPosts)
+	}
+}
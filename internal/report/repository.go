@@ -0,0 +1,15 @@
+package report
+
+import (
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+)
+
+type ReportRepository interface {
+	Insert(report *models.Report) error
+	UpdateStatusByPost(postID uint64, status string) (int64, error)
+	UpdatePostStatus(postID uint64, fromStatuses []string, status string) error
+	SelectByPostAndNickname(postID uint64, nickname string) (*models.Report, error)
+	SelectOpenCountByPost(postID uint64) (int, error)
+	SelectAllByPost(postID uint64) ([]*models.Report, error)
+	SelectInbox(forumSlug string, since uint64, pgnt *models.Pagination) ([]*models.ReportedPost, error)
+}
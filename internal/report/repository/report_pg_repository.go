@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/placeholders"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/report"
+)
+
+type ReportPgRepository struct {
+	dbConn *sql.DB
+}
+
+func NewReportPgRepository(conn *sql.DB) report.ReportRepository {
+	return &ReportPgRepository{
+		dbConn: conn,
+	}
+}
+
+func (rr *ReportPgRepository) Insert(report *models.Report) error {
+	tx, err := rr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	row := tx.QueryRow(
+		`INSERT INTO post_reports(post, nickname, reason)
+		VALUES ($1, $2, $3)
+		RETURNING id, status, created`,
+		report.Post, report.Nickname, report.Reason)
+
+	err = row.Scan(&report.ID, &report.Status, &report.Created)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (rr *ReportPgRepository) UpdateStatusByPost(postID uint64, status string) (int64, error) {
+	tx, err := rr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.Exec(
+		`UPDATE post_reports
+		SET status = $2
+		WHERE post = $1 AND status = 'open'`,
+		postID, status)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (rr *ReportPgRepository) UpdatePostStatus(postID uint64, fromStatuses []string, status string) error {
+	tx, err := rr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	var values []interface{}
+
+	updateQuery := `
+		UPDATE posts
+		SET status = $2
+		WHERE id = $1`
+	values = append(values, postID, status)
+
+	filterQuery := "AND status IN " + placeholders.List(len(values)+1, len(fromStatuses))
+	for _, fromStatus := range fromStatuses {
+		values = append(values, fromStatus)
+	}
+
+	resultQuery := strings.Join([]string{
+		updateQuery,
+		filterQuery,
+	}, " ")
+
+	_, err = tx.Exec(resultQuery, values...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (rr *ReportPgRepository) SelectByPostAndNickname(postID uint64, nickname string) (*models.Report, error) {
+	report := &models.Report{}
+
+	row := rr.dbConn.QueryRow(
+		`SELECT id, post, nickname, reason, status, created
+		FROM post_reports
+		WHERE post=$1 AND nickname=$2`,
+		postID, nickname)
+
+	err := row.Scan(&report.ID, &report.Post, &report.Nickname, &report.Reason,
+		&report.Status, &report.Created)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (rr *ReportPgRepository) SelectOpenCountByPost(postID uint64) (int, error) {
+	var count int
+
+	row := rr.dbConn.QueryRow(
+		`SELECT COUNT(*)
+		FROM post_reports
+		WHERE post=$1 AND status='open'`,
+		postID)
+
+	if err := row.Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (rr *ReportPgRepository) SelectAllByPost(postID uint64) ([]*models.Report, error) {
+	rows, err := rr.dbConn.Query(
+		`SELECT id, post, nickname, reason, status, created
+		FROM post_reports
+		WHERE post=$1
+		ORDER BY id`,
+		postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []*models.Report
+	for rows.Next() {
+		report := &models.Report{}
+		err := rows.Scan(&report.ID, &report.Post, &report.Nickname, &report.Reason,
+			&report.Status, &report.Created)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+func (rr *ReportPgRepository) SelectInbox(forumSlug string, since uint64, pgnt *models.Pagination) ([]*models.ReportedPost, error) {
+	var values []interface{}
+
+	// Open reports aggregated by post
+	selectQuery := `
+		SELECT p.id, p.parent, p.author, p.message, p.isedited, p.forum, p.thread, p.created,
+			p.score, p.status, r.count, r.last
+		FROM posts AS p
+		JOIN (
+			SELECT post, COUNT(*) AS count, MAX(created) AS last
+			FROM post_reports
+			WHERE status='open'
+			GROUP BY post
+		) AS r ON r.post=p.id
+		WHERE p.forum=$1`
+	values = append(values, forumSlug)
+
+	var filterQuery string
+	if since != 0 {
+		ind := len(values) + 1
+		if pgnt.Desc {
+			filterQuery = "AND p.id < $" + strconv.Itoa(ind)
+		} else {
+			filterQuery = "AND p.id > $" + strconv.Itoa(ind)
+		}
+		values = append(values, since)
+	}
+
+	var sortQuery string
+	if pgnt.Desc {
+		sortQuery = "ORDER BY p.id DESC"
+	} else {
+		sortQuery = "ORDER BY p.id"
+	}
+
+	var pgntQuery string
+	if pgnt.Limit != 0 {
+		pgntQuery = "LIMIT $" + strconv.Itoa(len(values)+1)
+		values = append(values, pgnt.Limit)
+	}
+
+	resultQuery := strings.Join([]string{
+		selectQuery,
+		filterQuery,
+		sortQuery,
+		pgntQuery,
+	}, " ")
+
+	rows, err := rr.dbConn.Query(resultQuery, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reportedPosts []*models.ReportedPost
+	for rows.Next() {
+		reportedPost := &models.ReportedPost{
+			Post: &models.Post{},
+		}
+		post := reportedPost.Post
+		err := rows.Scan(&post.ID, &post.Parent, &post.Author, &post.Message, &post.IsEdited,
+			&post.Forum, &post.Thread, &post.Created, &post.Score, &post.Status,
+			&reportedPost.Reports, &reportedPost.LastReported)
+		if err != nil {
+			return nil, err
+		}
+		reportedPosts = append(reportedPosts, reportedPost)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return reportedPosts, nil
+}
@@ -0,0 +1,14 @@
+package report
+
+import (
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+)
+
+type ReportUsecase interface {
+	Create(report *models.Report) *errors.Error
+	Resolve(postID uint64) *errors.Error
+	Dismiss(postID uint64) *errors.Error
+	ListByPost(postID uint64) ([]*models.Report, *errors.Error)
+	ListInbox(forumSlug string, since uint64, pgnt *models.Pagination) ([]*models.ReportedPost, *errors.Error)
+}
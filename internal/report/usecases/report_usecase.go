@@ -0,0 +1,125 @@
+package usecases
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+
+	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/report"
+)
+
+type ReportUsecase struct {
+	reportRepo    report.ReportRepository
+	hideThreshold int
+}
+
+func NewReportUsecase(repo report.ReportRepository, hideThreshold int) report.ReportUsecase {
+	return &ReportUsecase{
+		reportRepo:    repo,
+		hideThreshold: hideThreshold,
+	}
+}
+
+func (ru *ReportUsecase) Create(report *models.Report) *errors.Error {
+	// Request validation is disabled, so required reason is checked here
+	if strings.TrimSpace(report.Reason) == "" {
+		return errors.BuildByMsg(CodeWrongReport, "reason is required")
+	}
+
+	anotherReport, err := ru.reportRepo.SelectByPostAndNickname(report.Post, report.Nickname)
+	switch {
+	case err == nil:
+		return errors.BuildByBody(CodeReportAlreadyExists, anotherReport)
+	case err != sql.ErrNoRows:
+		return errors.New(CodeInternalError, err)
+	}
+
+	err = ru.reportRepo.Insert(report)
+	switch {
+	case err != nil && err.Error() == OnReportInsertExceptionMsgConflict:
+		// Same user reported post concurrently after the check above
+		anotherReport, err := ru.reportRepo.SelectByPostAndNickname(report.Post, report.Nickname)
+		if err != nil {
+			return errors.New(CodeInternalError, err)
+		}
+		return errors.BuildByBody(CodeReportAlreadyExists, anotherReport)
+	case err != nil:
+		return errors.New(CodeInternalError, err)
+	}
+
+	// Zero threshold means that posts are never hidden automatically
+	if ru.hideThreshold == 0 {
+		return nil
+	}
+
+	count, err := ru.reportRepo.SelectOpenCountByPost(report.Post)
+	if err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	if count >= ru.hideThreshold {
+		err := ru.reportRepo.UpdatePostStatus(report.Post, []string{models.StatusApproved}, models.StatusHidden)
+		if err != nil {
+			return errors.New(CodeInternalError, err)
+		}
+	}
+	return nil
+}
+
+func (ru *ReportUsecase) Resolve(postID uint64) *errors.Error {
+	count, err := ru.reportRepo.UpdateStatusByPost(postID, models.ReportResolved)
+	if err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	if count == 0 {
+		return errors.BuildByMsg(CodeReportDoesNotExist, strconv.Itoa(int(postID)))
+	}
+
+	// Confirmed reports take post down for good
+	fromStatuses := []string{models.StatusApproved, models.StatusHidden}
+	if err := ru.reportRepo.UpdatePostStatus(postID, fromStatuses, models.StatusRejected); err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	return nil
+}
+
+func (ru *ReportUsecase) Dismiss(postID uint64) *errors.Error {
+	count, err := ru.reportRepo.UpdateStatusByPost(postID, models.ReportDismissed)
+	if err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	if count == 0 {
+		return errors.BuildByMsg(CodeReportDoesNotExist, strconv.Itoa(int(postID)))
+	}
+
+	// Automatically hidden post is shown again
+	fromStatuses := []string{models.StatusHidden}
+	if err := ru.reportRepo.UpdatePostStatus(postID, fromStatuses, models.StatusApproved); err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	return nil
+}
+
+func (ru *ReportUsecase) ListByPost(postID uint64) ([]*models.Report, *errors.Error) {
+	reports, err := ru.reportRepo.SelectAllByPost(postID)
+	if err != nil {
+		return nil, errors.New(CodeInternalError, err)
+	}
+	if len(reports) == 0 {
+		return []*models.Report{}, nil
+	}
+	return reports, nil
+}
+
+func (ru *ReportUsecase) ListInbox(forumSlug string, since uint64, pgnt *models.Pagination) ([]*models.ReportedPost, *errors.Error) {
+	reportedPosts, err := ru.reportRepo.SelectInbox(forumSlug, since, pgnt)
+	if err != nil {
+		return nil, errors.New(CodeInternalError, err)
+	}
+	if len(reportedPosts) == 0 {
+		return []*models.ReportedPost{}, nil
+	}
+	return reportedPosts, nil
+}
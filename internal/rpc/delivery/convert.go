@@ -0,0 +1,105 @@
+package delivery
+
+import (
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/forumpb"
+)
+
+func toUserProto(user *models.User) *forumpb.User {
+	return &forumpb.User{
+		Nickname: user.Nickname,
+		Fullname: user.Fullname,
+		Email:    user.Email,
+		About:    user.About,
+		Version:  user.EditVersion,
+	}
+}
+
+func toUsersProto(users []*models.User) []*forumpb.User {
+	usersProto := make([]*forumpb.User, 0, len(users))
+	for _, user := range users {
+		usersProto = append(usersProto, toUserProto(user))
+	}
+	return usersProto
+}
+
+func toForumProto(forum *models.Forum) *forumpb.Forum {
+	return &forumpb.Forum{
+		Slug:    forum.Slug,
+		Title:   forum.Title,
+		User:    forum.User,
+		Posts:   forum.Posts,
+		Threads: forum.Threads,
+	}
+}
+
+func toThreadProto(thread *models.Thread) *forumpb.Thread {
+	return &forumpb.Thread{
+		Id:      thread.ID,
+		Slug:    thread.Slug,
+		Title:   thread.Title,
+		Author:  thread.Author,
+		Forum:   thread.Forum,
+		Message: thread.Message,
+		Votes:   thread.Votes,
+		Created: timestamppb.New(thread.Created),
+		Version: thread.EditVersion,
+	}
+}
+
+func toThreadsProto(threads []*models.Thread) []*forumpb.Thread {
+	threadsProto := make([]*forumpb.Thread, 0, len(threads))
+	for _, thread := range threads {
+		threadsProto = append(threadsProto, toThreadProto(thread))
+	}
+	return threadsProto
+}
+
+func toPostProto(post *models.Post) *forumpb.Post {
+	return &forumpb.Post{
+		Id:       post.ID,
+		Parent:   post.Parent,
+		Author:   post.Author,
+		Message:  post.Message,
+		IsEdited: post.IsEdited,
+		Forum:    post.Forum,
+		Thread:   post.Thread,
+		Created:  timestamppb.New(post.Created),
+		Score:    post.Score,
+		Version:  post.EditVersion,
+	}
+}
+
+func toPostsProto(posts []*models.Post) []*forumpb.Post {
+	postsProto := make([]*forumpb.Post, 0, len(posts))
+	for _, post := range posts {
+		postsProto = append(postsProto, toPostProto(post))
+	}
+	return postsProto
+}
+
+func toVotesProto(votes []*models.Vote) []*forumpb.Vote {
+	votesProto := make([]*forumpb.Vote, 0, len(votes))
+	for _, vote := range votes {
+		votesProto = append(votesProto, &forumpb.Vote{
+			Nickname: vote.Nickname,
+			Voice:    int32(vote.Voice),
+			Thread:   vote.Thread,
+			Post:     vote.Post,
+		})
+	}
+	return votesProto
+}
+
+func toPagination(pgnt *forumpb.Pagination) *models.Pagination {
+	if pgnt == nil {
+		return &models.Pagination{}
+	}
+	return &models.Pagination{
+		Limit: pgnt.Limit,
+		Desc:  pgnt.Desc,
+		Sort:  pgnt.Sort,
+	}
+}
@@ -0,0 +1,74 @@
+package delivery
+
+import (
+	"net/http"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/forumpb"
+)
+
+// Errors which http status doesn't describe them well enough
+var codesByErrorCode = map[ErrorCode]codes.Code{
+	CodeParentPostDoesNotExist: codes.FailedPrecondition,
+	CodeVersionConflict:        codes.Aborted,
+}
+
+var codesByHTTPCode = map[int]codes.Code{
+	http.StatusBadRequest:            codes.InvalidArgument,
+	http.StatusForbidden:             codes.PermissionDenied,
+	http.StatusNotFound:              codes.NotFound,
+	http.StatusConflict:              codes.AlreadyExists,
+	http.StatusPreconditionFailed:    codes.FailedPrecondition,
+	http.StatusRequestEntityTooLarge: codes.InvalidArgument,
+	http.StatusUnprocessableEntity:   codes.InvalidArgument,
+	http.StatusTooManyRequests:       codes.ResourceExhausted,
+	http.StatusInternalServerError:   codes.Internal,
+}
+
+// toStatus converts usecase error to gRPC status, entity from conflict body is put to details
+func toStatus(err *errors.Error) error {
+	code, has := codesByErrorCode[err.Code]
+	if !has {
+		code, has = codesByHTTPCode[err.HTTPCode]
+	}
+	if !has {
+		code = codes.Unknown
+	}
+
+	message := err.Message
+	if message == "" {
+		message = http.StatusText(err.HTTPCode)
+	}
+	st := status.New(code, message)
+
+	if detail := bodyToProto(err.Body); detail != nil {
+		if detailed, detailsErr := st.WithDetails(detail); detailsErr == nil {
+			st = detailed
+		}
+	}
+	return st.Err()
+}
+
+func bodyToProto(body errors.BodyType) proto.Message {
+	switch body := body.(type) {
+	case *models.User:
+		return toUserProto(body)
+	case []*models.User:
+		return &forumpb.UserList{
+			Users: toUsersProto(body),
+		}
+	case *models.Forum:
+		return toForumProto(body)
+	case *models.Thread:
+		return toThreadProto(body)
+	case *models.Post:
+		return toPostProto(body)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package delivery
+
+import (
+	"context"
+	"time"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/forumpb"
+)
+
+func (fs *ForumServer) CreateForum(ctx context.Context, req *forumpb.CreateForumRequest) (*forumpb.Forum, error) {
+	user, err := fs.userUcase.GetByNickname(req.GetForum().GetUser())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	forum := &models.Forum{
+		Slug:  req.GetForum().GetSlug(),
+		Title: req.GetForum().GetTitle(),
+		User:  user.Nickname,
+	}
+
+	if err := fs.forumUcase.Create(forum); err != nil {
+		return nil, toStatus(err)
+	}
+	return toForumProto(forum), nil
+}
+
+func (fs *ForumServer) GetForum(ctx context.Context, req *forumpb.GetForumRequest) (*forumpb.Forum, error) {
+	forum, err := fs.forumUcase.GetBySlug(req.GetSlug())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toForumProto(forum), nil
+}
+
+func (fs *ForumServer) ListForumUsers(ctx context.Context,
+	req *forumpb.ListForumUsersRequest) (*forumpb.ListUsersResponse, error) {
+	if _, err := fs.forumUcase.GetBySlug(req.GetSlug()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	users, err := fs.userUcase.ListByForum(req.GetSlug(), req.GetSince(), toPagination(req.GetPagination()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &forumpb.ListUsersResponse{
+		Users: toUsersProto(users),
+	}, nil
+}
+
+func (fs *ForumServer) ListForumThreads(ctx context.Context,
+	req *forumpb.ListForumThreadsRequest) (*forumpb.ListThreadsResponse, error) {
+	if _, err := fs.forumUcase.GetBySlug(req.GetSlug()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	// Unset since lists threads from the beginning like missing query parameter
+	var since time.Time
+	if req.GetSince() != nil {
+		since = req.GetSince().AsTime()
+	}
+
+	threads, err := fs.threadUcase.ListByForum(req.GetSlug(), since, toPagination(req.GetPagination()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &forumpb.ListThreadsResponse{
+		Threads: toThreadsProto(threads),
+	}, nil
+}
@@ -0,0 +1,130 @@
+package delivery
+
+import (
+	"context"
+
+	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/forumpb"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/uniq"
+)
+
+func (fs *ForumServer) CreatePosts(ctx context.Context,
+	req *forumpb.CreatePostsRequest) (*forumpb.CreatePostsResponse, error) {
+	// Zero max batch means that batch size is not limited
+	if fs.maxBatch != 0 && len(req.GetPosts()) > fs.maxBatch {
+		return nil, toStatus(errors.BuildByMsg(CodeBatchIsTooLarge, len(req.GetPosts()), fs.maxBatch))
+	}
+
+	thread, err := fs.threadUcase.GetBySlugOrID(req.GetSlugOrId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	var nicknames []string
+	posts := make([]*models.Post, 0, len(req.GetPosts()))
+	for _, postProto := range req.GetPosts() {
+		posts = append(posts, &models.Post{
+			Parent:  postProto.GetParent(),
+			Author:  postProto.GetAuthor(),
+			Message: postProto.GetMessage(),
+		})
+		nicknames = append(nicknames, postProto.GetAuthor())
+	}
+
+	nicknames = uniq.RemoveDuplicates(nicknames)
+	if err := fs.userUcase.CheckUsersExistence(nicknames); err != nil {
+		return nil, toStatus(err)
+	}
+
+	if err := fs.postUcase.Create(posts, thread); err != nil {
+		return nil, toStatus(err)
+	}
+	return &forumpb.CreatePostsResponse{
+		Posts: toPostsProto(posts),
+	}, nil
+}
+
+func (fs *ForumServer) GetPost(ctx context.Context, req *forumpb.GetPostRequest) (*forumpb.PostDetails, error) {
+	post, err := fs.postUcase.GetByID(req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	details := &forumpb.PostDetails{
+		Post: toPostProto(post),
+	}
+	for _, related := range req.GetRelated() {
+		switch related {
+		case "user":
+			user, err := fs.userUcase.GetByPostID(post.ID)
+			if err != nil {
+				return nil, toStatus(err)
+			}
+			details.Author = toUserProto(user)
+		case "forum":
+			forum, err := fs.forumUcase.GetByPostID(post.ID)
+			if err != nil {
+				return nil, toStatus(err)
+			}
+			details.Forum = toForumProto(forum)
+		case "thread":
+			thread, err := fs.threadUcase.GetByPostID(post.ID)
+			if err != nil {
+				return nil, toStatus(err)
+			}
+			details.Thread = toThreadProto(thread)
+		}
+	}
+	return details, nil
+}
+
+func (fs *ForumServer) UpdatePost(ctx context.Context, req *forumpb.UpdatePostRequest) (*forumpb.Post, error) {
+	postData := &models.Post{
+		Message:     req.GetMessage(),
+		EditVersion: req.GetVersion(),
+	}
+
+	post, err := fs.postUcase.Update(req.GetId(), postData)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toPostProto(post), nil
+}
+
+func (fs *ForumServer) VotePost(ctx context.Context, req *forumpb.VotePostRequest) (*forumpb.Post, error) {
+	user, err := fs.userUcase.GetByNickname(req.GetVote().GetNickname())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	vote := &models.Vote{
+		Nickname: user.Nickname,
+		Voice:    int(req.GetVote().GetVoice()),
+	}
+
+	post, err := fs.postUcase.Vote(req.GetId(), vote)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toPostProto(post), nil
+}
+
+func (fs *ForumServer) ListThreadPosts(req *forumpb.ListThreadPostsRequest,
+	stream forumpb.ForumService_ListThreadPostsServer) error {
+	thread, err := fs.threadUcase.GetBySlugOrID(req.GetSlugOrId())
+	if err != nil {
+		return toStatus(err)
+	}
+
+	// Posts are sent while rows are read, so listing without limit doesn't have to fit into memory
+	send := func(post *models.Post) error {
+		return stream.Send(toPostProto(post))
+	}
+	pgnt := toPagination(req.GetPagination())
+	if err := fs.postUcase.StreamByThread(thread.ID, req.GetSince(), pgnt, send); err != nil {
+		return toStatus(err)
+	}
+	return nil
+}
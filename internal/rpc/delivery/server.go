@@ -0,0 +1,35 @@
+package delivery
+
+import (
+	"github.com/OlegGibadulin/tech-db-forum/internal/forum"
+	"github.com/OlegGibadulin/tech-db-forum/internal/post"
+	"github.com/OlegGibadulin/tech-db-forum/internal/service"
+	"github.com/OlegGibadulin/tech-db-forum/internal/thread"
+	"github.com/OlegGibadulin/tech-db-forum/internal/user"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/forumpb"
+)
+
+// ForumServer implements gRPC API on top of the same usecases as REST handlers
+type ForumServer struct {
+	forumpb.UnimplementedForumServiceServer
+
+	userUcase    user.UserUsecase
+	forumUcase   forum.ForumUsecase
+	threadUcase  thread.ThreadUsecase
+	postUcase    post.PostUsecase
+	serviceUcase service.ServiceUsecase
+	maxBatch     int
+}
+
+func NewForumServer(userUcase user.UserUsecase, forumUcase forum.ForumUsecase,
+	threadUcase thread.ThreadUsecase, postUcase post.PostUsecase,
+	serviceUcase service.ServiceUsecase, maxBatch int) *ForumServer {
+	return &ForumServer{
+		userUcase:    userUcase,
+		forumUcase:   forumUcase,
+		threadUcase:  threadUcase,
+		postUcase:    postUcase,
+		serviceUcase: serviceUcase,
+		maxBatch:     maxBatch,
+	}
+}
@@ -0,0 +1,27 @@
+package delivery
+
+import (
+	"context"
+
+	"github.com/OlegGibadulin/tech-db-forum/pkg/forumpb"
+)
+
+func (fs *ForumServer) Clear(ctx context.Context, req *forumpb.ClearRequest) (*forumpb.ClearResponse, error) {
+	if err := fs.serviceUcase.Clear(); err != nil {
+		return nil, toStatus(err)
+	}
+	return &forumpb.ClearResponse{}, nil
+}
+
+func (fs *ForumServer) GetStatus(ctx context.Context, req *forumpb.GetStatusRequest) (*forumpb.Status, error) {
+	status, err := fs.serviceUcase.GetStatus()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &forumpb.Status{
+		Users:   int64(status.Users),
+		Forums:  int64(status.Forums),
+		Threads: int64(status.Threads),
+		Posts:   int64(status.Posts),
+	}, nil
+}
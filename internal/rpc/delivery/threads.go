@@ -0,0 +1,94 @@
+package delivery
+
+import (
+	"context"
+	"time"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/forumpb"
+)
+
+func (fs *ForumServer) CreateThread(ctx context.Context, req *forumpb.CreateThreadRequest) (*forumpb.Thread, error) {
+	forum, err := fs.forumUcase.GetBySlug(req.GetThread().GetForum())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	user, err := fs.userUcase.GetByNickname(req.GetThread().GetAuthor())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	thread := &models.Thread{
+		Title:   req.GetThread().GetTitle(),
+		Author:  user.Nickname,
+		Forum:   forum.Slug,
+		Message: req.GetThread().GetMessage(),
+		Slug:    req.GetThread().GetSlug(),
+		Created: time.Now(),
+	}
+	if req.GetThread().GetCreated() != nil {
+		thread.Created = req.GetThread().GetCreated().AsTime()
+	}
+
+	if err := fs.threadUcase.Create(thread); err != nil {
+		return nil, toStatus(err)
+	}
+	return toThreadProto(thread), nil
+}
+
+func (fs *ForumServer) GetThread(ctx context.Context, req *forumpb.GetThreadRequest) (*forumpb.Thread, error) {
+	thread, err := fs.threadUcase.GetBySlugOrID(req.GetSlugOrId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toThreadProto(thread), nil
+}
+
+func (fs *ForumServer) UpdateThread(ctx context.Context, req *forumpb.UpdateThreadRequest) (*forumpb.Thread, error) {
+	threadData := &models.Thread{
+		Title:       req.GetTitle(),
+		Message:     req.GetMessage(),
+		EditVersion: req.GetVersion(),
+	}
+
+	thread, err := fs.threadUcase.Update(req.GetSlugOrId(), threadData)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toThreadProto(thread), nil
+}
+
+func (fs *ForumServer) VoteThread(ctx context.Context, req *forumpb.VoteThreadRequest) (*forumpb.Thread, error) {
+	user, err := fs.userUcase.GetByNickname(req.GetVote().GetNickname())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	vote := &models.Vote{
+		Nickname: user.Nickname,
+		Voice:    int(req.GetVote().GetVoice()),
+	}
+
+	thread, err := fs.threadUcase.Vote(req.GetSlugOrId(), vote)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toThreadProto(thread), nil
+}
+
+func (fs *ForumServer) ListThreadVotes(ctx context.Context,
+	req *forumpb.ListThreadVotesRequest) (*forumpb.ListVotesResponse, error) {
+	threadID, err := fs.threadUcase.CheckThreadExistence(req.GetSlugOrId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	votes, err := fs.threadUcase.ListVotes(threadID, req.GetSince(), toPagination(req.GetPagination()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &forumpb.ListVotesResponse{
+		Votes: toVotesProto(votes),
+	}, nil
+}
@@ -0,0 +1,45 @@
+package delivery
+
+import (
+	"context"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/forumpb"
+)
+
+func (fs *ForumServer) CreateUser(ctx context.Context, req *forumpb.CreateUserRequest) (*forumpb.User, error) {
+	user := &models.User{
+		Nickname: req.GetUser().GetNickname(),
+		Fullname: req.GetUser().GetFullname(),
+		Email:    req.GetUser().GetEmail(),
+		About:    req.GetUser().GetAbout(),
+	}
+
+	if err := fs.userUcase.Create(user); err != nil {
+		return nil, toStatus(err)
+	}
+	return toUserProto(user), nil
+}
+
+func (fs *ForumServer) GetUser(ctx context.Context, req *forumpb.GetUserRequest) (*forumpb.User, error) {
+	user, err := fs.userUcase.GetByNickname(req.GetNickname())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toUserProto(user), nil
+}
+
+func (fs *ForumServer) UpdateUser(ctx context.Context, req *forumpb.UpdateUserRequest) (*forumpb.User, error) {
+	userData := &models.User{
+		Fullname:    req.GetFullname(),
+		Email:       req.GetEmail(),
+		About:       req.GetAbout(),
+		EditVersion: req.GetVersion(),
+	}
+
+	user, err := fs.userUcase.Update(req.GetNickname(), userData)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toUserProto(user), nil
+}
@@ -0,0 +1,107 @@
+package delivery
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/forum"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/mwares"
+	"github.com/OlegGibadulin/tech-db-forum/internal/sanction"
+	"github.com/OlegGibadulin/tech-db-forum/internal/user"
+	reader "github.com/OlegGibadulin/tech-db-forum/tools/request_reader"
+	"github.com/labstack/echo/v4"
+)
+
+type SanctionHandler struct {
+	sanctionUcase sanction.SanctionUsecase
+	userUcase     user.UserUsecase
+	forumUcase    forum.ForumUsecase
+}
+
+func NewSanctionHandler(sanctionUcase sanction.SanctionUsecase, userUcase user.UserUsecase,
+	forumUcase forum.ForumUsecase) *SanctionHandler {
+	return &SanctionHandler{
+		sanctionUcase: sanctionUcase,
+		userUcase:     userUcase,
+		forumUcase:    forumUcase,
+	}
+}
+
+func (sh *SanctionHandler) Configure(e *echo.Echo, mw *mwares.MiddlewareManager) {
+	// Sanctions are managed by moderators only, listing is closed too since it shows reasons
+	e.POST("/api/moderation/sanctions", sh.CreateSanctionHandler(), mw.AdminOnly, mw.Idempotency)
+	e.GET("/api/moderation/sanctions", sh.GetActiveSanctionsHandler(), mw.AdminOnly)
+	e.POST("/api/moderation/sanctions/:id/lift", sh.LiftSanctionHandler(), mw.AdminOnly)
+}
+
+func (sh *SanctionHandler) CreateSanctionHandler() echo.HandlerFunc {
+	type Request struct {
+		models.Sanction
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		user, err := sh.userUcase.GetByNickname(req.Nickname)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		req.Nickname = user.Nickname
+
+		if req.Forum != "" {
+			forum, err := sh.forumUcase.GetBySlug(req.Forum)
+			if err != nil {
+				// logrus.Error(err.Message)
+				return cntx.JSON(err.HTTPCode, err.Response())
+			}
+			req.Forum = forum.Slug
+		}
+
+		if err := sh.sanctionUcase.Create(&req.Sanction); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusCreated, req.Sanction)
+	}
+}
+
+func (sh *SanctionHandler) GetActiveSanctionsHandler() echo.HandlerFunc {
+	type Request struct {
+		Forum string `query:"forum"`
+		Since uint64 `query:"since"`
+		models.Pagination
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		sanctions, err := sh.sanctionUcase.ListActive(req.Forum, req.Since, &req.Pagination)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, sanctions)
+	}
+}
+
+func (sh *SanctionHandler) LiftSanctionHandler() echo.HandlerFunc {
+	return func(cntx echo.Context) error {
+		sanctionID, _ := strconv.ParseUint(cntx.Param("id"), 10, 64)
+		sanction, err := sh.sanctionUcase.Lift(sanctionID)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, sanction)
+	}
+}
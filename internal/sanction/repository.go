@@ -0,0 +1,16 @@
+package sanction
+
+import (
+	"time"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+)
+
+type SanctionRepository interface {
+	Insert(sanction *models.Sanction) error
+	UpdateAsLifted(sanctionID uint64) error
+	UpdateExpiredAsLifted(now time.Time) (int64, error)
+	SelectByID(sanctionID uint64) (*models.Sanction, error)
+	SelectActiveByNicknames(nicknames []string, forumSlug string) ([]*models.Sanction, error)
+	SelectAllActive(forumSlug string, since uint64, pgnt *models.Pagination) ([]*models.Sanction, error)
+}
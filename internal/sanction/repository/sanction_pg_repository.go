@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/placeholders"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/sanction"
+)
+
+type SanctionPgRepository struct {
+	dbConn *sql.DB
+}
+
+func NewSanctionPgRepository(conn *sql.DB) sanction.SanctionRepository {
+	return &SanctionPgRepository{
+		dbConn: conn,
+	}
+}
+
+func scanSanction(scanner interface{ Scan(...interface{}) error }) (*models.Sanction, error) {
+	sanction := &models.Sanction{}
+	var forum sql.NullString
+	var expires, lifted sql.NullTime
+
+	err := scanner.Scan(&sanction.ID, &sanction.Nickname, &forum, &sanction.Reason,
+		&sanction.Created, &expires, &lifted)
+	if err != nil {
+		return nil, err
+	}
+
+	sanction.Forum = forum.String
+	if expires.Valid {
+		sanction.Expires = &expires.Time
+	}
+	if lifted.Valid {
+		sanction.Lifted = &lifted.Time
+	}
+	return sanction, nil
+}
+
+func (sr *SanctionPgRepository) Insert(sanction *models.Sanction) error {
+	tx, err := sr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	forum := sql.NullString{String: sanction.Forum, Valid: sanction.Forum != ""}
+	row := tx.QueryRow(
+		`INSERT INTO sanctions(nickname, forum, reason, expires)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created`,
+		sanction.Nickname, forum, sanction.Reason, sanction.Expires)
+
+	err = row.Scan(&sanction.ID, &sanction.Created)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (sr *SanctionPgRepository) UpdateAsLifted(sanctionID uint64) error {
+	tx, err := sr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`UPDATE sanctions
+		SET lifted = now()
+		WHERE id = $1 AND lifted IS NULL`,
+		sanctionID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (sr *SanctionPgRepository) UpdateExpiredAsLifted(now time.Time) (int64, error) {
+	tx, err := sr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.Exec(
+		`UPDATE sanctions
+		SET lifted = expires
+		WHERE lifted IS NULL AND expires <= $1`,
+		now)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (sr *SanctionPgRepository) SelectByID(sanctionID uint64) (*models.Sanction, error) {
+	row := sr.dbConn.QueryRow(
+		`SELECT id, nickname, forum, reason, created, expires, lifted
+		FROM sanctions
+		WHERE id=$1`,
+		sanctionID)
+
+	return scanSanction(row)
+}
+
+func (sr *SanctionPgRepository) selectSanctions(query string, values []interface{}) ([]*models.Sanction, error) {
+	rows, err := sr.dbConn.Query(query, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sanctions []*models.Sanction
+	for rows.Next() {
+		sanction, err := scanSanction(rows)
+		if err != nil {
+			return nil, err
+		}
+		sanctions = append(sanctions, sanction)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return sanctions, nil
+}
+
+func (sr *SanctionPgRepository) SelectActiveByNicknames(nicknames []string, forumSlug string) ([]*models.Sanction, error) {
+	var values []interface{}
+
+	// Expiration is checked here too, so sanctions end on time regardless of sweeper
+	selectQuery := `
+		SELECT id, nickname, forum, reason, created, expires, lifted
+		FROM sanctions
+		WHERE lifted IS NULL
+		AND (expires IS NULL OR expires > now())
+		AND (forum IS NULL OR forum = $1)
+		AND nickname IN`
+	values = append(values, forumSlug)
+
+	filterQuery := placeholders.List(len(values)+1, len(nicknames))
+	for _, nickname := range nicknames {
+		values = append(values, nickname)
+	}
+
+	// Global bans go first
+	sortQuery := "ORDER BY forum NULLS FIRST, id"
+
+	resultQuery := strings.Join([]string{
+		selectQuery,
+		filterQuery,
+		sortQuery,
+	}, " ")
+
+	return sr.selectSanctions(resultQuery, values)
+}
+
+func (sr *SanctionPgRepository) SelectAllActive(forumSlug string, since uint64, pgnt *models.Pagination) ([]*models.Sanction, error) {
+	var values []interface{}
+
+	selectQuery := `
+		SELECT id, nickname, forum, reason, created, expires, lifted
+		FROM sanctions
+		WHERE lifted IS NULL
+		AND (expires IS NULL OR expires > now())`
+
+	var forumQuery string
+	if forumSlug != "" {
+		forumQuery = "AND forum = $" + strconv.Itoa(len(values)+1)
+		values = append(values, forumSlug)
+	}
+
+	var filterQuery string
+	if since != 0 {
+		ind := len(values) + 1
+		if pgnt.Desc {
+			filterQuery = "AND id < $" + strconv.Itoa(ind)
+		} else {
+			filterQuery = "AND id > $" + strconv.Itoa(ind)
+		}
+		values = append(values, since)
+	}
+
+	var sortQuery string
+	if pgnt.Desc {
+		sortQuery = "ORDER BY id DESC"
+	} else {
+		sortQuery = "ORDER BY id"
+	}
+
+	var pgntQuery string
+	if pgnt.Limit != 0 {
+		pgntQuery = "LIMIT $" + strconv.Itoa(len(values)+1)
+		values = append(values, pgnt.Limit)
+	}
+
+	resultQuery := strings.Join([]string{
+		selectQuery,
+		forumQuery,
+		filterQuery,
+		sortQuery,
+		pgntQuery,
+	}, " ")
+
+	return sr.selectSanctions(resultQuery, values)
+}
@@ -0,0 +1,15 @@
+package sanction
+
+import (
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+)
+
+type SanctionUsecase interface {
+	Create(sanction *models.Sanction) *errors.Error
+	Lift(sanctionID uint64) (*models.Sanction, *errors.Error)
+	LiftExpired() *errors.Error
+	GetByID(sanctionID uint64) (*models.Sanction, *errors.Error)
+	CheckUsers(nicknames []string, forumSlug string) *errors.Error
+	ListActive(forumSlug string, since uint64, pgnt *models.Pagination) ([]*models.Sanction, *errors.Error)
+}
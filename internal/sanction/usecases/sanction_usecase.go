@@ -0,0 +1,100 @@
+package usecases
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/sanction"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/uniq"
+	"github.com/sirupsen/logrus"
+)
+
+type SanctionUsecase struct {
+	sanctionRepo sanction.SanctionRepository
+}
+
+func NewSanctionUsecase(repo sanction.SanctionRepository) sanction.SanctionUsecase {
+	return &SanctionUsecase{
+		sanctionRepo: repo,
+	}
+}
+
+func (su *SanctionUsecase) Create(sanction *models.Sanction) *errors.Error {
+	if sanction.Expires != nil && !sanction.Expires.After(time.Now()) {
+		return errors.BuildByMsg(CodeSanctionIsExpired, sanction.Expires.Format(time.RFC3339))
+	}
+
+	if err := su.sanctionRepo.Insert(sanction); err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	return nil
+}
+
+func (su *SanctionUsecase) Lift(sanctionID uint64) (*models.Sanction, *errors.Error) {
+	if _, customErr := su.GetByID(sanctionID); customErr != nil {
+		return nil, customErr
+	}
+
+	if err := su.sanctionRepo.UpdateAsLifted(sanctionID); err != nil {
+		return nil, errors.New(CodeInternalError, err)
+	}
+	return su.GetByID(sanctionID)
+}
+
+func (su *SanctionUsecase) LiftExpired() *errors.Error {
+	count, err := su.sanctionRepo.UpdateExpiredAsLifted(time.Now())
+	if err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	if count != 0 {
+		logrus.Info("Expired sanctions lifted: ", count)
+	}
+	return nil
+}
+
+func (su *SanctionUsecase) GetByID(sanctionID uint64) (*models.Sanction, *errors.Error) {
+	sanction, err := su.sanctionRepo.SelectByID(sanctionID)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, errors.BuildByMsg(CodeSanctionDoesNotExist, "id", strconv.Itoa(int(sanctionID)))
+	case err != nil:
+		return nil, errors.New(CodeInternalError, err)
+	}
+	return sanction, nil
+}
+
+func (su *SanctionUsecase) CheckUsers(nicknames []string, forumSlug string) *errors.Error {
+	nicknames = uniq.RemoveDuplicates(nicknames)
+	if len(nicknames) == 0 {
+		return nil
+	}
+
+	sanctions, err := su.sanctionRepo.SelectActiveByNicknames(nicknames, forumSlug)
+	if err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	if len(sanctions) == 0 {
+		return nil
+	}
+
+	sanction := sanctions[0]
+	if sanction.Forum == "" {
+		return errors.BuildByMsg(CodeUserIsBanned, sanction.Nickname, sanction.Reason)
+	}
+	return errors.BuildByMsg(CodeUserIsMuted, sanction.Nickname, sanction.Forum, sanction.Reason)
+}
+
+func (su *SanctionUsecase) ListActive(forumSlug string, since uint64, pgnt *models.Pagination) ([]*models.Sanction, *errors.Error) {
+	sanctions, err := su.sanctionRepo.SelectAllActive(forumSlug, since, pgnt)
+	if err != nil {
+		return nil, errors.New(CodeInternalError, err)
+	}
+	if len(sanctions) == 0 {
+		return []*models.Sanction{}, nil
+	}
+	return sanctions, nil
+}
@@ -1,11 +1,16 @@
 package delivery
 
 import (
+	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/ndjson"
 	"github.com/OlegGibadulin/tech-db-forum/internal/mwares"
 	"github.com/OlegGibadulin/tech-db-forum/internal/service"
+	reader "github.com/OlegGibadulin/tech-db-forum/tools/request_reader"
 	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
 )
 
 type ServiceHandler struct {
@@ -21,6 +26,8 @@ func NewServiceHandler(serviceUcase service.ServiceUsecase) *ServiceHandler {
 func (sh *ServiceHandler) Configure(e *echo.Echo, mw *mwares.MiddlewareManager) {
 	e.POST("/api/service/clear", sh.ClearServiceHandler())
 	e.GET("/api/service/status", sh.GetServiceStatusHandler())
+	e.GET("/api/service/export", sh.ExportHandler(), mw.AdminOnly)
+	e.POST("/api/service/restore", sh.RestoreHandler(), mw.AdminOnly)
 }
 
 func (sh *ServiceHandler) ClearServiceHandler() echo.HandlerFunc {
@@ -43,3 +50,55 @@ func (sh *ServiceHandler) GetServiceStatusHandler() echo.HandlerFunc {
 		return cntx.JSON(http.StatusOK, status)
 	}
 }
+
+func (sh *ServiceHandler) ExportHandler() echo.HandlerFunc {
+	type Request struct {
+		Forum string `query:"forum"`
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		name := "forum"
+		if req.Forum != "" {
+			name = req.Forum
+		}
+		filename := fmt.Sprintf("%s-%s.jsonl", name, time.Now().Format("20060102-150405"))
+
+		header := cntx.Response().Header()
+		header.Set(echo.HeaderContentType, ndjson.MIMEApplicationNDJSON)
+		header.Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+
+		// Archive is written while it is read from database, status is sent with its first line
+		if err := sh.serviceUcase.Export(req.Forum, cntx.Response()); err != nil {
+			if cntx.Response().Committed {
+				logrus.Error(err.Message)
+				return nil
+			}
+			header.Del(echo.HeaderContentDisposition)
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return nil
+	}
+}
+
+func (sh *ServiceHandler) RestoreHandler() echo.HandlerFunc {
+	return func(cntx echo.Context) error {
+		if err := sh.serviceUcase.Restore(cntx.Request().Body); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		status, err := sh.serviceUcase.GetStatus()
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, status)
+	}
+}
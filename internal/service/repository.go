@@ -2,7 +2,15 @@ package service
 
 import "github.com/OlegGibadulin/tech-db-forum/internal/models"
 
+// ArchiveRowSender receives archived rows, tables go in order of their references
+type ArchiveRowSender func(row *models.ArchiveRow) error
+
+// ArchiveRowReader returns the next row of archive or io.EOF after the last one
+type ArchiveRowReader func() (*models.ArchiveRow, error)
+
 type ServiceRepository interface {
 	ClearAllTables() error
 	GetRowsCount() (*models.Status, error)
+	SelectArchiveRows(forumSlug string, send ArchiveRowSender) error
+	InsertArchiveRows(next ArchiveRowReader) error
 }
@@ -1,9 +1,15 @@
 package repository
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
 
+	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
 	"github.com/OlegGibadulin/tech-db-forum/internal/models"
 	"github.com/OlegGibadulin/tech-db-forum/internal/service"
 )
@@ -25,7 +31,10 @@ func (sr *ServicePgRepository) ClearAllTables() error {
 	}
 
 	_, err = tx.Exec(
-		`TRUNCATE users, forums, forum_user, threads, posts, votes CASCADE`)
+		`TRUNCATE users, forums, forum_user, threads, posts, votes, post_votes,
+		user_follows, thread_follows, forum_follows, notifications, digest_settings,
+		nickname_history, user_deletions, sanctions,
+		moderation_rules, post_reports, attachments, polls, poll_options, poll_ballots, drafts, idempotency_keys, import_ids, import_checkpoints CASCADE`)
 	if err != nil {
 		tx.Rollback()
 		return err
@@ -53,3 +62,226 @@ func (sr *ServicePgRepository) GetRowsCount() (*models.Status, error) {
 	}
 	return status, nil
 }
+
+// Tables are archived in order of their references, so they are restored in the same order.
+// Rate limits and idempotency keys live for minutes and aren't archived.
+// Only metadata of attachments is archived, files stay in storage
+var archiveTables = []string{
+	"users", "nickname_history", "user_deletions", "forums", "forum_user", "threads", "posts",
+	"votes", "post_votes", "user_follows", "thread_follows", "forum_follows", "notifications",
+	"digest_settings", "sanctions", "moderation_rules", "post_reports", "attachments",
+	"polls", "poll_options", "poll_ballots", "drafts", "import_ids", "import_checkpoints",
+}
+
+// Tables with serial ids, their sequences continue after restored rows
+var archiveSerialTables = []string{
+	"user_deletions", "threads", "posts", "notifications", "sanctions", "post_reports", "attachments",
+	"poll_options", "drafts",
+}
+
+const restoreChunkSize = 1000
+
+type archiveQuery struct {
+	table string
+	query string
+}
+
+// Archive of one forum has its threads and posts with votes for them and all users taking part in it,
+// unpublished drafts are private, so they are only in archive of the whole database
+var forumArchiveQueries = []archiveQuery{
+	{"users", `
+		SELECT row_to_json(u)
+		FROM users AS u
+		WHERE u.nickname IN (
+			SELECT author FROM forums WHERE slug=$1
+			UNION SELECT nickname FROM forum_user WHERE forum=$1
+			UNION SELECT author FROM threads WHERE forum=$1
+			UNION SELECT author FROM posts WHERE forum=$1
+			UNION SELECT v.nickname FROM votes AS v JOIN threads AS t ON t.id = v.thread WHERE t.forum=$1
+			UNION SELECT v.nickname FROM post_votes AS v JOIN posts AS p ON p.id = v.post WHERE p.forum=$1
+			UNION SELECT b.nickname FROM poll_ballots AS b JOIN threads AS t ON t.id = b.thread WHERE t.forum=$1
+		)`},
+	{"forums", `SELECT row_to_json(f) FROM forums AS f WHERE f.slug=$1`},
+	{"forum_user", `SELECT row_to_json(fu) FROM forum_user AS fu WHERE fu.forum=$1`},
+	{"threads", `SELECT row_to_json(t) FROM threads AS t WHERE t.forum=$1`},
+	{"posts", `SELECT row_to_json(p) FROM posts AS p WHERE p.forum=$1`},
+	{"votes", `
+		SELECT row_to_json(v)
+		FROM votes AS v
+		JOIN threads AS t ON t.id = v.thread
+		WHERE t.forum=$1`},
+	{"post_votes", `
+		SELECT row_to_json(v)
+		FROM post_votes AS v
+		JOIN posts AS p ON p.id = v.post
+		WHERE p.forum=$1`},
+	{"attachments", `
+		SELECT row_to_json(a)
+		FROM attachments AS a
+		JOIN posts AS p ON p.id = a.post
+		WHERE p.forum=$1`},
+	{"polls", `
+		SELECT row_to_json(pl)
+		FROM polls AS pl
+		JOIN threads AS t ON t.id = pl.thread
+		WHERE t.forum=$1`},
+	{"poll_options", `
+		SELECT row_to_json(o)
+		FROM poll_options AS o
+		JOIN threads AS t ON t.id = o.thread
+		WHERE t.forum=$1`},
+	{"poll_ballots", `
+		SELECT row_to_json(b)
+		FROM poll_ballots AS b
+		JOIN threads AS t ON t.id = b.thread
+		WHERE t.forum=$1`},
+}
+
+func isArchiveTable(table string) bool {
+	for _, archiveTable := range archiveTables {
+		if table == archiveTable {
+			return true
+		}
+	}
+	return false
+}
+
+func (sr *ServicePgRepository) SelectArchiveRows(forumSlug string, send service.ArchiveRowSender) error {
+	// All tables are read from one snapshot, so archive is consistent without stopping writes
+	tx, err := sr.dbConn.BeginTx(context.Background(), &sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  true,
+	})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var queries []archiveQuery
+	var values []interface{}
+	if forumSlug != "" {
+		var slug string
+		if err := tx.QueryRow(`SELECT slug FROM forums WHERE slug=$1`, forumSlug).Scan(&slug); err != nil {
+			return err
+		}
+		queries = forumArchiveQueries
+		values = append(values, slug)
+	} else {
+		for _, table := range archiveTables {
+			queries = append(queries, archiveQuery{table, fmt.Sprintf(`SELECT row_to_json(t) FROM %s AS t`, table)})
+		}
+	}
+
+	for _, query := range queries {
+		if err := sr.sendArchiveRows(tx, query, values, send); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sr *ServicePgRepository) sendArchiveRows(tx *sql.Tx, query archiveQuery, values []interface{},
+	send service.ArchiveRowSender) error {
+	rows, err := tx.Query(query.query, values...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row []byte
+		if err := rows.Scan(&row); err != nil {
+			return err
+		}
+		if err := send(&models.ArchiveRow{Table: query.table, Row: row}); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (sr *ServicePgRepository) InsertArchiveRows(next service.ArchiveRowReader) error {
+	tx, err := sr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	if err := sr.restoreArchiveRows(tx, next); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (sr *ServicePgRepository) restoreArchiveRows(tx *sql.Tx, next service.ArchiveRowReader) error {
+	var existsQueries []string
+	for _, table := range archiveTables {
+		existsQueries = append(existsQueries, fmt.Sprintf("EXISTS(SELECT 1 FROM %s)", table))
+	}
+
+	var notEmpty bool
+	if err := tx.QueryRow("SELECT " + strings.Join(existsQueries, " OR ")).Scan(&notEmpty); err != nil {
+		return err
+	}
+	if notEmpty {
+		return errors.New(OnArchiveRestoreExceptionMsgNotEmpty)
+	}
+
+	// Archived counters, paths and versions are restored as they are, so triggers mustn't change them.
+	// Triggers on insert are skipped by setting local to transaction, tables aren't altered and locked
+	if _, err := tx.Exec(`SET LOCAL forum.bulk_load = 'on'`); err != nil {
+		return err
+	}
+
+	var table string
+	var chunk [][]byte
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		query := fmt.Sprintf(`INSERT INTO %[1]s SELECT * FROM json_populate_recordset(NULL::%[1]s, $1::json)`, table)
+		rows := "[" + string(bytes.Join(chunk, []byte(","))) + "]"
+		chunk = nil
+
+		_, err := tx.Exec(query, rows)
+		return err
+	}
+
+	for {
+		row, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if !isArchiveTable(row.Table) {
+			return errors.New(OnArchiveRestoreExceptionMsgUnknownTable)
+		}
+
+		if row.Table != table || len(chunk) == restoreChunkSize {
+			if err := flush(); err != nil {
+				return err
+			}
+			table = row.Table
+		}
+		chunk = append(chunk, row.Row)
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	for _, table := range archiveSerialTables {
+		_, err := tx.Exec(fmt.Sprintf(
+			`SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM %[1]s`,
+			table))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,6 +1,8 @@
 package service
 
 import (
+	"io"
+
 	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
 	"github.com/OlegGibadulin/tech-db-forum/internal/models"
 )
@@ -8,4 +10,6 @@ import (
 type ServiceUsecase interface {
 	Clear() *errors.Error
 	GetStatus() (*models.Status, *errors.Error)
+	Export(forumSlug string, w io.Writer) *errors.Error
+	Restore(r io.Reader) *errors.Error
 }
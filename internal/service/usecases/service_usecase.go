@@ -1,12 +1,22 @@
 package usecases
 
 import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
 	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
 	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
 	"github.com/OlegGibadulin/tech-db-forum/internal/models"
 	"github.com/OlegGibadulin/tech-db-forum/internal/service"
 )
 
+// Long posts don't fit into default buffer of scanner
+const maxArchiveLineSize = 16 * 1024 * 1024
+
 type ServiceUsecase struct {
 	serviceRepo service.ServiceRepository
 }
@@ -31,3 +41,98 @@ func (su *ServiceUsecase) GetStatus() (*models.Status, *errors.Error) {
 	}
 	return status, nil
 }
+
+// Export writes header and rows of archive as JSON lines, empty forum slug means all tables
+func (su *ServiceUsecase) Export(forumSlug string, w io.Writer) *errors.Error {
+	encoder := json.NewEncoder(w)
+	header := &models.ArchiveHeader{
+		Format:   models.ArchiveFormat,
+		Version:  models.ArchiveVersion,
+		Exported: time.Now(),
+		Forum:    forumSlug,
+	}
+
+	// Header is written with the first row, so nothing is written if forum doesn't exist
+	headerWritten := false
+	writeHeader := func() error {
+		if headerWritten {
+			return nil
+		}
+		headerWritten = true
+		return encoder.Encode(header)
+	}
+
+	send := func(row *models.ArchiveRow) error {
+		if err := writeHeader(); err != nil {
+			return err
+		}
+		return encoder.Encode(row)
+	}
+
+	err := su.serviceRepo.SelectArchiveRows(forumSlug, send)
+	switch {
+	case err == sql.ErrNoRows:
+		return errors.BuildByMsg(CodeForumDoesNotExist, "slug", forumSlug)
+	case err != nil:
+		return errors.New(CodeInternalError, err)
+	}
+
+	if err := writeHeader(); err != nil {
+		return errors.New(CodeInternalError, err)
+	}
+	return nil
+}
+
+// Restore loads archive into empty database in one transaction
+func (su *ServiceUsecase) Restore(r io.Reader) *errors.Error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxArchiveLineSize)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return errors.New(CodeInternalError, err)
+		}
+		return errors.BuildByMsg(CodeWrongArchive, "archive is empty")
+	}
+
+	header := &models.ArchiveHeader{}
+	if err := json.Unmarshal(scanner.Bytes(), header); err != nil || header.Format != models.ArchiveFormat {
+		return errors.BuildByMsg(CodeWrongArchive, "header is missing")
+	}
+	if header.Version != models.ArchiveVersion {
+		return errors.BuildByMsg(CodeWrongArchive, fmt.Sprintf("version %d is not supported", header.Version))
+	}
+
+	// Errors of archive itself are told apart from database ones by reader
+	var archiveErr *errors.Error
+	line := 1
+	next := func() (*models.ArchiveRow, error) {
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		line++
+
+		row := &models.ArchiveRow{}
+		if err := json.Unmarshal(scanner.Bytes(), row); err != nil {
+			archiveErr = errors.BuildByMsg(CodeWrongArchive, fmt.Sprintf("line %d is not a row", line))
+			return nil, err
+		}
+		return row, nil
+	}
+
+	err := su.serviceRepo.InsertArchiveRows(next)
+	switch {
+	case archiveErr != nil:
+		return archiveErr
+	case err != nil && err.Error() == OnArchiveRestoreExceptionMsgNotEmpty:
+		return errors.Get(CodeDatabaseIsNotEmpty)
+	case err != nil && err.Error() == OnArchiveRestoreExceptionMsgUnknownTable:
+		return errors.BuildByMsg(CodeWrongArchive, fmt.Sprintf("line %d has unknown table", line))
+	case err != nil:
+		return errors.New(CodeInternalError, err)
+	}
+	return nil
+}
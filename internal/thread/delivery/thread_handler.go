@@ -1,33 +1,50 @@
 package delivery
 
 import (
+	"fmt"
 	"net/http"
 
+	"github.com/OlegGibadulin/tech-db-forum/internal/attachment"
+	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
 	"github.com/OlegGibadulin/tech-db-forum/internal/forum"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/conditional"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/ndjson"
 	"github.com/OlegGibadulin/tech-db-forum/internal/models"
 	"github.com/OlegGibadulin/tech-db-forum/internal/mwares"
+	"github.com/OlegGibadulin/tech-db-forum/internal/poll"
 	"github.com/OlegGibadulin/tech-db-forum/internal/post"
 	"github.com/OlegGibadulin/tech-db-forum/internal/thread"
 	"github.com/OlegGibadulin/tech-db-forum/internal/user"
 	"github.com/OlegGibadulin/tech-db-forum/pkg/uniq"
 	reader "github.com/OlegGibadulin/tech-db-forum/tools/request_reader"
 	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
 )
 
 type ThreadHandler struct {
-	threadUcase thread.ThreadUsecase
-	userUcase   user.UserUsecase
-	postUcase   post.PostUsecase
-	forumUcase  forum.ForumUsecase
+	threadUcase     thread.ThreadUsecase
+	userUcase       user.UserUsecase
+	postUcase       post.PostUsecase
+	forumUcase      forum.ForumUsecase
+	attachmentUcase attachment.AttachmentUsecase
+	pollUcase       poll.PollUsecase
+	maxBatch        int
+	maxLimit        uint64
 }
 
 func NewThreadHandler(threadUcase thread.ThreadUsecase, userUcase user.UserUsecase,
-	postUcase post.PostUsecase, forumUcase forum.ForumUsecase) *ThreadHandler {
+	postUcase post.PostUsecase, forumUcase forum.ForumUsecase, attachmentUcase attachment.AttachmentUsecase,
+	pollUcase poll.PollUsecase, maxBatch int, maxLimit uint64) *ThreadHandler {
 	return &ThreadHandler{
-		threadUcase: threadUcase,
-		userUcase:   userUcase,
-		postUcase:   postUcase,
-		forumUcase:  forumUcase,
+		threadUcase:     threadUcase,
+		userUcase:       userUcase,
+		postUcase:       postUcase,
+		forumUcase:      forumUcase,
+		attachmentUcase: attachmentUcase,
+		pollUcase:       pollUcase,
+		maxBatch:        maxBatch,
+		maxLimit:        maxLimit,
 	}
 }
 
@@ -35,7 +52,8 @@ func (th *ThreadHandler) Configure(e *echo.Echo, mw *mwares.MiddlewareManager) {
 	e.GET("/api/thread/:slug_or_id/details", th.GetThreadDetailesHandler())
 	e.POST("/api/thread/:slug_or_id/details", th.UpdateThreadHandler())
 	e.POST("/api/thread/:slug_or_id/vote", th.VoteThreadHandler())
-	e.POST("/api/thread/:slug_or_id/create", th.CreatePostsHandler())
+	e.GET("/api/thread/:slug_or_id/votes", th.GetVotesByThreadHandler())
+	e.POST("/api/thread/:slug_or_id/create", th.CreatePostsHandler(), mw.Idempotency)
 	e.GET("/api/thread/:slug_or_id/posts", th.GetPostsByThreadHandler())
 }
 
@@ -52,17 +70,34 @@ func (th *ThreadHandler) UpdateThreadHandler() echo.HandlerFunc {
 			return cntx.JSON(err.HTTPCode, err.Response())
 		}
 
-		slugOrID := cntx.Param("slug_or_id")
 		threadData := &models.Thread{
 			Title:   req.Title,
 			Message: req.Message,
 		}
 
+		slugOrID := cntx.Param("slug_or_id")
+		if conditional.HasPrecondition(cntx) {
+			thread, err := th.threadUcase.GetBySlugOrID(slugOrID)
+			if err != nil {
+				// logrus.Error(err.Message)
+				return cntx.JSON(err.HTTPCode, err.Response())
+			}
+
+			etag := conditional.ETag(thread.Version, thread.Updated)
+			if !conditional.MatchesPrecondition(cntx, etag) {
+				err := errors.BuildByMsg(CodePreconditionFailed, etag)
+				return cntx.JSON(err.HTTPCode, err.Response())
+			}
+			threadData.EditVersion = thread.EditVersion
+		}
+
 		thread, err := th.threadUcase.Update(slugOrID, threadData)
 		if err != nil {
 			// logrus.Error(err.Message)
 			return cntx.JSON(err.HTTPCode, err.Response())
 		}
+
+		cntx.Response().Header().Set("ETag", conditional.ETag(thread.Version, thread.Updated))
 		return cntx.JSON(http.StatusOK, thread)
 	}
 }
@@ -75,6 +110,23 @@ func (th *ThreadHandler) GetThreadDetailesHandler() echo.HandlerFunc {
 			// logrus.Error(err.Message)
 			return cntx.JSON(err.HTTPCode, err.Response())
 		}
+
+		if err := th.pollUcase.FillThread(thread); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		etag := conditional.ETag(thread.Version, thread.Updated)
+		if conditional.IsNotModified(cntx, etag, thread.Updated) {
+			return cntx.NoContent(http.StatusNotModified)
+		}
+
+		if cntx.QueryParam("format") == models.FormatHTML {
+			if err := th.threadUcase.RenderHTML([]*models.Thread{thread}); err != nil {
+				// logrus.Error(err.Message)
+				return cntx.JSON(err.HTTPCode, err.Response())
+			}
+		}
 		return cntx.JSON(http.StatusOK, thread)
 	}
 }
@@ -106,6 +158,35 @@ func (th *ThreadHandler) VoteThreadHandler() echo.HandlerFunc {
 	}
 }
 
+func (th *ThreadHandler) GetVotesByThreadHandler() echo.HandlerFunc {
+	type Request struct {
+		Since string `query:"since"`
+		models.Pagination
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		slugOrID := cntx.Param("slug_or_id")
+		threadID, err := th.threadUcase.CheckThreadExistence(slugOrID)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		votes, err := th.threadUcase.ListVotes(threadID, req.Since, &req.Pagination)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, votes)
+	}
+}
+
 func (th *ThreadHandler) CreatePostsHandler() echo.HandlerFunc {
 	return func(cntx echo.Context) error {
 		posts, err := reader.NewRequestReader(cntx).ReadPosts()
@@ -114,6 +195,12 @@ func (th *ThreadHandler) CreatePostsHandler() echo.HandlerFunc {
 			return cntx.JSON(err.HTTPCode, err.Response())
 		}
 
+		// Zero max batch means that batch size is not limited
+		if th.maxBatch != 0 && len(posts) > th.maxBatch {
+			err := errors.BuildByMsg(CodeBatchIsTooLarge, len(posts), th.maxBatch)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
 		slugOrID := cntx.Param("slug_or_id")
 		thread, err := th.threadUcase.GetBySlugOrID(slugOrID)
 		if err != nil {
@@ -141,7 +228,8 @@ func (th *ThreadHandler) CreatePostsHandler() echo.HandlerFunc {
 
 func (th *ThreadHandler) GetPostsByThreadHandler() echo.HandlerFunc {
 	type Request struct {
-		Since uint64 `query:"since"`
+		Since  uint64 `query:"since"`
+		Format string `query:"format"`
 		models.Pagination
 	}
 
@@ -153,17 +241,73 @@ func (th *ThreadHandler) GetPostsByThreadHandler() echo.HandlerFunc {
 		}
 
 		slugOrID := cntx.Param("slug_or_id")
-		threadID, err := th.threadUcase.CheckThreadExistence(slugOrID)
+		thread, err := th.threadUcase.GetBySlugOrID(slugOrID)
 		if err != nil {
 			// logrus.Error(err.Message)
 			return cntx.JSON(err.HTTPCode, err.Response())
 		}
 
-		posts, err := th.postUcase.ListByThread(threadID, req.Since, &req.Pagination)
+		// Cached listing is stored by URL and Accept header, streamed one gets its own tag,
+		// since its body differs from array of the same posts
+		streamed := ndjson.IsAccepted(cntx)
+		cntx.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+		etag := conditional.ETag(thread.PostsVersion, thread.PostsUpdated)
+		if streamed {
+			etag = conditional.ETag(thread.PostsVersion, thread.PostsUpdated, 1)
+		}
+		if conditional.IsNotModified(cntx, etag, thread.PostsUpdated) {
+			return cntx.NoContent(http.StatusNotModified)
+		}
+
+		// Streamed listing isn't kept in memory, so only it may go without limit
+		if streamed {
+			writer := ndjson.NewWriter(cntx)
+			send := func(post *models.Post) error {
+				if req.Format == models.FormatHTML {
+					if err := th.postUcase.RenderHTML([]*models.Post{post}); err != nil {
+						return fmt.Errorf("%s", err.Message)
+					}
+				}
+				return writer.Encode(post)
+			}
+
+			send, flush := th.attachmentUcase.WithAttachments(send)
+
+			err := th.postUcase.StreamByThread(thread.ID, req.Since, &req.Pagination, send)
+			if err == nil {
+				err = flush()
+			}
+			if err != nil {
+				// Status is sent with the first post, so stream broken after it can only be cut
+				if writer.Started() {
+					logrus.Error(err.Message)
+					return nil
+				}
+				// logrus.Error(err.Message)
+				return cntx.JSON(err.HTTPCode, err.Response())
+			}
+			writer.Close()
+			return nil
+		}
+
+		req.Pagination.LimitTo(th.maxLimit)
+		posts, err := th.postUcase.ListByThread(thread.ID, req.Since, &req.Pagination)
 		if err != nil {
 			// logrus.Error(err.Message)
 			return cntx.JSON(err.HTTPCode, err.Response())
 		}
+
+		if err := th.attachmentUcase.FillPosts(posts); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		if req.Format == models.FormatHTML {
+			if err := th.postUcase.RenderHTML(posts); err != nil {
+				// logrus.Error(err.Message)
+				return cntx.JSON(err.HTTPCode, err.Response())
+			}
+		}
 		return cntx.JSON(http.StatusOK, posts)
 	}
 }
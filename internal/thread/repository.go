@@ -10,10 +10,15 @@ type ThreadRepository interface {
 	Insert(thread *models.Thread) error
 	Update(thread *models.Thread) error
 	VoteByID(threadID uint64, vote *models.Vote) error
+	DeleteVoteByID(threadID uint64, nickname string) error
 	SelectIDByID(threadID uint64) (uint64, error)
 	SelectIDBySlug(slug string) (uint64, error)
 	SelectBySlug(slug string) (*models.Thread, error)
 	SelectByID(threadID uint64) (*models.Thread, error)
 	SelectByPostID(postID uint64) (*models.Thread, error)
+	SelectAllByIDs(threadIDs []uint64) ([]*models.Thread, error)
 	SelectAllByForum(forumSlug string, since time.Time, pgnt *models.Pagination) ([]*models.Thread, error)
+	SelectAllByAuthor(nickname string, since time.Time, pgnt *models.Pagination) ([]*models.Thread, error)
+	SelectVotesByID(threadID uint64, since string, pgnt *models.Pagination) ([]*models.Vote, error)
+	SelectVotesByNickname(nickname string, since uint64, pgnt *models.Pagination) ([]*models.Vote, error)
 }
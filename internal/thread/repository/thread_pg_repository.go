@@ -9,6 +9,7 @@ import (
 
 	"github.com/OlegGibadulin/tech-db-forum/internal/models"
 	"github.com/OlegGibadulin/tech-db-forum/internal/thread"
+	"github.com/lib/pq"
 )
 
 type ThreadPgRepository struct {
@@ -28,34 +29,71 @@ func (tr *ThreadPgRepository) Insert(thread *models.Thread) error {
 	}
 
 	row := tx.QueryRow(
-		`INSERT INTO threads(title, author, message, created, forum, slug)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, votes`,
-		thread.Title, thread.Author, thread.Message, thread.Created, thread.Forum, thread.Slug)
+		`INSERT INTO threads(title, author, message, created, forum, slug, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, votes, version, edit_version`,
+		thread.Title, thread.Author, thread.Message, thread.Created, thread.Forum, thread.Slug,
+		thread.Status)
 
-	err = row.Scan(&thread.ID, &thread.Votes)
+	err = row.Scan(&thread.ID, &thread.Votes, &thread.Version, &thread.EditVersion)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
+	if thread.Poll != nil {
+		thread.Poll.Thread = thread.ID
+		if err := insertPoll(tx, thread.Poll); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		return err
 	}
 	return nil
 }
 
+func insertPoll(tx *sql.Tx, poll *models.Poll) error {
+	row := tx.QueryRow(
+		`INSERT INTO polls(thread, question, multiple, closes)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created`,
+		poll.Thread, poll.Question, poll.Multiple, poll.Closes)
+
+	if err := row.Scan(&poll.Created); err != nil {
+		return err
+	}
+
+	for ind, option := range poll.Options {
+		row := tx.QueryRow(
+			`INSERT INTO poll_options(thread, position, text)
+			VALUES ($1, $2, $3)
+			RETURNING id`,
+			poll.Thread, ind, option.Text)
+
+		if err := row.Scan(&option.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (tr *ThreadPgRepository) Update(thread *models.Thread) error {
 	tx, err := tr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
 	if err != nil {
 		return err
 	}
 
-	_, err = tx.Exec(
+	row := tx.QueryRow(
 		`UPDATE threads
 		SET title = $2, message = $3
-		WHERE id = $1`,
-		thread.ID, thread.Title, thread.Message)
+		WHERE id = $1 AND edit_version = $4
+		RETURNING version, updated, edit_version`,
+		thread.ID, thread.Title, thread.Message, thread.EditVersion)
+
+	err = row.Scan(&thread.Version, &thread.Updated, &thread.EditVersion)
 	if err != nil {
 		tx.Rollback()
 		return err
@@ -89,6 +127,27 @@ func (tr *ThreadPgRepository) VoteByID(threadID uint64, vote *models.Vote) error
 	return nil
 }
 
+func (tr *ThreadPgRepository) DeleteVoteByID(threadID uint64, nickname string) error {
+	tx, err := tr.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`DELETE FROM votes
+		WHERE nickname = $1 AND thread = $2`,
+		nickname, threadID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (tr *ThreadPgRepository) SelectIDByID(threadID uint64) (uint64, error) {
 	var checkedThreadID uint64
 
@@ -125,13 +184,15 @@ func (tr *ThreadPgRepository) SelectBySlug(slug string) (*models.Thread, error)
 	thread := &models.Thread{}
 
 	row := tr.dbConn.QueryRow(
-		`SELECT id, title, author, message, created, forum, votes, slug
+		`SELECT id, title, author, message, created, forum, votes, slug,
+			version, updated, posts_version, posts_updated, edit_version
 		FROM threads
 		WHERE slug=$1`,
 		slug)
 
 	err := row.Scan(&thread.ID, &thread.Title, &thread.Author, &thread.Message, &thread.Created,
-		&thread.Forum, &thread.Votes, &thread.Slug)
+		&thread.Forum, &thread.Votes, &thread.Slug,
+		&thread.Version, &thread.Updated, &thread.PostsVersion, &thread.PostsUpdated, &thread.EditVersion)
 	if err != nil {
 		return nil, err
 	}
@@ -142,13 +203,15 @@ func (tr *ThreadPgRepository) SelectByID(threadID uint64) (*models.Thread, error
 	thread := &models.Thread{}
 
 	row := tr.dbConn.QueryRow(
-		`SELECT id, title, author, message, created, forum, votes, slug
+		`SELECT id, title, author, message, created, forum, votes, slug,
+			version, updated, posts_version, posts_updated, edit_version
 		FROM threads
 		WHERE id=$1`,
 		threadID)
 
 	err := row.Scan(&thread.ID, &thread.Title, &thread.Author, &thread.Message, &thread.Created,
-		&thread.Forum, &thread.Votes, &thread.Slug)
+		&thread.Forum, &thread.Votes, &thread.Slug,
+		&thread.Version, &thread.Updated, &thread.PostsVersion, &thread.PostsUpdated, &thread.EditVersion)
 	if err != nil {
 		return nil, err
 	}
@@ -173,20 +236,55 @@ func (tr *ThreadPgRepository) SelectByPostID(postID uint64) (*models.Thread, err
 	return thread, nil
 }
 
+func (tr *ThreadPgRepository) SelectAllByIDs(threadIDs []uint64) ([]*models.Thread, error) {
+	ids := make([]int64, 0, len(threadIDs))
+	for _, threadID := range threadIDs {
+		ids = append(ids, int64(threadID))
+	}
+
+	rows, err := tr.dbConn.Query(
+		`SELECT id, title, author, message, created, forum, votes, slug,
+			version, updated, posts_version, posts_updated, edit_version
+		FROM threads
+		WHERE id = ANY($1)`,
+		pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var threads []*models.Thread
+	for rows.Next() {
+		thread := &models.Thread{}
+		err := rows.Scan(&thread.ID, &thread.Title, &thread.Author, &thread.Message, &thread.Created,
+			&thread.Forum, &thread.Votes, &thread.Slug,
+			&thread.Version, &thread.Updated, &thread.PostsVersion, &thread.PostsUpdated, &thread.EditVersion)
+		if err != nil {
+			return nil, err
+		}
+		threads = append(threads, thread)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return threads, nil
+}
+
 func (tr *ThreadPgRepository) SelectAllByForum(forumSlug string, since time.Time, pgnt *models.Pagination) ([]*models.Thread, error) {
 	var values []interface{}
 
 	selectQuery := `
 		SELECT id, title, author, message, created, forum, votes, slug
 		FROM threads
-		WHERE forum=$1`
+		WHERE forum=$1
+		AND status='approved'`
 	values = append(values, forumSlug)
 
 	var sortQuery string
 	if pgnt.Desc {
-		sortQuery = "ORDER BY created DESC"
+		sortQuery = "ORDER BY created DESC, id DESC"
 	} else {
-		sortQuery = "ORDER BY created"
+		sortQuery = "ORDER BY created, id"
 	}
 
 	var pgntQuery string
@@ -234,3 +332,174 @@ func (tr *ThreadPgRepository) SelectAllByForum(forumSlug string, since time.Time
 	}
 	return threads, nil
 }
+
+func (tr *ThreadPgRepository) SelectAllByAuthor(nickname string, since time.Time, pgnt *models.Pagination) ([]*models.Thread, error) {
+	var values []interface{}
+
+	selectQuery := `
+		SELECT id, title, author, message, created, forum, votes, slug
+		FROM threads
+		WHERE author=$1
+		AND status='approved'`
+	values = append(values, nickname)
+
+	var sortQuery string
+	if pgnt.Desc {
+		sortQuery = "ORDER BY created DESC, id DESC"
+	} else {
+		sortQuery = "ORDER BY created, id"
+	}
+
+	var pgntQuery string
+	if pgnt.Limit != 0 {
+		pgntQuery = "LIMIT $2"
+		values = append(values, pgnt.Limit)
+	}
+
+	var filterQuery string
+	if !since.IsZero() {
+		ind := len(values) + 1
+		if pgnt.Desc {
+			filterQuery = "AND created <= $" + strconv.Itoa(ind)
+		} else {
+			filterQuery = "AND created >= $" + strconv.Itoa(ind)
+		}
+		values = append(values, since)
+	}
+
+	resultQuery := strings.Join([]string{
+		selectQuery,
+		filterQuery,
+		sortQuery,
+		pgntQuery,
+	}, " ")
+
+	rows, err := tr.dbConn.Query(resultQuery, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var threads []*models.Thread
+	for rows.Next() {
+		thread := &models.Thread{}
+		err := rows.Scan(&thread.ID, &thread.Title, &thread.Author, &thread.Message, &thread.Created,
+			&thread.Forum, &thread.Votes, &thread.Slug)
+		if err != nil {
+			return nil, err
+		}
+		threads = append(threads, thread)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return threads, nil
+}
+
+func (tr *ThreadPgRepository) SelectVotesByID(threadID uint64, since string, pgnt *models.Pagination) ([]*models.Vote, error) {
+	var values []interface{}
+
+	selectQuery := `
+		SELECT nickname, voice, thread
+		FROM votes
+		WHERE thread=$1`
+	values = append(values, threadID)
+
+	var sortQuery string
+	if pgnt.Desc {
+		sortQuery = "ORDER BY nickname DESC"
+	} else {
+		sortQuery = "ORDER BY nickname"
+	}
+
+	var pgntQuery string
+	if pgnt.Limit != 0 {
+		pgntQuery = "LIMIT $2"
+		values = append(values, pgnt.Limit)
+	}
+
+	var filterQuery string
+	if since != "" {
+		ind := len(values) + 1
+		if pgnt.Desc {
+			filterQuery = "AND nickname < $" + strconv.Itoa(ind)
+		} else {
+			filterQuery = "AND nickname > $" + strconv.Itoa(ind)
+		}
+		values = append(values, since)
+	}
+
+	resultQuery := strings.Join([]string{
+		selectQuery,
+		filterQuery,
+		sortQuery,
+		pgntQuery,
+	}, " ")
+
+	return tr.selectVotes(resultQuery, values)
+}
+
+func (tr *ThreadPgRepository) SelectVotesByNickname(nickname string, since uint64, pgnt *models.Pagination) ([]*models.Vote, error) {
+	var values []interface{}
+
+	selectQuery := `
+		SELECT nickname, voice, thread
+		FROM votes
+		WHERE nickname=$1`
+	values = append(values, nickname)
+
+	var sortQuery string
+	if pgnt.Desc {
+		sortQuery = "ORDER BY thread DESC"
+	} else {
+		sortQuery = "ORDER BY thread"
+	}
+
+	var pgntQuery string
+	if pgnt.Limit != 0 {
+		pgntQuery = "LIMIT $2"
+		values = append(values, pgnt.Limit)
+	}
+
+	var filterQuery string
+	if since != 0 {
+		ind := len(values) + 1
+		if pgnt.Desc {
+			filterQuery = "AND thread < $" + strconv.Itoa(ind)
+		} else {
+			filterQuery = "AND thread > $" + strconv.Itoa(ind)
+		}
+		values = append(values, since)
+	}
+
+	resultQuery := strings.Join([]string{
+		selectQuery,
+		filterQuery,
+		sortQuery,
+		pgntQuery,
+	}, " ")
+
+	return tr.selectVotes(resultQuery, values)
+}
+
+func (tr *ThreadPgRepository) selectVotes(query string, values []interface{}) ([]*models.Vote, error) {
+	rows, err := tr.dbConn.Query(query, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var votes []*models.Vote
+	for rows.Next() {
+		vote := &models.Vote{}
+		err := rows.Scan(&vote.Nickname, &vote.Voice, &vote.Thread)
+		if err != nil {
+			return nil, err
+		}
+		votes = append(votes, vote)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return votes, nil
+}
@@ -10,11 +10,16 @@ import (
 type ThreadUsecase interface {
 	Create(thread *models.Thread) *errors.Error
 	Update(threadSlugOrID string, threadData *models.Thread) (*models.Thread, *errors.Error)
+	RenderHTML(threads []*models.Thread) *errors.Error
 	GetBySlug(threadSlug string) (*models.Thread, *errors.Error)
 	GetByID(threadID uint64) (*models.Thread, *errors.Error)
 	GetBySlugOrID(threadSlugOrID string) (*models.Thread, *errors.Error)
 	GetByPostID(postID uint64) (*models.Thread, *errors.Error)
 	CheckThreadExistence(threadSlugOrID string) (uint64, *errors.Error)
+	ListByIDs(threadIDs []uint64) ([]*models.Thread, *errors.Error)
 	Vote(threadSlugOrID string, vote *models.Vote) (*models.Thread, *errors.Error)
 	ListByForum(forumSlug string, since time.Time, pgnt *models.Pagination) ([]*models.Thread, *errors.Error)
+	ListByAuthor(nickname string, since time.Time, pgnt *models.Pagination) ([]*models.Thread, *errors.Error)
+	ListVotes(threadID uint64, since string, pgnt *models.Pagination) ([]*models.Vote, *errors.Error)
+	ListVotesByNickname(nickname string, since uint64, pgnt *models.Pagination) ([]*models.Vote, *errors.Error)
 }
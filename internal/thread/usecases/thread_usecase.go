@@ -2,6 +2,7 @@ package usecases
 
 import (
 	"database/sql"
+	"fmt"
 	"time"
 
 	"strconv"
@@ -9,20 +10,34 @@ import (
 	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
 	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
 	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+	"github.com/OlegGibadulin/tech-db-forum/internal/moderation"
+	"github.com/OlegGibadulin/tech-db-forum/internal/sanction"
 	"github.com/OlegGibadulin/tech-db-forum/internal/thread"
+	"github.com/OlegGibadulin/tech-db-forum/pkg/markdown"
 )
 
 type ThreadUsecase struct {
-	threadRepo thread.ThreadRepository
+	threadRepo      thread.ThreadRepository
+	sanctionUcase   sanction.SanctionUsecase
+	moderationUcase moderation.ModerationUsecase
+	renderer        *markdown.Renderer
 }
 
-func NewThreadUsecase(repo thread.ThreadRepository) thread.ThreadUsecase {
+func NewThreadUsecase(repo thread.ThreadRepository, sanctionUcase sanction.SanctionUsecase,
+	moderationUcase moderation.ModerationUsecase, renderer *markdown.Renderer) thread.ThreadUsecase {
 	return &ThreadUsecase{
-		threadRepo: repo,
+		threadRepo:      repo,
+		sanctionUcase:   sanctionUcase,
+		moderationUcase: moderationUcase,
+		renderer:        renderer,
 	}
 }
 
 func (tu *ThreadUsecase) Create(thread *models.Thread) *errors.Error {
+	if customErr := tu.sanctionUcase.CheckUsers([]string{thread.Author}, thread.Forum); customErr != nil {
+		return customErr
+	}
+
 	if thread.Slug != "" {
 		anotherThread, customErr := tu.GetBySlug(thread.Slug)
 		if customErr == nil {
@@ -33,6 +48,10 @@ func (tu *ThreadUsecase) Create(thread *models.Thread) *errors.Error {
 		}
 	}
 
+	if customErr := tu.moderationUcase.CheckThread(thread); customErr != nil {
+		return customErr
+	}
+
 	if err := tu.threadRepo.Insert(thread); err != nil {
 		return errors.New(CodeInternalError, err)
 	}
@@ -48,16 +67,49 @@ func (tu *ThreadUsecase) Update(threadSlugOrID string, threadData *models.Thread
 	if threadData.Title != "" {
 		thread.Title = threadData.Title
 	}
-	if threadData.Message != "" {
+	isMessageChanged := threadData.Message != "" && threadData.Message != thread.Message
+	if isMessageChanged {
 		thread.Message = threadData.Message
 	}
+	// Edit version of precondition checked by caller wins over the one just read
+	if threadData.EditVersion != 0 {
+		thread.EditVersion = threadData.EditVersion
+	}
 
-	if err := tu.threadRepo.Update(thread); err != nil {
+	err := tu.threadRepo.Update(thread)
+	switch {
+	case err == sql.ErrNoRows:
+		currentThread, customErr := tu.GetByID(thread.ID)
+		if customErr != nil {
+			return nil, customErr
+		}
+		return nil, errors.BuildByBody(CodeVersionConflict, currentThread)
+	case err != nil:
 		return nil, errors.New(CodeInternalError, err)
 	}
+
+	if isMessageChanged {
+		tu.renderer.Invalidate(threadRenderKey(thread.ID))
+	}
 	return thread, nil
 }
 
+func threadRenderKey(threadID uint64) string {
+	return fmt.Sprintf("thread:%d", threadID)
+}
+
+// RenderHTML fills rendered messages of threads, cached HTML is reused until message is updated
+func (tu *ThreadUsecase) RenderHTML(threads []*models.Thread) *errors.Error {
+	for _, thread := range threads {
+		html, err := tu.renderer.RenderCached(threadRenderKey(thread.ID), thread.Message)
+		if err != nil {
+			return errors.New(CodeInternalError, err)
+		}
+		thread.MessageHTML = html
+	}
+	return nil
+}
+
 func (tu *ThreadUsecase) GetBySlug(threadSlug string) (*models.Thread, *errors.Error) {
 	thread, err := tu.threadRepo.SelectBySlug(threadSlug)
 	switch {
@@ -134,17 +186,46 @@ func (tu *ThreadUsecase) CheckThreadExistence(threadSlugOrID string) (uint64, *e
 	return threadID, nil
 }
 
+func (tu *ThreadUsecase) ListByIDs(threadIDs []uint64) ([]*models.Thread, *errors.Error) {
+	if len(threadIDs) == 0 {
+		return []*models.Thread{}, nil
+	}
+	threads, err := tu.threadRepo.SelectAllByIDs(threadIDs)
+	if err != nil {
+		return nil, errors.New(CodeInternalError, err)
+	}
+	if len(threads) == 0 {
+		return []*models.Thread{}, nil
+	}
+	return threads, nil
+}
+
 func (tu *ThreadUsecase) Vote(threadSlugOrID string, vote *models.Vote) (*models.Thread, *errors.Error) {
-	threadID, customErr := tu.CheckThreadExistence(threadSlugOrID)
+	if vote.Voice != models.VoiceUp && vote.Voice != models.VoiceDown && vote.Voice != models.VoiceRetract {
+		return nil, errors.BuildByMsg(CodeWrongVoice, vote.Voice)
+	}
+
+	thread, customErr := tu.GetBySlugOrID(threadSlugOrID)
 	if customErr != nil {
 		return nil, customErr
 	}
+	threadID := thread.ID
+
+	if customErr := tu.sanctionUcase.CheckUsers([]string{vote.Nickname}, thread.Forum); customErr != nil {
+		return nil, customErr
+	}
 
-	if err := tu.threadRepo.VoteByID(threadID, vote); err != nil {
+	var err error
+	if vote.Voice == models.VoiceRetract {
+		err = tu.threadRepo.DeleteVoteByID(threadID, vote.Nickname)
+	} else {
+		err = tu.threadRepo.VoteByID(threadID, vote)
+	}
+	if err != nil {
 		return nil, errors.New(CodeInternalError, err)
 	}
 
-	thread, customErr := tu.GetByID(threadID)
+	thread, customErr = tu.GetByID(threadID)
 	if customErr != nil {
 		return nil, customErr
 	}
@@ -161,3 +242,36 @@ func (tu *ThreadUsecase) ListByForum(forumSlug string, since time.Time, pgnt *mo
 	}
 	return threads, nil
 }
+
+func (tu *ThreadUsecase) ListByAuthor(nickname string, since time.Time, pgnt *models.Pagination) ([]*models.Thread, *errors.Error) {
+	threads, err := tu.threadRepo.SelectAllByAuthor(nickname, since, pgnt)
+	if err != nil {
+		return nil, errors.New(CodeInternalError, err)
+	}
+	if len(threads) == 0 {
+		return []*models.Thread{}, nil
+	}
+	return threads, nil
+}
+
+func (tu *ThreadUsecase) ListVotes(threadID uint64, since string, pgnt *models.Pagination) ([]*models.Vote, *errors.Error) {
+	votes, err := tu.threadRepo.SelectVotesByID(threadID, since, pgnt)
+	if err != nil {
+		return nil, errors.New(CodeInternalError, err)
+	}
+	if len(votes) == 0 {
+		return []*models.Vote{}, nil
+	}
+	return votes, nil
+}
+
+func (tu *ThreadUsecase) ListVotesByNickname(nickname string, since uint64, pgnt *models.Pagination) ([]*models.Vote, *errors.Error) {
+	votes, err := tu.threadRepo.SelectVotesByNickname(nickname, since, pgnt)
+	if err != nil {
+		return nil, errors.New(CodeInternalError, err)
+	}
+	if len(votes) == 0 {
+		return []*models.Vote{}, nil
+	}
+	return votes, nil
+}
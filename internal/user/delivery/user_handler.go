@@ -1,29 +1,64 @@
 package delivery
 
 import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"time"
 
+	"github.com/OlegGibadulin/tech-db-forum/internal/attachment"
+	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/forum"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/conditional"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/ndjson"
 	"github.com/OlegGibadulin/tech-db-forum/internal/models"
 	"github.com/OlegGibadulin/tech-db-forum/internal/mwares"
+	"github.com/OlegGibadulin/tech-db-forum/internal/post"
+	"github.com/OlegGibadulin/tech-db-forum/internal/thread"
 	"github.com/OlegGibadulin/tech-db-forum/internal/user"
 	reader "github.com/OlegGibadulin/tech-db-forum/tools/request_reader"
 	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
 )
 
 type UserHandler struct {
-	userUcase user.UserUsecase
+	userUcase       user.UserUsecase
+	threadUcase     thread.ThreadUsecase
+	postUcase       post.PostUsecase
+	forumUcase      forum.ForumUsecase
+	attachmentUcase attachment.AttachmentUsecase
+	maxLimit        uint64
 }
 
-func NewUserHandler(userUcase user.UserUsecase) *UserHandler {
+func NewUserHandler(userUcase user.UserUsecase, threadUcase thread.ThreadUsecase,
+	postUcase post.PostUsecase, forumUcase forum.ForumUsecase, attachmentUcase attachment.AttachmentUsecase,
+	maxLimit uint64) *UserHandler {
 	return &UserHandler{
-		userUcase: userUcase,
+		userUcase:       userUcase,
+		threadUcase:     threadUcase,
+		postUcase:       postUcase,
+		forumUcase:      forumUcase,
+		attachmentUcase: attachmentUcase,
+		maxLimit:        maxLimit,
 	}
 }
 
 func (uh *UserHandler) Configure(e *echo.Echo, mw *mwares.MiddlewareManager) {
-	e.POST("/api/user/:nickname/create", uh.CreateUserHandler())
+	e.POST("/api/user/:nickname/create", uh.CreateUserHandler(), mw.Idempotency)
 	e.GET("/api/user/:nickname/profile", uh.GetUserHandler())
 	e.POST("/api/user/:nickname/profile", uh.UpdateUserHandler())
+	e.POST("/api/user/:nickname/rename", uh.RenameUserHandler())
+	e.DELETE("/api/user/:nickname", uh.DeleteUserHandler())
+	e.GET("/api/user/:nickname/export", uh.ExportUserHandler())
+	e.GET("/api/user/:nickname/votes", uh.GetVotesByUserHandler())
+	e.GET("/api/user/:nickname/posts", uh.GetPostsByUserHandler())
+	e.GET("/api/user/:nickname/threads", uh.GetThreadsByUserHandler())
+	e.GET("/api/user/:nickname/forums", uh.GetForumsByUserHandler())
+	e.GET("/api/user/:nickname/activity", uh.GetActivityByUserHandler())
 }
 
 func (uh *UserHandler) CreateUserHandler() echo.HandlerFunc {
@@ -60,30 +95,387 @@ func (uh *UserHandler) UpdateUserHandler() echo.HandlerFunc {
 			return cntx.JSON(err.HTTPCode, err.Response())
 		}
 
-		nickname := cntx.Param("nickname")
 		userData := &models.User{
 			Fullname: req.Fullname,
 			Email:    req.Email,
 			About:    req.About,
 		}
 
+		nickname := cntx.Param("nickname")
+		if conditional.HasPrecondition(cntx) {
+			profile, err := uh.userUcase.GetProfile(nickname)
+			if err != nil {
+				// logrus.Error(err.Message)
+				return cntx.JSON(err.HTTPCode, err.Response())
+			}
+
+			etag := profileETag(profile)
+			if !conditional.MatchesPrecondition(cntx, etag) {
+				err := errors.BuildByMsg(CodePreconditionFailed, etag)
+				return cntx.JSON(err.HTTPCode, err.Response())
+			}
+			userData.EditVersion = profile.EditVersion
+		}
+
 		user, err := uh.userUcase.Update(nickname, userData)
 		if err != nil {
 			// logrus.Error(err.Message)
 			return cntx.JSON(err.HTTPCode, err.Response())
 		}
+
+		// Tag of profile lets client chain edits, update is done anyway, so it is skipped on failure
+		if profile, err := uh.userUcase.GetProfile(user.Nickname); err == nil {
+			cntx.Response().Header().Set("ETag", profileETag(profile))
+		} else {
+			logrus.Warn(err.Message)
+		}
+		return cntx.JSON(http.StatusOK, user)
+	}
+}
+
+func (uh *UserHandler) RenameUserHandler() echo.HandlerFunc {
+	type Request struct {
+		Nickname string `json:"nickname" validate:"required,gte=3,lte=32"`
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		nickname := cntx.Param("nickname")
+		user, err := uh.userUcase.Rename(nickname, req.Nickname)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
 		return cntx.JSON(http.StatusOK, user)
 	}
 }
 
+// profileETag takes counters into account since they are changed without user version
+func profileETag(profile *models.Profile) string {
+	return conditional.ETag(profile.Version, profile.Updated,
+		profile.Reputation, profile.Followers, profile.Following)
+}
+
 func (uh *UserHandler) GetUserHandler() echo.HandlerFunc {
 	return func(cntx echo.Context) error {
+		nickname := cntx.Param("nickname")
+		profile, err := uh.userUcase.GetProfile(nickname)
+		if err != nil && err.Code == CodeUserDoesNotExist {
+			// Old nickname of renamed user
+			if redirect, redirectErr := uh.userUcase.GetRedirect(nickname); redirectErr == nil {
+				location := fmt.Sprintf("/api/user/%s/profile", url.PathEscape(redirect.Owner))
+				return cntx.Redirect(http.StatusMovedPermanently, location)
+			}
+		}
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		// Counters are not tracked by time, so profile has no Last-Modified
+		if conditional.IsNotModified(cntx, profileETag(profile), time.Time{}) {
+			return cntx.NoContent(http.StatusNotModified)
+		}
+		return cntx.JSON(http.StatusOK, profile)
+	}
+}
+
+func (uh *UserHandler) GetVotesByUserHandler() echo.HandlerFunc {
+	type Request struct {
+		Since uint64 `query:"since"`
+		models.Pagination
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
 		nickname := cntx.Param("nickname")
 		user, err := uh.userUcase.GetByNickname(nickname)
 		if err != nil {
 			// logrus.Error(err.Message)
 			return cntx.JSON(err.HTTPCode, err.Response())
 		}
-		return cntx.JSON(http.StatusOK, user)
+
+		votes, err := uh.threadUcase.ListVotesByNickname(user.Nickname, req.Since, &req.Pagination)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, votes)
+	}
+}
+
+func (uh *UserHandler) GetPostsByUserHandler() echo.HandlerFunc {
+	type Request struct {
+		Since  uint64 `query:"since"`
+		Format string `query:"format"`
+		models.Pagination
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		nickname := cntx.Param("nickname")
+		user, err := uh.userUcase.GetByNickname(nickname)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		// Streamed listing isn't kept in memory, so only it may go without limit
+		if ndjson.IsAccepted(cntx) {
+			writer := ndjson.NewWriter(cntx)
+			send := func(post *models.Post) error {
+				if req.Format == models.FormatHTML {
+					if err := uh.postUcase.RenderHTML([]*models.Post{post}); err != nil {
+						return fmt.Errorf("%s", err.Message)
+					}
+				}
+				return writer.Encode(post)
+			}
+
+			send, flush := uh.attachmentUcase.WithAttachments(send)
+
+			err := uh.postUcase.StreamByAuthor(user.Nickname, req.Since, &req.Pagination, send)
+			if err == nil {
+				err = flush()
+			}
+			if err != nil {
+				// Status is sent with the first post, so stream broken after it can only be cut
+				if writer.Started() {
+					logrus.Error(err.Message)
+					return nil
+				}
+				// logrus.Error(err.Message)
+				return cntx.JSON(err.HTTPCode, err.Response())
+			}
+			writer.Close()
+			return nil
+		}
+
+		req.Pagination.LimitTo(uh.maxLimit)
+		posts, err := uh.postUcase.ListByAuthor(user.Nickname, req.Since, &req.Pagination)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		if err := uh.attachmentUcase.FillPosts(posts); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		if req.Format == models.FormatHTML {
+			if err := uh.postUcase.RenderHTML(posts); err != nil {
+				// logrus.Error(err.Message)
+				return cntx.JSON(err.HTTPCode, err.Response())
+			}
+		}
+		return cntx.JSON(http.StatusOK, posts)
+	}
+}
+
+func (uh *UserHandler) GetThreadsByUserHandler() echo.HandlerFunc {
+	type Request struct {
+		Since  time.Time `query:"since"`
+		Format string    `query:"format"`
+		models.Pagination
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		nickname := cntx.Param("nickname")
+		user, err := uh.userUcase.GetByNickname(nickname)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		threads, err := uh.threadUcase.ListByAuthor(user.Nickname, req.Since, &req.Pagination)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		if req.Format == models.FormatHTML {
+			if err := uh.threadUcase.RenderHTML(threads); err != nil {
+				// logrus.Error(err.Message)
+				return cntx.JSON(err.HTTPCode, err.Response())
+			}
+		}
+		return cntx.JSON(http.StatusOK, threads)
+	}
+}
+
+func (uh *UserHandler) GetForumsByUserHandler() echo.HandlerFunc {
+	type Request struct {
+		Since string `query:"since"`
+		models.Pagination
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		nickname := cntx.Param("nickname")
+		user, err := uh.userUcase.GetByNickname(nickname)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		forums, err := uh.forumUcase.ListByUser(user.Nickname, req.Since, &req.Pagination)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, forums)
+	}
+}
+
+func (uh *UserHandler) GetActivityByUserHandler() echo.HandlerFunc {
+	type Request struct {
+		Since time.Time `query:"since"`
+		models.Pagination
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		nickname := cntx.Param("nickname")
+		user, err := uh.userUcase.GetByNickname(nickname)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		activities, err := uh.userUcase.ListActivity(user.Nickname, req.Since, &req.Pagination)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, activities)
+	}
+}
+
+func (uh *UserHandler) DeleteUserHandler() echo.HandlerFunc {
+	return func(cntx echo.Context) error {
+		nickname := cntx.Param("nickname")
+		deletion, err := uh.userUcase.Delete(nickname)
+		if err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		return cntx.JSON(http.StatusOK, deletion)
+	}
+}
+
+func writeUserExportZip(cntx echo.Context, export *models.UserExport) error {
+	filename := fmt.Sprintf("%s-export.zip", export.Profile.Nickname)
+	cntx.Response().Header().Set(echo.HeaderContentType, "application/zip")
+	cntx.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	cntx.Response().WriteHeader(http.StatusOK)
+
+	files := []struct {
+		name    string
+		content interface{}
+	}{
+		{"export.json", export},
+		{"profile.json", export.Profile},
+		{"threads.json", export.Threads},
+		{"posts.json", export.Posts},
+		{"votes.json", export.Votes},
+		{"post_votes.json", export.PostVotes},
+	}
+
+	archive := zip.NewWriter(cntx.Response())
+	for _, file := range files {
+		writer, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func (uh *UserHandler) ExportUserHandler() echo.HandlerFunc {
+	type Request struct {
+		Format string `query:"format"`
+	}
+
+	return func(cntx echo.Context) error {
+		req := &Request{}
+		if err := reader.NewRequestReader(cntx).Read(req); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		export := &models.UserExport{
+			Version:  models.UserExportVersion,
+			Exported: time.Now(),
+		}
+		var err *errors.Error
+
+		nickname := cntx.Param("nickname")
+		if export.Profile, err = uh.userUcase.GetProfile(nickname); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		// Whole history of user without pagination
+		nickname = export.Profile.Nickname
+		pgnt := &models.Pagination{}
+
+		if export.Threads, err = uh.threadUcase.ListByAuthor(nickname, time.Time{}, pgnt); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		if export.Posts, err = uh.postUcase.ListByAuthor(nickname, 0, pgnt); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		if export.Votes, err = uh.threadUcase.ListVotesByNickname(nickname, 0, pgnt); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+		if export.PostVotes, err = uh.postUcase.ListVotesByNickname(nickname, 0, pgnt); err != nil {
+			// logrus.Error(err.Message)
+			return cntx.JSON(err.HTTPCode, err.Response())
+		}
+
+		if req.Format == "zip" {
+			return writeUserExportZip(cntx, export)
+		}
+		return cntx.JSON(http.StatusOK, export)
 	}
 }
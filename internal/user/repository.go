@@ -1,14 +1,24 @@
 package user
 
-import "github.com/OlegGibadulin/tech-db-forum/internal/models"
+import (
+	"time"
+
+	"github.com/OlegGibadulin/tech-db-forum/internal/models"
+)
 
 type UserRepository interface {
 	Insert(user *models.User) error
 	Update(user *models.User) error
+	Rename(nickname string, newNickname string) error
+	Delete(nickname string) (*models.UserDeletion, error)
 	SelectByNickname(nickname string) (*models.User, error)
 	SelectByEmail(email string) (*models.User, error)
+	SelectProfileByNickname(nickname string) (*models.Profile, error)
+	SelectRedirectByNickname(nickname string) (*models.NicknameRedirect, error)
 	SelectByPostID(postID uint64) (*models.User, error)
 	SelectExistingUsersCount(nicknames []string) (int, error)
+	SelectAllByNicknames(nicknames []string) ([]*models.User, error)
 	SelectAllByNicknameOrEmail(nickname string, email string) ([]*models.User, error)
 	SelectAllByForum(forumSlug string, since string, pgnt *models.Pagination) ([]*models.User, error)
+	SelectActivity(nickname string, since time.Time, pgnt *models.Pagination) ([]*models.Activity, error)
 }
@@ -3,12 +3,16 @@ package repository
 import (
 	"context"
 	"database/sql"
-	"fmt"
+	"errors"
 	"strconv"
 	"strings"
+	"time"
 
+	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
+	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/placeholders"
 	"github.com/OlegGibadulin/tech-db-forum/internal/models"
 	"github.com/OlegGibadulin/tech-db-forum/internal/user"
+	"github.com/lib/pq"
 )
 
 type UserPgRepository struct {
@@ -36,6 +40,16 @@ func (ur *UserPgRepository) Insert(user *models.User) error {
 		return err
 	}
 
+	// Reused nickname doesn't redirect to its previous owner anymore
+	_, err = tx.Exec(
+		`DELETE FROM nickname_history
+		WHERE nickname = $1`,
+		user.Nickname)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return err
 	}
@@ -48,11 +62,60 @@ func (ur *UserPgRepository) Update(user *models.User) error {
 		return err
 	}
 
-	_, err = tx.Exec(
+	row := tx.QueryRow(
 		`UPDATE users
 		SET fullname = $2, email = $3, about = $4
+		WHERE nickname = $1 AND edit_version = $5
+		RETURNING version, updated, edit_version`,
+		user.Nickname, user.Fullname, user.Email, user.About, user.EditVersion)
+
+	err = row.Scan(&user.Version, &user.Updated, &user.EditVersion)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (ur *UserPgRepository) Rename(nickname string, newNickname string) error {
+	tx, err := ur.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	// User can take back own released nickname
+	_, err = tx.Exec(
+		`DELETE FROM nickname_history
 		WHERE nickname = $1`,
-		user.Nickname, user.Fullname, user.Email, user.About)
+		newNickname)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// All references to nickname are updated by ON UPDATE CASCADE
+	_, err = tx.Exec(
+		`UPDATE users
+		SET nickname = $2
+		WHERE nickname = $1`,
+		nickname, newNickname)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Changing only the case of letters doesn't release nickname
+	_, err = tx.Exec(
+		`INSERT INTO nickname_history(nickname, owner)
+		SELECT $1::citext, $2::citext
+		WHERE $1::citext <> $2::citext
+		ON CONFLICT (nickname) DO UPDATE
+		SET owner = EXCLUDED.owner, released = now()`,
+		nickname, newNickname)
 	if err != nil {
 		tx.Rollback()
 		return err
@@ -64,16 +127,119 @@ func (ur *UserPgRepository) Update(user *models.User) error {
 	return nil
 }
 
+func (ur *UserPgRepository) Delete(nickname string) (*models.UserDeletion, error) {
+	tx, err := ur.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	deletion := &models.UserDeletion{}
+
+	// Lock user so nothing new is authored during deletion
+	row := tx.QueryRow(
+		`SELECT u.nickname,
+		(SELECT COUNT(*) FROM threads WHERE author=u.nickname),
+		(SELECT COUNT(*) FROM posts WHERE author=u.nickname),
+		(SELECT COUNT(*) FROM votes WHERE nickname=u.nickname) +
+		(SELECT COUNT(*) FROM post_votes WHERE nickname=u.nickname)
+		FROM users AS u
+		WHERE u.nickname=$1
+		FOR UPDATE`,
+		nickname)
+
+	err = row.Scan(&deletion.Nickname, &deletion.Threads, &deletion.Posts, &deletion.Votes)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO users(nickname, fullname, email, about)
+		VALUES ($1, $2, $3, '')
+		ON CONFLICT DO NOTHING`,
+		models.GhostNickname, models.GhostFullname, models.GhostEmail)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Content must not be handed over to real user who took ghost nickname or email before they were reserved
+	var ghostEmail string
+	row = tx.QueryRow(`SELECT email FROM users WHERE nickname=$1`, models.GhostNickname)
+	err = row.Scan(&ghostEmail)
+	if err == sql.ErrNoRows || (err == nil && !strings.EqualFold(ghostEmail, models.GhostEmail)) {
+		tx.Rollback()
+		return nil, errors.New(OnUserDeleteExceptionMsgGhostIsTaken)
+	}
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	queries := []string{
+		// Authored content is kept, so post trees and counters stay intact
+		`UPDATE forums SET author = $2 WHERE author = $1`,
+		`UPDATE threads SET author = $2 WHERE author = $1`,
+		`UPDATE posts SET author = $2 WHERE author = $1`,
+		`UPDATE notifications SET author = $2 WHERE author = $1`,
+		`INSERT INTO forum_user(nickname, forum)
+		SELECT $2, forum FROM forum_user WHERE nickname = $1
+		ON CONFLICT DO NOTHING`,
+	}
+	for _, query := range queries {
+		_, err = tx.Exec(query, deletion.Nickname, models.GhostNickname)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	queries = []string{
+		// Votes are removed, triggers correct thread votes and post scores
+		`DELETE FROM votes WHERE nickname = $1`,
+		`DELETE FROM post_votes WHERE nickname = $1`,
+
+		// Follows, notifications, settings and nickname history are removed by cascade,
+		// so are poll ballots, whose triggers correct poll tallies
+		`DELETE FROM users WHERE nickname = $1`,
+	}
+	for _, query := range queries {
+		_, err = tx.Exec(query, deletion.Nickname)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	row = tx.QueryRow(
+		`INSERT INTO user_deletions(nickname, threads, posts, votes)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, deleted`,
+		deletion.Nickname, deletion.Threads, deletion.Posts, deletion.Votes)
+
+	err = row.Scan(&deletion.ID, &deletion.Deleted)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return deletion, nil
+}
+
 func (ur *UserPgRepository) SelectByNickname(nickname string) (*models.User, error) {
 	user := &models.User{}
 
 	row := ur.dbConn.QueryRow(
-		`SELECT nickname, fullname, email, about
+		`SELECT nickname, fullname, email, about, version, updated, edit_version
 		FROM users
 		WHERE nickname=$1`,
 		nickname)
 
-	err := row.Scan(&user.Nickname, &user.Fullname, &user.Email, &user.About)
+	err := row.Scan(&user.Nickname, &user.Fullname, &user.Email, &user.About, &user.Version, &user.Updated,
+		&user.EditVersion)
 	if err != nil {
 		return nil, err
 	}
@@ -96,6 +262,46 @@ func (ur *UserPgRepository) SelectByEmail(email string) (*models.User, error) {
 	return user, nil
 }
 
+func (ur *UserPgRepository) SelectProfileByNickname(nickname string) (*models.Profile, error) {
+	profile := &models.Profile{}
+
+	// Reputation is the sum of votes received on user's posts and threads
+	row := ur.dbConn.QueryRow(
+		`SELECT u.nickname, u.fullname, u.email, u.about,
+		(SELECT COALESCE(SUM(p.score), 0) FROM posts AS p WHERE p.author=u.nickname) +
+		(SELECT COALESCE(SUM(t.votes), 0) FROM threads AS t WHERE t.author=u.nickname),
+		(SELECT COUNT(*) FROM user_follows AS uf WHERE uf.followee=u.nickname),
+		(SELECT COUNT(*) FROM user_follows AS uf WHERE uf.follower=u.nickname),
+		u.version, u.updated, u.edit_version
+		FROM users AS u
+		WHERE u.nickname=$1`,
+		nickname)
+
+	err := row.Scan(&profile.Nickname, &profile.Fullname, &profile.Email, &profile.About,
+		&profile.Reputation, &profile.Followers, &profile.Following, &profile.Version, &profile.Updated,
+		&profile.EditVersion)
+	if err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+func (ur *UserPgRepository) SelectRedirectByNickname(nickname string) (*models.NicknameRedirect, error) {
+	redirect := &models.NicknameRedirect{}
+
+	row := ur.dbConn.QueryRow(
+		`SELECT nickname, owner, released
+		FROM nickname_history
+		WHERE nickname=$1`,
+		nickname)
+
+	err := row.Scan(&redirect.Nickname, &redirect.Owner, &redirect.Released)
+	if err != nil {
+		return nil, err
+	}
+	return redirect, nil
+}
+
 func (ur *UserPgRepository) SelectByPostID(postID uint64) (*models.User, error) {
 	user := &models.User{}
 
@@ -113,26 +319,13 @@ func (ur *UserPgRepository) SelectByPostID(postID uint64) (*models.User, error)
 	return user, nil
 }
 
-func buildValuesQuery(valuesCount int) string {
-	/*
-		($1, $2, ...)
-	*/
-	var values []string
-	for i := 0; i < valuesCount; i++ {
-		value := i + 1
-		values = append(values, fmt.Sprintf("$%d", value))
-	}
-	valuesQuery := fmt.Sprintf("(%s)", strings.Join(values, ", "))
-	return valuesQuery
-}
-
 func (ur *UserPgRepository) SelectExistingUsersCount(nicknames []string) (int, error) {
 	var usersCount int
 	var values []interface{}
 
 	selectQuery := "SELECT COUNT(nickname) FROM users WHERE nickname IN"
 
-	filterQuery := buildValuesQuery(len(nicknames))
+	filterQuery := placeholders.List(1, len(nicknames))
 	for _, nickname := range nicknames {
 		values = append(values, nickname)
 	}
@@ -151,6 +344,33 @@ func (ur *UserPgRepository) SelectExistingUsersCount(nicknames []string) (int, e
 	return usersCount, nil
 }
 
+func (ur *UserPgRepository) SelectAllByNicknames(nicknames []string) ([]*models.User, error) {
+	rows, err := ur.dbConn.Query(
+		`SELECT nickname, fullname, email, about, version, updated, edit_version
+		FROM users
+		WHERE nickname = ANY($1::citext[])`,
+		pq.Array(nicknames))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user := &models.User{}
+		err := rows.Scan(&user.Nickname, &user.Fullname, &user.Email, &user.About, &user.Version, &user.Updated,
+			&user.EditVersion)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 func (ur *UserPgRepository) SelectAllByNicknameOrEmail(nickname string, email string) ([]*models.User, error) {
 	rows, err := ur.dbConn.Query(
 		`SELECT nickname, fullname, email, about
@@ -238,3 +458,73 @@ func (ur *UserPgRepository) SelectAllByForum(forumSlug string, since string, pgn
 	}
 	return users, nil
 }
+
+func (ur *UserPgRepository) SelectActivity(nickname string, since time.Time, pgnt *models.Pagination) ([]*models.Activity, error) {
+	var values []interface{}
+
+	// Posts and threads of user merged into one chronological sequence
+	selectQuery := `
+		SELECT type, id, title, message, forum, thread, created
+		FROM (
+			SELECT 'post' AS type, id, '' AS title, message, forum, thread, created
+			FROM posts
+			WHERE author=$1 AND status='approved'
+			UNION ALL
+			SELECT 'thread' AS type, id, title, message, forum, id AS thread, created
+			FROM threads
+			WHERE author=$1 AND status='approved'
+		) AS a`
+	values = append(values, nickname)
+
+	var sortQuery string
+	if pgnt.Desc {
+		sortQuery = "ORDER BY created DESC, type DESC, id DESC"
+	} else {
+		sortQuery = "ORDER BY created, type, id"
+	}
+
+	var pgntQuery string
+	if pgnt.Limit != 0 {
+		pgntQuery = "LIMIT $2"
+		values = append(values, pgnt.Limit)
+	}
+
+	var filterQuery string
+	if !since.IsZero() {
+		ind := len(values) + 1
+		if pgnt.Desc {
+			filterQuery = "WHERE created <= $" + strconv.Itoa(ind)
+		} else {
+			filterQuery = "WHERE created >= $" + strconv.Itoa(ind)
+		}
+		values = append(values, since)
+	}
+
+	resultQuery := strings.Join([]string{
+		selectQuery,
+		filterQuery,
+		sortQuery,
+		pgntQuery,
+	}, " ")
+
+	rows, err := ur.dbConn.Query(resultQuery, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var activities []*models.Activity
+	for rows.Next() {
+		activity := &models.Activity{}
+		err := rows.Scan(&activity.Type, &activity.ID, &activity.Title, &activity.Message,
+			&activity.Forum, &activity.Thread, &activity.Created)
+		if err != nil {
+			return nil, err
+		}
+		activities = append(activities, activity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return activities, nil
+}
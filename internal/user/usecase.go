@@ -1,6 +1,8 @@
 package user
 
 import (
+	"time"
+
 	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
 	"github.com/OlegGibadulin/tech-db-forum/internal/models"
 )
@@ -8,10 +10,16 @@ import (
 type UserUsecase interface {
 	Create(user *models.User) *errors.Error
 	Update(nickname string, newUserData *models.User) (*models.User, *errors.Error)
+	Rename(nickname string, newNickname string) (*models.User, *errors.Error)
+	Delete(nickname string) (*models.UserDeletion, *errors.Error)
 	GetByNickname(nickname string) (*models.User, *errors.Error)
 	GetByEmail(email string) (*models.User, *errors.Error)
+	GetProfile(nickname string) (*models.Profile, *errors.Error)
+	GetRedirect(nickname string) (*models.NicknameRedirect, *errors.Error)
 	GetByPostID(postID uint64) (*models.User, *errors.Error)
 	CheckUsersExistence(uniqNicknames []string) *errors.Error
+	ListByNicknames(nicknames []string) ([]*models.User, *errors.Error)
 	ListByNicknameOrEmail(nickname string, email string) ([]*models.User, *errors.Error)
 	ListByForum(forumSlug string, since string, pgnt *models.Pagination) ([]*models.User, *errors.Error)
+	ListActivity(nickname string, since time.Time, pgnt *models.Pagination) ([]*models.Activity, *errors.Error)
 }
@@ -2,7 +2,10 @@ package usecases
 
 import (
 	"database/sql"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
 	. "github.com/OlegGibadulin/tech-db-forum/internal/consts"
 	"github.com/OlegGibadulin/tech-db-forum/internal/helpers/errors"
@@ -10,17 +13,48 @@ import (
 	"github.com/OlegGibadulin/tech-db-forum/internal/user"
 )
 
+var nicknameRegexp = regexp.MustCompile(`^[A-Za-z0-9_.]+$`)
+
 type UserUsecase struct {
-	userRepo user.UserRepository
+	userRepo            user.UserRepository
+	nicknameGracePeriod time.Duration
 }
 
-func NewUserUsecase(repo user.UserRepository) user.UserUsecase {
+func NewUserUsecase(repo user.UserRepository, nicknameGracePeriod time.Duration) user.UserUsecase {
 	return &UserUsecase{
-		userRepo: repo,
+		userRepo:            repo,
+		nicknameGracePeriod: nicknameGracePeriod,
 	}
 }
 
+// Released nickname can't be taken by another user during grace period
+func (uu *UserUsecase) checkNicknameReservation(nickname string, owner string) *errors.Error {
+	if strings.EqualFold(nickname, models.GhostNickname) {
+		return errors.BuildByMsg(CodeNicknameIsForbidden, nickname)
+	}
+
+	redirect, customErr := uu.GetRedirect(nickname)
+	switch {
+	case customErr != nil && customErr.Code == CodeUserDoesNotExist:
+		return nil
+	case customErr != nil:
+		return customErr
+	case strings.EqualFold(redirect.Owner, owner):
+		return nil
+	}
+
+	reservedUntil := redirect.Released.Add(uu.nicknameGracePeriod)
+	if time.Now().Before(reservedUntil) {
+		return errors.BuildByMsg(CodeNicknameIsReserved, nickname, reservedUntil.Format(time.RFC3339))
+	}
+	return nil
+}
+
 func (uu *UserUsecase) Create(user *models.User) *errors.Error {
+	if customErr := uu.checkNicknameReservation(user.Nickname, ""); customErr != nil {
+		return customErr
+	}
+
 	users, customErr := uu.ListByNicknameOrEmail(user.Nickname, user.Email)
 	switch {
 	case customErr != nil:
@@ -58,13 +92,80 @@ func (uu *UserUsecase) Update(nickname string, newUserData *models.User) (*model
 	if newUserData.About != "" {
 		user.About = newUserData.About
 	}
+	if newUserData.EditVersion != 0 {
+		user.EditVersion = newUserData.EditVersion
+	}
 
-	if err := uu.userRepo.Update(user); err != nil {
+	err := uu.userRepo.Update(user)
+	switch {
+	case err == sql.ErrNoRows:
+		currentUser, customErr := uu.GetByNickname(user.Nickname)
+		if customErr != nil {
+			return nil, customErr
+		}
+		return nil, errors.BuildByBody(CodeVersionConflict, currentUser)
+	case err != nil && err.Error() == OnUserUpdateExceptionMsgEmailConflict:
+		// Email was taken by concurrent update after the check above
+		return nil, errors.BuildByMsg(CodeEmailAlreadyExists, user.Email)
+	case err != nil:
 		return nil, errors.New(CodeInternalError, err)
 	}
 	return user, nil
 }
 
+func (uu *UserUsecase) Rename(nickname string, newNickname string) (*models.User, *errors.Error) {
+	if !nicknameRegexp.MatchString(newNickname) {
+		return nil, errors.BuildByMsg(CodeWrongNickname, newNickname)
+	}
+
+	user, customErr := uu.GetByNickname(nickname)
+	if customErr != nil {
+		return nil, customErr
+	}
+
+	if !strings.EqualFold(user.Nickname, newNickname) {
+		anotherUser, customErr := uu.GetByNickname(newNickname)
+		if customErr == nil {
+			return nil, errors.BuildByBody(CodeUserAlreadyExists, anotherUser)
+		} else if customErr.Code == CodeInternalError {
+			return nil, customErr
+		}
+
+		if customErr := uu.checkNicknameReservation(newNickname, user.Nickname); customErr != nil {
+			return nil, customErr
+		}
+	}
+
+	err := uu.userRepo.Rename(user.Nickname, newNickname)
+	switch {
+	case err != nil && err.Error() == OnUserRenameExceptionMsgNicknameConflict:
+		// Nickname was taken by concurrent rename or creation after the check above
+		anotherUser, customErr := uu.GetByNickname(newNickname)
+		if customErr != nil {
+			return nil, customErr
+		}
+		return nil, errors.BuildByBody(CodeUserAlreadyExists, anotherUser)
+	case err != nil:
+		return nil, errors.New(CodeInternalError, err)
+	}
+	return uu.GetByNickname(newNickname)
+}
+
+func (uu *UserUsecase) Delete(nickname string) (*models.UserDeletion, *errors.Error) {
+	if strings.EqualFold(nickname, models.GhostNickname) {
+		return nil, errors.BuildByMsg(CodeNicknameIsForbidden, nickname)
+	}
+
+	deletion, err := uu.userRepo.Delete(nickname)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, errors.BuildByMsg(CodeUserDoesNotExist, "nickname", nickname)
+	case err != nil:
+		return nil, errors.New(CodeInternalError, err)
+	}
+	return deletion, nil
+}
+
 func (uu *UserUsecase) GetByNickname(nickname string) (*models.User, *errors.Error) {
 	user, err := uu.userRepo.SelectByNickname(nickname)
 	switch {
@@ -87,6 +188,28 @@ func (uu *UserUsecase) GetByEmail(email string) (*models.User, *errors.Error) {
 	return user, nil
 }
 
+func (uu *UserUsecase) GetProfile(nickname string) (*models.Profile, *errors.Error) {
+	profile, err := uu.userRepo.SelectProfileByNickname(nickname)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, errors.BuildByMsg(CodeUserDoesNotExist, "nickname", nickname)
+	case err != nil:
+		return nil, errors.New(CodeInternalError, err)
+	}
+	return profile, nil
+}
+
+func (uu *UserUsecase) GetRedirect(nickname string) (*models.NicknameRedirect, *errors.Error) {
+	redirect, err := uu.userRepo.SelectRedirectByNickname(nickname)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, errors.BuildByMsg(CodeUserDoesNotExist, "nickname", nickname)
+	case err != nil:
+		return nil, errors.New(CodeInternalError, err)
+	}
+	return redirect, nil
+}
+
 func (uu *UserUsecase) GetByPostID(postID uint64) (*models.User, *errors.Error) {
 	user, err := uu.userRepo.SelectByPostID(postID)
 	switch {
@@ -112,6 +235,20 @@ func (uu *UserUsecase) CheckUsersExistence(uniqNicknames []string) *errors.Error
 	return nil
 }
 
+func (uu *UserUsecase) ListByNicknames(nicknames []string) ([]*models.User, *errors.Error) {
+	if len(nicknames) == 0 {
+		return []*models.User{}, nil
+	}
+	users, err := uu.userRepo.SelectAllByNicknames(nicknames)
+	if err != nil {
+		return nil, errors.New(CodeInternalError, err)
+	}
+	if len(users) == 0 {
+		return []*models.User{}, nil
+	}
+	return users, nil
+}
+
 func (uu *UserUsecase) ListByNicknameOrEmail(nickname string, email string) ([]*models.User, *errors.Error) {
 	users, err := uu.userRepo.SelectAllByNicknameOrEmail(nickname, email)
 	if err != nil {
@@ -133,3 +270,14 @@ func (uu *UserUsecase) ListByForum(forumSlug string, since string, pgnt *models.
 	}
 	return users, nil
 }
+
+func (uu *UserUsecase) ListActivity(nickname string, since time.Time, pgnt *models.Pagination) ([]*models.Activity, *errors.Error) {
+	activities, err := uu.userRepo.SelectActivity(nickname, since, pgnt)
+	if err != nil {
+		return nil, errors.New(CodeInternalError, err)
+	}
+	if len(activities) == 0 {
+		return []*models.Activity{}, nil
+	}
+	return activities, nil
+}
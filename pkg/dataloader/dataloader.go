@@ -0,0 +1,138 @@
+package dataloader
+
+import (
+	"sync"
+	"time"
+)
+
+// BatchFunc loads values for all keys at once, missing keys are left out of result
+type BatchFunc func(keys []string) (map[string]interface{}, error)
+
+type result struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+type batch struct {
+	keys    []string
+	results []*result
+}
+
+// Loader collects keys requested concurrently during wait window and loads them
+// with one BatchFunc call. Loaded values are cached, so loader should live as long as one request
+type Loader struct {
+	batchFn  BatchFunc
+	wait     time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	cache   map[string]*result
+	pending *batch
+}
+
+func NewLoader(batchFn BatchFunc, wait time.Duration, maxBatch int) *Loader {
+	return &Loader{
+		batchFn:  batchFn,
+		wait:     wait,
+		maxBatch: maxBatch,
+		cache:    map[string]*result{},
+	}
+}
+
+// Load returns nil value without error if key was not found by BatchFunc
+func (l *Loader) Load(key string) (interface{}, error) {
+	res := l.enqueue(key)
+	<-res.done
+	return res.value, res.err
+}
+
+// LoadMany puts all keys to the same batch, values are returned in order of keys
+func (l *Loader) LoadMany(keys []string) ([]interface{}, error) {
+	results := make([]*result, 0, len(keys))
+	for _, key := range keys {
+		results = append(results, l.enqueue(key))
+	}
+
+	values := make([]interface{}, 0, len(keys))
+	for _, res := range results {
+		<-res.done
+		if res.err != nil {
+			return nil, res.err
+		}
+		values = append(values, res.value)
+	}
+	return values, nil
+}
+
+func (l *Loader) enqueue(key string) *result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if res, ok := l.cache[key]; ok {
+		return res
+	}
+
+	res := &result{
+		done: make(chan struct{}),
+	}
+	l.cache[key] = res
+
+	if l.pending == nil {
+		l.pending = &batch{}
+		go l.dispatchAfterWait(l.pending)
+	}
+	b := l.pending
+	b.keys = append(b.keys, key)
+	b.results = append(b.results, res)
+
+	if l.maxBatch > 0 && len(b.keys) >= l.maxBatch {
+		l.pending = nil
+		go l.dispatch(b)
+	}
+	return res
+}
+
+// Prime puts already loaded value to cache, so it won't be requested again
+func (l *Loader) Prime(key string, value interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.cache[key]; ok {
+		return
+	}
+	res := &result{
+		done:  make(chan struct{}),
+		value: value,
+	}
+	close(res.done)
+	l.cache[key] = res
+}
+
+func (l *Loader) dispatchAfterWait(b *batch) {
+	time.Sleep(l.wait)
+
+	l.mu.Lock()
+	// Batch could be already dispatched because of its size
+	if l.pending != b {
+		l.mu.Unlock()
+		return
+	}
+	l.pending = nil
+	l.mu.Unlock()
+
+	l.dispatch(b)
+}
+
+func (l *Loader) dispatch(b *batch) {
+	values, err := l.batchFn(b.keys)
+	for i, key := range b.keys {
+		res := b.results[i]
+		if err != nil {
+			res.err = err
+		} else {
+			res.value = values[key]
+		}
+		close(res.done)
+	}
+}
@@ -0,0 +1,31 @@
+package idempotency
+
+import (
+	"time"
+)
+
+// Record is request stored by idempotency key, it has no response while request is in progress
+type Record struct {
+	Key         string
+	Fingerprint string
+	Status      int
+	ContentType string
+	Body        []byte
+	Expires     time.Time
+}
+
+func (r *Record) IsCompleted() bool {
+	return r.Status != 0
+}
+
+type Store interface {
+	// Reserve stores new record unless there is unexpired record with the same key,
+	// it returns existing record and false in that case
+	Reserve(key, fingerprint string, expires time.Time) (*Record, bool, error)
+	// Complete saves response of reserved record
+	Complete(key string, status int, contentType string, body []byte) error
+	// Release removes reserved record so request can be retried
+	Release(key string) error
+	// Sweep removes expired records
+	Sweep(now time.Time) error
+}
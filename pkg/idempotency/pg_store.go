@@ -0,0 +1,82 @@
+package idempotency
+
+import (
+	"database/sql"
+	"time"
+)
+
+type PgStore struct {
+	dbConn *sql.DB
+}
+
+func NewPgStore(conn *sql.DB) Store {
+	return &PgStore{
+		dbConn: conn,
+	}
+}
+
+func (ps *PgStore) Reserve(key, fingerprint string, expires time.Time) (*Record, bool, error) {
+	// Expired record is replaced as if it did not exist
+	res, err := ps.dbConn.Exec(
+		`INSERT INTO idempotency_keys(key, fingerprint, expires)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE
+		SET fingerprint = EXCLUDED.fingerprint, status = NULL, content_type = NULL, body = NULL,
+			expires = EXCLUDED.expires
+		WHERE idempotency_keys.expires <= now()`,
+		key, fingerprint, expires)
+	if err != nil {
+		return nil, false, err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return nil, false, err
+	}
+	if count != 0 {
+		return nil, true, nil
+	}
+
+	record := &Record{}
+	var status sql.NullInt64
+	var contentType sql.NullString
+
+	row := ps.dbConn.QueryRow(
+		`SELECT key, fingerprint, status, content_type, body, expires
+		FROM idempotency_keys
+		WHERE key=$1`,
+		key)
+
+	err = row.Scan(&record.Key, &record.Fingerprint, &status, &contentType, &record.Body, &record.Expires)
+	if err != nil {
+		return nil, false, err
+	}
+	record.Status = int(status.Int64)
+	record.ContentType = contentType.String
+	return record, false, nil
+}
+
+func (ps *PgStore) Complete(key string, status int, contentType string, body []byte) error {
+	_, err := ps.dbConn.Exec(
+		`UPDATE idempotency_keys
+		SET status = $2, content_type = $3, body = $4
+		WHERE key = $1`,
+		key, status, contentType, body)
+	return err
+}
+
+func (ps *PgStore) Release(key string) error {
+	_, err := ps.dbConn.Exec(
+		`DELETE FROM idempotency_keys
+		WHERE key = $1 AND status IS NULL`,
+		key)
+	return err
+}
+
+func (ps *PgStore) Sweep(now time.Time) error {
+	_, err := ps.dbConn.Exec(
+		`DELETE FROM idempotency_keys
+		WHERE expires <= $1`,
+		now)
+	return err
+}
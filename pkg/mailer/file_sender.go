@@ -0,0 +1,38 @@
+package mailer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileSender stores emails as .eml files instead of sending them, it is useful for local testing
+type FileSender struct {
+	dir  string
+	from string
+}
+
+func NewFileSender(dir string, from string) Sender {
+	return &FileSender{
+		dir:  dir,
+		from: from,
+	}
+}
+
+func (fs *FileSender) Send(msg *Message) error {
+	data, err := Build(fs.from, msg)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(fs.dir, 0755); err != nil {
+		return err
+	}
+
+	recipient := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(msg.To)
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), recipient)
+	return ioutil.WriteFile(filepath.Join(fs.dir, name), data, 0644)
+}
@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"net"
+	"net/smtp"
+)
+
+type SMTPSender struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPSender(addr string, username string, password string, from string) Sender {
+	var auth smtp.Auth
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTPSender{
+		addr: addr,
+		auth: auth,
+		from: from,
+	}
+}
+
+func (ss *SMTPSender) Send(msg *Message) error {
+	data, err := Build(ss.from, msg)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(ss.addr, ss.auth, ss.from, []string{msg.To}, data)
+}
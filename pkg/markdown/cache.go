@@ -0,0 +1,78 @@
+package markdown
+
+import (
+	"container/list"
+	"sync"
+)
+
+type cacheEntry struct {
+	key    string
+	source string
+	html   string
+}
+
+// Cache keeps rendered HTML of recently used keys. Source is kept with HTML,
+// so HTML of key whose source was changed without invalidation is never returned
+type Cache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// NewCache creates cache for size keys, zero size disables caching
+func NewCache(size int) *Cache {
+	return &Cache{
+		size:    size,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+func (c *Cache) Get(key string, source string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if entry.source != source {
+		return "", false
+	}
+	c.order.MoveToFront(elem)
+	return entry.html, true
+}
+
+func (c *Cache) Set(key string, source string, html string) {
+	if c.size == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = &cacheEntry{key, source, html}
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key, source, html})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+	}
+}
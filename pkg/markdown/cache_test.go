@@ -0,0 +1,36 @@
+package markdown
+
+import "testing"
+
+func TestCache(t *testing.T) {
+	c := NewCache(2)
+	c.Set("a", "source a", "html a")
+	c.Set("b", "source b", "html b")
+
+	if html, ok := c.Get("a", "source a"); !ok || html != "html a" {
+		t.Errorf("get a: got %q %v, want %q", html, ok, "html a")
+	}
+	if _, ok := c.Get("a", "changed a"); ok {
+		t.Errorf("get a with changed source: cached HTML is returned")
+	}
+
+	// a was used last, so b is evicted
+	c.Set("c", "source c", "html c")
+	if _, ok := c.Get("b", "source b"); ok {
+		t.Errorf("get b: least recently used key isn't evicted")
+	}
+	if _, ok := c.Get("a", "source a"); !ok {
+		t.Errorf("get a: recently used key is evicted")
+	}
+
+	c.Delete("a")
+	if _, ok := c.Get("a", "source a"); ok {
+		t.Errorf("get a: deleted key is returned")
+	}
+
+	disabled := NewCache(0)
+	disabled.Set("a", "source a", "html a")
+	if _, ok := disabled.Get("a", "source a"); ok {
+		t.Errorf("cache of zero size keeps keys")
+	}
+}
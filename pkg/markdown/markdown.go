@@ -0,0 +1,198 @@
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+const (
+	MentionClass = "mention"
+	QuoteClass   = "post-quote"
+)
+
+var mentionRegexp = regexp.MustCompile(`^@([A-Za-z0-9_.]+)`)
+var quoteRegexp = regexp.MustCompile(`^>>([0-9]+)`)
+
+// Only language names are kept from classes of code, they are used by highlighters on client side
+var codeClassRegexp = regexp.MustCompile(`^language-[A-Za-z0-9_+#.-]+$`)
+var linkClassRegexp = regexp.MustCompile(`^(` + MentionClass + `|` + QuoteClass + `)$`)
+var alignRegexp = regexp.MustCompile(`^(left|center|right)$`)
+
+type Options struct {
+	// MentionURL builds link to profile of @nickname
+	MentionURL func(nickname string) string
+	// QuoteURL builds link to post quoted as >>postID
+	QuoteURL func(postID uint64) string
+}
+
+// Renderer converts CommonMark to HTML which is safe to embed into page,
+// raw HTML of message is dropped and output is sanitized once more after rendering
+type Renderer struct {
+	markdown goldmark.Markdown
+	policy   *bluemonday.Policy
+	cache    *Cache
+}
+
+func NewRenderer(opts Options, cacheSize int) *Renderer {
+	md := goldmark.New(
+		goldmark.WithExtensions(extension.Linkify, extension.Strikethrough,
+			extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute))),
+		goldmark.WithParserOptions(
+			parser.WithBlockParsers(util.Prioritized(&quoteLineParser{parser.NewParagraphParser()}, 799)),
+			parser.WithInlineParsers(
+				util.Prioritized(&mentionParser{opts.MentionURL}, 999),
+				util.Prioritized(&quoteParser{opts.QuoteURL}, 999),
+			),
+		),
+	)
+
+	return &Renderer{
+		markdown: md,
+		policy:   newPolicy(),
+		cache:    NewCache(cacheSize),
+	}
+}
+
+func newPolicy() *bluemonday.Policy {
+	policy := bluemonday.NewPolicy()
+	policy.AllowStandardURLs()
+	policy.RequireNoFollowOnLinks(true)
+	policy.AllowAttrs("href").OnElements("a")
+	policy.AllowAttrs("class").Matching(linkClassRegexp).OnElements("a")
+	policy.AllowAttrs("class").Matching(codeClassRegexp).OnElements("code")
+	policy.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+	policy.AllowAttrs("align").Matching(alignRegexp).OnElements("th", "td")
+	policy.AllowElements("p", "br", "hr", "em", "strong", "del", "code", "pre", "blockquote",
+		"ul", "ol", "li", "h1", "h2", "h3", "h4", "h5", "h6",
+		"table", "thead", "tbody", "tr", "th", "td")
+	return policy
+}
+
+func (r *Renderer) Render(source string) (string, error) {
+	var buf bytes.Buffer
+	if err := r.markdown.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(r.policy.Sanitize(buf.String())), nil
+}
+
+// RenderCached renders source once per key, cached HTML is used while source of key stays the same
+func (r *Renderer) RenderCached(key string, source string) (string, error) {
+	if html, ok := r.cache.Get(key, source); ok {
+		return html, nil
+	}
+
+	html, err := r.Render(source)
+	if err != nil {
+		return "", err
+	}
+	r.cache.Set(key, source, html)
+	return html, nil
+}
+
+// Invalidate drops cached HTML of key, it's called when source of key is changed
+func (r *Renderer) Invalidate(key string) {
+	r.cache.Delete(key)
+}
+
+// quoteLineParser keeps lines started with >>postID from being parsed as nested blockquotes,
+// such line starts paragraph, so quote link is found in it by inline parser
+type quoteLineParser struct {
+	parser.BlockParser
+}
+
+func (p *quoteLineParser) Trigger() []byte {
+	return []byte{'>'}
+}
+
+func (p *quoteLineParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, _ := reader.PeekLine()
+	if !quoteRegexp.Match(bytes.TrimLeft(line, " \t")) {
+		return nil, parser.NoChildren
+	}
+	return p.BlockParser.Open(parent, reader, pc)
+}
+
+func (p *quoteLineParser) CanInterruptParagraph() bool {
+	return true
+}
+
+// Mentions are found the same way as pkg/mention finds them for notifications
+type mentionParser struct {
+	url func(nickname string) string
+}
+
+func (p *mentionParser) Trigger() []byte {
+	return []byte{'@'}
+}
+
+func (p *mentionParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	if prev := block.PrecendingCharacter(); isWordCharacter(prev) || prev == '.' || prev == '@' {
+		return nil
+	}
+
+	line, segment := block.PeekLine()
+	match := mentionRegexp.FindSubmatch(line)
+	if match == nil {
+		return nil
+	}
+	// Dot at the end belongs to sentence, not to nickname
+	nickname := strings.TrimRight(string(match[1]), ".")
+	if nickname == "" {
+		return nil
+	}
+
+	length := len(nickname) + 1
+	block.Advance(length)
+	return newLink(p.url(nickname), MentionClass, segment.WithStop(segment.Start+length))
+}
+
+type quoteParser struct {
+	url func(postID uint64) string
+}
+
+func (p *quoteParser) Trigger() []byte {
+	return []byte{'>'}
+}
+
+func (p *quoteParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	if prev := block.PrecendingCharacter(); isWordCharacter(prev) || prev == '>' {
+		return nil
+	}
+
+	line, segment := block.PeekLine()
+	match := quoteRegexp.FindSubmatch(line)
+	if match == nil {
+		return nil
+	}
+	postID, err := strconv.ParseUint(string(match[1]), 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	length := len(match[0])
+	block.Advance(length)
+	return newLink(p.url(postID), QuoteClass, segment.WithStop(segment.Start+length))
+}
+
+func newLink(destination string, class string, segment text.Segment) ast.Node {
+	link := ast.NewLink()
+	link.Destination = []byte(destination)
+	link.SetAttributeString("class", []byte(class))
+	link.AppendChild(link, ast.NewTextSegment(segment))
+	return link
+}
+
+func isWordCharacter(r rune) bool {
+	return r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
@@ -0,0 +1,132 @@
+package markdown
+
+import (
+	"strconv"
+	"testing"
+)
+
+func newTestRenderer(cacheSize int) *Renderer {
+	return NewRenderer(Options{
+		MentionURL: func(nickname string) string {
+			return "/user/" + nickname
+		},
+		QuoteURL: func(postID uint64) string {
+			return "/post/" + strconv.FormatUint(postID, 10)
+		},
+	}, cacheSize)
+}
+
+func TestRenderSanitizes(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		html   string
+	}{
+		{"script", "<script>alert(1)</script>", ""},
+		{"inline html", "<b onclick=\"alert(1)\">bold</b>", "<p>bold</p>"},
+		{"javascript link", "[x](javascript:alert(1))", "<p>x</p>"},
+		{"javascript raw link", "<a href=\"javascript:alert(1)\">x</a>", "<p>x</p>"},
+		{"image", "![x](http://example.com/x.png)", "<p></p>"},
+		{"raw link with class", "<a class=\"mention\" href=\"http://example.com\">x</a>", "<p>x</p>"},
+		{"link", "[x](http://example.com)", `<p><a href="http://example.com" rel="nofollow">x</a></p>`},
+		{"code language", "```go\nx\n```", "<pre><code class=\"language-go\">x\n</code></pre>"},
+		{"code language with space", "```go evil\nx\n```", "<pre><code class=\"language-go\">x\n</code></pre>"},
+		{"table align", "| a |\n|:-:|\n| b |",
+			"<table>\n<thead>\n<tr>\n<th align=\"center\">a</th>\n</tr>\n</thead>\n" +
+				"<tbody>\n<tr>\n<td align=\"center\">b</td>\n</tr>\n</tbody>\n</table>"},
+	}
+
+	r := newTestRenderer(0)
+	for _, test := range tests {
+		html, err := r.Render(test.source)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if html != test.html {
+			t.Errorf("%s: got %q, want %q", test.name, html, test.html)
+		}
+	}
+}
+
+func TestRenderMentions(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		html   string
+	}{
+		{"mention", "hi @nick", `<p>hi <a href="/user/nick" class="mention" rel="nofollow">@nick</a></p>`},
+		{"dot ends sentence", "hi @nick.", `<p>hi <a href="/user/nick" class="mention" rel="nofollow">@nick</a>.</p>`},
+		{"dot inside nickname", "@nick.name here",
+			`<p><a href="/user/nick.name" class="mention" rel="nofollow">@nick.name</a> here</p>`},
+		{"only dot", "@.", "<p>@.</p>"},
+		{"email", "a@b", "<p>a@b</p>"},
+		{"linked email", "mail a@b.com", `<p>mail <a href="mailto:a@b.com" rel="nofollow">a@b.com</a></p>`},
+		{"double at", "@@nick", "<p>@@nick</p>"},
+		{"code", "`@nick`", "<p><code>@nick</code></p>"},
+	}
+
+	r := newTestRenderer(0)
+	for _, test := range tests {
+		html, err := r.Render(test.source)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if html != test.html {
+			t.Errorf("%s: got %q, want %q", test.name, html, test.html)
+		}
+	}
+}
+
+func TestRenderQuotes(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		html   string
+	}{
+		{"line start", ">>123 answer",
+			`<p><a href="/post/123" class="post-quote" rel="nofollow">&gt;&gt;123</a> answer</p>`},
+		{"after paragraph line", "text\n>>123 answer",
+			"<p>text</p>\n" + `<p><a href="/post/123" class="post-quote" rel="nofollow">&gt;&gt;123</a> answer</p>`},
+		{"inside blockquote", "> >>123 quoted",
+			"<blockquote>\n" +
+				`<p><a href="/post/123" class="post-quote" rel="nofollow">&gt;&gt;123</a> quoted</p>` +
+				"\n</blockquote>"},
+		{"nested blockquote", ">> quoted twice",
+			"<blockquote>\n<blockquote>\n<p>quoted twice</p>\n</blockquote>\n</blockquote>"},
+		{"inline", "see >>5", `<p>see <a href="/post/5" class="post-quote" rel="nofollow">&gt;&gt;5</a></p>`},
+		{"after word", "x>>5", "<p>x&gt;&gt;5</p>"},
+	}
+
+	r := newTestRenderer(0)
+	for _, test := range tests {
+		html, err := r.Render(test.source)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if html != test.html {
+			t.Errorf("%s: got %q, want %q", test.name, html, test.html)
+		}
+	}
+}
+
+func TestRenderCached(t *testing.T) {
+	r := newTestRenderer(10)
+	html, err := r.RenderCached("1", "*a*")
+	if err != nil || html != "<p><em>a</em></p>" {
+		t.Fatalf("first render: got %q %v", html, err)
+	}
+
+	// Edited post is rendered again even without invalidation
+	html, err = r.RenderCached("1", "**a**")
+	if err != nil || html != "<p><strong>a</strong></p>" {
+		t.Errorf("render of edited source: got %q %v", html, err)
+	}
+
+	r.Invalidate("1")
+	if _, ok := r.cache.Get("1", "**a**"); ok {
+		t.Errorf("invalidated key stays cached")
+	}
+}
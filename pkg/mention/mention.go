@@ -0,0 +1,23 @@
+package mention
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/OlegGibadulin/tech-db-forum/pkg/uniq"
+)
+
+var mentionRegexp = regexp.MustCompile(`(?:^|[^\w.@])@([A-Za-z0-9_.]+)`)
+
+// Parse returns unique nicknames mentioned in message as @nickname
+func Parse(message string) []string {
+	var nicknames []string
+	for _, match := range mentionRegexp.FindAllStringSubmatch(message, -1) {
+		// Dot at the end belongs to sentence, not to nickname
+		nickname := strings.TrimRight(match[1], ".")
+		if nickname != "" {
+			nicknames = append(nicknames, nickname)
+		}
+	}
+	return uniq.RemoveDuplicates(nicknames)
+}
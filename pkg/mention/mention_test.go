@@ -0,0 +1,28 @@
+package mention
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		message   string
+		nicknames []string
+	}{
+		{"hi @nick", []string{"nick"}},
+		{"hi @nick.", []string{"nick"}},
+		{"@nick.name here", []string{"nick.name"}},
+		{"@a and @b and @a", []string{"a", "b"}},
+		{"a@b", []string{}},
+		{"mail a@b.com", []string{}},
+		{"@@nick", []string{}},
+		{"@.", []string{}},
+	}
+
+	for _, test := range tests {
+		if got := Parse(test.message); !reflect.DeepEqual(got, test.nicknames) {
+			t.Errorf("%q: got %q, want %q", test.message, got, test.nicknames)
+		}
+	}
+}
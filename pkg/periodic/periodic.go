@@ -0,0 +1,20 @@
+package periodic
+
+import (
+	"time"
+)
+
+// Run calls job every interval, it blocks forever so should be run in goroutine.
+// Job with nonpositive interval, e.g. missing in config, is disabled
+func Run(interval time.Duration, job func()) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		job()
+	}
+}
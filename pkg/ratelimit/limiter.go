@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"time"
+)
+
+// Limiter applies route limits to clients, routes without own limit share one default bucket
+type Limiter struct {
+	store        Store
+	defaultLimit Limit
+	routeLimits  map[string]Limit
+	now          func() time.Time
+}
+
+func NewLimiter(store Store, defaultLimit Limit, routeLimits map[string]Limit) *Limiter {
+	return &Limiter{
+		store:        store,
+		defaultLimit: defaultLimit,
+		routeLimits:  routeLimits,
+		now:          time.Now,
+	}
+}
+
+// Allow takes token from bucket of every client key and returns the most restrictive result,
+// nil result means that route is not limited
+func (l *Limiter) Allow(route string, keys []string) (*Result, error) {
+	limit, ok := l.routeLimits[route]
+	if !ok {
+		route = "*"
+		limit = l.defaultLimit
+	}
+	if limit.IsZero() {
+		return nil, nil
+	}
+
+	now := l.now()
+	var result *Result
+	for _, key := range keys {
+		res, err := l.store.Take(route+" "+key, limit, now)
+		if err != nil {
+			return nil, err
+		}
+		if result == nil || isMoreRestrictive(res, result) {
+			result = res
+		}
+	}
+	return result, nil
+}
+
+func isMoreRestrictive(res, another *Result) bool {
+	if res.Allowed != another.Allowed {
+		return !res.Allowed
+	}
+	if !res.Allowed {
+		return res.RetryAfter > another.RetryAfter
+	}
+	return res.Remaining < another.Remaining
+}
+
+func (l *Limiter) Sweep(before time.Time) error {
+	return l.store.Sweep(before)
+}
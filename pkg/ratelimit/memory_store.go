@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// MemoryStore keeps buckets in process memory, so every instance limits clients on its own
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+func NewMemoryStore() Store {
+	return &MemoryStore{
+		buckets: map[string]*bucket{},
+	}
+}
+
+func (ms *MemoryStore) Take(key string, limit Limit, now time.Time) (*Result, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	b, ok := ms.buckets[key]
+	if !ok {
+		b = &bucket{
+			tokens:  float64(limit.Burst),
+			updated: now,
+		}
+		ms.buckets[key] = b
+	}
+
+	tokens, res := take(b.tokens, b.updated, limit, now)
+	b.tokens = tokens
+	b.updated = now
+	return res, nil
+}
+
+func (ms *MemoryStore) Sweep(before time.Time) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for key, b := range ms.buckets {
+		if b.updated.Before(before) {
+			delete(ms.buckets, key)
+		}
+	}
+	return nil
+}
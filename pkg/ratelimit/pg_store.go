@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// PgStore keeps buckets in rate_limits table, so they are shared between instances
+type PgStore struct {
+	dbConn *sql.DB
+}
+
+func NewPgStore(conn *sql.DB) Store {
+	return &PgStore{
+		dbConn: conn,
+	}
+}
+
+func (ps *PgStore) Take(key string, limit Limit, now time.Time) (*Result, error) {
+	tx, err := ps.dbConn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO rate_limits(key, tokens, updated)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO NOTHING`,
+		key, limit.Burst, now)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	var tokens float64
+	var updated time.Time
+
+	// Row lock serializes concurrent requests of the same client
+	row := tx.QueryRow(
+		`SELECT tokens, updated
+		FROM rate_limits
+		WHERE key=$1
+		FOR UPDATE`,
+		key)
+	if err := row.Scan(&tokens, &updated); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	tokens, res := take(tokens, updated, limit, now)
+
+	_, err = tx.Exec(
+		`UPDATE rate_limits
+		SET tokens = $2, updated = $3
+		WHERE key = $1`,
+		key, tokens, now)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (ps *PgStore) Sweep(before time.Time) error {
+	_, err := ps.dbConn.Exec(
+		`DELETE FROM rate_limits
+		WHERE updated < $1`,
+		before)
+	return err
+}
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// Limit describes token bucket: it holds up to Burst tokens and refills Rate tokens per second
+type Limit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// IsZero reports whether limit does not restrict anything
+func (l Limit) IsZero() bool {
+	return l.Burst == 0
+}
+
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+type Store interface {
+	// Take removes one token from bucket stored by key
+	Take(key string, limit Limit, now time.Time) (*Result, error)
+	// Sweep removes buckets which were not used since before
+	Sweep(before time.Time) error
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
+
+// take refills bucket with tokens left at updated time and tries to remove one token from it
+func take(tokens float64, updated time.Time, limit Limit, now time.Time) (float64, *Result) {
+	if elapsed := now.Sub(updated).Seconds(); elapsed > 0 {
+		tokens = math.Min(float64(limit.Burst), tokens+elapsed*limit.Rate)
+	}
+
+	res := &Result{
+		Limit: limit.Burst,
+	}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else if limit.Rate > 0 {
+		res.RetryAfter = secondsToDuration((1 - tokens) / limit.Rate)
+	}
+
+	res.Remaining = int(math.Floor(tokens))
+	if limit.Rate > 0 {
+		res.Reset = secondsToDuration((float64(limit.Burst) - tokens) / limit.Rate)
+	}
+	return tokens, res
+}
@@ -0,0 +1,169 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is moved forward by tests instead of sleeping
+type fakeClock struct {
+	now time.Time
+}
+
+func (fc *fakeClock) Now() time.Time {
+	return fc.now
+}
+
+func (fc *fakeClock) Advance(d time.Duration) {
+	fc.now = fc.now.Add(d)
+}
+
+func newTestLimiter(store Store, limit Limit, routeLimits map[string]Limit) (*Limiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)}
+	l := NewLimiter(store, limit, routeLimits)
+	l.now = clock.Now
+	return l, clock
+}
+
+func mustAllow(t *testing.T, l *Limiter, route string, keys ...string) *Result {
+	t.Helper()
+	res, err := l.Allow(route, keys)
+	if err != nil {
+		t.Fatalf("allow: %s", err)
+	}
+	if res == nil {
+		t.Fatalf("allow: route %q isn't limited", route)
+	}
+	return res
+}
+
+func TestLimiterBurst(t *testing.T) {
+	l, _ := newTestLimiter(NewMemoryStore(), Limit{Rate: 1, Burst: 3}, nil)
+
+	for i := 2; i >= 0; i-- {
+		res := mustAllow(t, l, "GET /", "ip:1")
+		if !res.Allowed || res.Remaining != i || res.Limit != 3 {
+			t.Errorf("request %d: got allowed %v remaining %d limit %d, want true %d 3",
+				3-i, res.Allowed, res.Remaining, res.Limit, i)
+		}
+	}
+
+	res := mustAllow(t, l, "GET /", "ip:1")
+	if res.Allowed {
+		t.Errorf("request over burst is allowed")
+	}
+	if res.RetryAfter != time.Second {
+		t.Errorf("retry after: got %s, want %s", res.RetryAfter, time.Second)
+	}
+	if res.Reset != 3*time.Second {
+		t.Errorf("reset: got %s, want %s", res.Reset, 3*time.Second)
+	}
+
+	// Other client has own bucket
+	if res := mustAllow(t, l, "GET /", "ip:2"); !res.Allowed {
+		t.Errorf("request of other client isn't allowed")
+	}
+}
+
+func TestLimiterRefill(t *testing.T) {
+	l, clock := newTestLimiter(NewMemoryStore(), Limit{Rate: 2, Burst: 2}, nil)
+
+	mustAllow(t, l, "GET /", "ip:1")
+	mustAllow(t, l, "GET /", "ip:1")
+
+	clock.Advance(100 * time.Millisecond)
+	res := mustAllow(t, l, "GET /", "ip:1")
+	if res.Allowed {
+		t.Fatalf("request before refill is allowed")
+	}
+	// 0.2 token is refilled, 0.8 token takes 400ms more
+	if res.RetryAfter != 400*time.Millisecond {
+		t.Errorf("retry after: got %s, want %s", res.RetryAfter, 400*time.Millisecond)
+	}
+
+	clock.Advance(res.RetryAfter)
+	if res := mustAllow(t, l, "GET /", "ip:1"); !res.Allowed {
+		t.Errorf("request after retry after isn't allowed")
+	}
+
+	// Bucket never holds more than burst
+	clock.Advance(time.Hour)
+	res = mustAllow(t, l, "GET /", "ip:1")
+	if !res.Allowed || res.Remaining != 1 {
+		t.Errorf("request after long pause: got allowed %v remaining %d, want true 1", res.Allowed, res.Remaining)
+	}
+}
+
+func TestLimiterZeroRate(t *testing.T) {
+	l, clock := newTestLimiter(NewMemoryStore(), Limit{Rate: 0, Burst: 1}, nil)
+
+	mustAllow(t, l, "GET /", "ip:1")
+	clock.Advance(time.Hour)
+	res := mustAllow(t, l, "GET /", "ip:1")
+	if res.Allowed || res.RetryAfter != 0 {
+		t.Errorf("got allowed %v retry after %s, want false 0s", res.Allowed, res.RetryAfter)
+	}
+}
+
+func TestLimiterRoutes(t *testing.T) {
+	l, _ := newTestLimiter(NewMemoryStore(), Limit{Rate: 1, Burst: 5}, map[string]Limit{
+		"POST /post":     {Rate: 1, Burst: 1},
+		"GET /unlimited": {},
+	})
+
+	if res, err := l.Allow("GET /unlimited", []string{"ip:1"}); err != nil || res != nil {
+		t.Errorf("unlimited route: got %v %v, want nil result", res, err)
+	}
+
+	// Routes without own limit share default bucket
+	mustAllow(t, l, "GET /a", "ip:1")
+	if res := mustAllow(t, l, "GET /b", "ip:1"); res.Remaining != 3 {
+		t.Errorf("default bucket: got remaining %d, want 3", res.Remaining)
+	}
+
+	mustAllow(t, l, "POST /post", "ip:1")
+	if res := mustAllow(t, l, "POST /post", "ip:1"); res.Allowed {
+		t.Errorf("route limit isn't applied")
+	}
+}
+
+func TestLimiterReturnsMostRestrictiveKey(t *testing.T) {
+	l, clock := newTestLimiter(NewMemoryStore(), Limit{Rate: 1, Burst: 2}, nil)
+
+	// User spends bucket from another IP
+	mustAllow(t, l, "GET /", "ip:2", "user:nick")
+	mustAllow(t, l, "GET /", "ip:2", "user:nick")
+
+	clock.Advance(500 * time.Millisecond)
+	res := mustAllow(t, l, "GET /", "ip:1", "user:nick")
+	if res.Allowed {
+		t.Errorf("request of user with empty bucket is allowed")
+	}
+	if res.RetryAfter != 500*time.Millisecond {
+		t.Errorf("retry after: got %s, want %s", res.RetryAfter, 500*time.Millisecond)
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	store := NewMemoryStore().(*MemoryStore)
+	l, clock := newTestLimiter(store, Limit{Rate: 1, Burst: 1}, nil)
+
+	mustAllow(t, l, "GET /", "ip:1")
+	clock.Advance(time.Minute)
+	mustAllow(t, l, "GET /", "ip:2")
+
+	if err := l.Sweep(clock.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("sweep: %s", err)
+	}
+	if _, has := store.buckets["* ip:1"]; has {
+		t.Errorf("unused bucket isn't swept")
+	}
+	if _, has := store.buckets["* ip:2"]; !has {
+		t.Errorf("recently used bucket is swept")
+	}
+
+	// Swept client starts with full bucket
+	if res := mustAllow(t, l, "GET /", "ip:1"); !res.Allowed || res.Remaining != 0 {
+		t.Errorf("swept client: got allowed %v remaining %d, want true 0", res.Allowed, res.Remaining)
+	}
+}
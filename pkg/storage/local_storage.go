@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStorage keeps objects as files in directory, keys become relative paths inside it
+type LocalStorage struct {
+	dir string
+}
+
+func NewLocalStorage(dir string) Storage {
+	return &LocalStorage{
+		dir: dir,
+	}
+}
+
+func (ls *LocalStorage) path(key string) (string, error) {
+	cleanKey := path.Clean("/" + key)
+	if cleanKey == "/" || strings.TrimPrefix(cleanKey, "/") != key {
+		return "", fmt.Errorf("storage: wrong key %q", key)
+	}
+	return filepath.Join(ls.dir, filepath.FromSlash(key)), nil
+}
+
+func (ls *LocalStorage) Put(key string, body io.Reader, size int64, contentType string) error {
+	filePath, err := ls.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+
+	// Object appears under its key only when it is written completely
+	file, err := ioutil.TempFile(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	written, err := io.Copy(file, body)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if written != size {
+		return fmt.Errorf("storage: %d bytes of %d were written", written, size)
+	}
+	return os.Rename(file.Name(), filePath)
+}
+
+func (ls *LocalStorage) Get(key string) (io.ReadCloser, error) {
+	filePath, err := ls.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+func (ls *LocalStorage) Delete(key string) error {
+	filePath, err := ls.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
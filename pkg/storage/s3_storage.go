@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	amzDateFormat   = "20060102T150405Z"
+	amzShortFormat  = "20060102"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// S3Storage keeps objects in bucket of S3 compatible service. Requests are signed with
+// AWS Signature Version 4 and use path-style addressing, which is supported by local stand-ins
+type S3Storage struct {
+	endpoint  string
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+// NewS3Storage creates storage for bucket, endpoint is base URL of service,
+// like https://s3.eu-central-1.amazonaws.com or http://localhost:9000 for local MinIO
+func NewS3Storage(endpoint string, region string, bucket string, accessKey string, secretKey string) Storage {
+	return &S3Storage{
+		endpoint:  strings.TrimSuffix(endpoint, "/"),
+		region:    region,
+		bucket:    bucket,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		client:    &http.Client{},
+	}
+}
+
+func (ss *S3Storage) Put(key string, body io.Reader, size int64, contentType string) error {
+	req, err := ss.newRequest(http.MethodPut, key, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := ss.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (ss *S3Storage) Get(key string) (io.ReadCloser, error) {
+	req, err := ss.newRequest(http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := ss.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (ss *S3Storage) Delete(key string) error {
+	req, err := ss.newRequest(http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	// Deletion of missing object succeeds in S3, so it's not checked
+	resp, err := ss.do(req)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+	return nil
+}
+
+func (ss *S3Storage) newRequest(method string, key string, body io.Reader) (*http.Request, error) {
+	objectPath := "/" + ss.bucket + "/" + key
+	req, err := http.NewRequest(method, ss.endpoint+escapePath(objectPath), body)
+	if err != nil {
+		return nil, err
+	}
+	ss.sign(req, time.Now().UTC())
+	return req, nil
+}
+
+func (ss *S3Storage) do(req *http.Request) (*http.Response, error) {
+	resp, err := ss.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	case resp.StatusCode >= 300:
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("storage: %s %s: %s %s", req.Method, req.URL.Path, resp.Status, message)
+	}
+	return resp, nil
+}
+
+// sign adds Authorization header of AWS Signature Version 4, payload isn't hashed,
+// so uploaded files are streamed without reading them twice
+func (ss *S3Storage) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format(amzDateFormat)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": unsignedPayload,
+		"x-amz-date":           amzDate,
+	}
+	req.Header.Set("Authorization",
+		ss.authorization(req.Method, req.URL.EscapedPath(), req.URL.RawQuery, headers, unsignedPayload, now))
+}
+
+// authorization computes value of Authorization header for request with given lowercase headers,
+// all of them are signed
+func (ss *S3Storage) authorization(method string, path string, query string,
+	headers map[string]string, payloadHash string, now time.Time) string {
+	scope := strings.Join([]string{now.Format(amzShortFormat), ss.region, "s3", "aws4_request"}, "/")
+
+	var names []string
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		path,
+		query,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		now.Format(amzDateFormat),
+		scope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := []byte("AWS4" + ss.secretKey)
+	for _, part := range []string{now.Format(amzShortFormat), ss.region, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	return fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		ss.accessKey, scope, signedHeaders, signature)
+}
+
+// escapePath encodes every segment of path like S3 expects in canonical request
+func escapePath(objectPath string) string {
+	segments := strings.Split(objectPath, "/")
+	for ind, segment := range segments {
+		segments[ind] = strings.Replace(url.PathEscape(segment), "+", "%2B", -1)
+	}
+	return strings.Join(segments, "/")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
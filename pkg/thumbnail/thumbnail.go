@@ -0,0 +1,90 @@
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"golang.org/x/image/draw"
+)
+
+// Images with more pixels or longer side aren't decoded, so small file can't take a lot of memory.
+// Side is limited separately, so degenerate strips like 1×40M are rejected too
+const (
+	maxPixels    = 40 * 1000 * 1000
+	maxDimension = 16 * 1000
+)
+
+var ErrTooLarge = errors.New("thumbnail: image is too large")
+var ErrUnsupported = errors.New("thumbnail: image format is not supported")
+
+// Make scales image down to fit into square of maxSize pixels keeping its proportions,
+// smaller images are only re-encoded. JPEG stays JPEG, PNG and GIF become PNG
+func Make(r io.Reader, maxSize int) ([]byte, string, error) {
+	var buf bytes.Buffer
+	config, format, err := image.DecodeConfig(io.TeeReader(r, &buf))
+	if err == image.ErrFormat {
+		return nil, "", ErrUnsupported
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if config.Width > maxDimension || config.Height > maxDimension ||
+		config.Width*config.Height > maxPixels {
+		return nil, "", ErrTooLarge
+	}
+
+	src, err := decode(io.MultiReader(&buf, r), format)
+	if err != nil {
+		return nil, "", err
+	}
+
+	width, height := fit(config.Width, config.Height, maxSize)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Over, nil)
+
+	var out bytes.Buffer
+	if format == "jpeg" {
+		if err := jpeg.Encode(&out, dst, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, "", err
+		}
+		return out.Bytes(), "image/jpeg", nil
+	}
+	if err := png.Encode(&out, dst); err != nil {
+		return nil, "", err
+	}
+	return out.Bytes(), "image/png", nil
+}
+
+func decode(r io.Reader, format string) (image.Image, error) {
+	switch format {
+	case "jpeg":
+		return jpeg.Decode(r)
+	case "png":
+		return png.Decode(r)
+	case "gif":
+		return gif.Decode(r)
+	}
+	return nil, ErrUnsupported
+}
+
+func fit(width int, height int, maxSize int) (int, int) {
+	if width <= maxSize && height <= maxSize {
+		return width, height
+	}
+	if width >= height {
+		return maxSize, max(1, height*maxSize/width)
+	}
+	return max(1, width*maxSize/height), maxSize
+}
+
+func max(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -1,7 +1,11 @@
 CREATE EXTENSION IF NOT EXISTS citext;
 
 DROP TABLE IF EXISTS
-    users, forums, forum_user, threads, posts, votes
+    users, forums, forum_user, threads, posts, votes, post_votes,
+    user_follows, thread_follows, forum_follows, notifications, digest_settings,
+    nickname_history, user_deletions, sanctions, moderation_rules, post_reports,
+    attachments, orphaned_files, polls, poll_options, poll_ballots, drafts,
+    rate_limits, idempotency_keys, import_ids, import_checkpoints
     CASCADE;
 
 
@@ -10,24 +14,49 @@ CREATE TABLE IF NOT EXISTS users (
     fullname varchar(32) NOT NULL,
     email citext UNIQUE NOT NULL,
     about varchar NOT NULL,
+    version integer NOT NULL DEFAULT 1, -- upd_version
+    updated timestamp with time zone NOT NULL DEFAULT now(),
+    edit_version integer NOT NULL DEFAULT 1, -- upd_edit_version
     PRIMARY KEY(nickname, email)
 );
 CREATE INDEX IF NOT EXISTS users_nickname ON users (nickname);
 CREATE INDEX IF NOT EXISTS users_cover ON users (nickname, email, fullname, about);
 
 
+-- Released nicknames redirect to the current one of the same user
+CREATE TABLE IF NOT EXISTS nickname_history (
+    nickname citext PRIMARY KEY,
+    owner citext NOT NULL REFERENCES users(nickname) ON DELETE CASCADE ON UPDATE CASCADE,
+    released timestamp with time zone NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS nickname_history_owner ON nickname_history (owner);
+
+
+-- Audit log of deleted users, their content was reassigned to ghost user
+CREATE TABLE IF NOT EXISTS user_deletions (
+    id serial PRIMARY KEY,
+    nickname citext NOT NULL,
+    threads integer NOT NULL,
+    posts integer NOT NULL,
+    votes integer NOT NULL,
+    deleted timestamp with time zone NOT NULL DEFAULT now()
+);
+
+
 CREATE TABLE IF NOT EXISTS forums (
     title varchar NOT NULL,
-    author citext NOT NULL REFERENCES users(nickname) ON DELETE CASCADE, -- ins_author
+    author citext NOT NULL REFERENCES users(nickname) ON DELETE CASCADE ON UPDATE CASCADE, -- ins_author
     slug citext UNIQUE NOT NULL PRIMARY KEY,
     posts integer NOT NULL DEFAULT 0 CONSTRAINT positive_posts CHECK (posts >= 0), -- inc_posts
-    threads integer NOT NULL DEFAULT 0 CONSTRAINT positive_threads CHECK (threads >= 0) -- inc_threads
+    threads integer NOT NULL DEFAULT 0 CONSTRAINT positive_threads CHECK (threads >= 0), -- inc_threads
+    version integer NOT NULL DEFAULT 1, -- upd_version
+    updated timestamp with time zone NOT NULL DEFAULT now()
 );
 CREATE INDEX IF NOT EXISTS forums_author ON forums (author);
 
 
 CREATE TABLE IF NOT EXISTS forum_user (
-    nickname citext NOT NULL REFERENCES users(nickname) ON DELETE CASCADE,
+    nickname citext NOT NULL REFERENCES users(nickname) ON DELETE CASCADE ON UPDATE CASCADE,
     forum citext NOT NULL REFERENCES forums(slug) ON DELETE CASCADE,
     UNIQUE(nickname, forum)
 );
@@ -38,12 +67,18 @@ CREATE INDEX IF NOT EXISTS forum_user_forum ON forum_user (forum);
 CREATE TABLE IF NOT EXISTS threads (
     id serial PRIMARY KEY,
     title varchar NOT NULL,
-    author citext NOT NULL REFERENCES users(nickname) ON DELETE CASCADE,
+    author citext NOT NULL REFERENCES users(nickname) ON DELETE CASCADE ON UPDATE CASCADE,
     message varchar NOT NULL,
     created timestamp with time zone NOT NULL DEFAULT now(),
     forum citext NOT NULL REFERENCES forums(slug) ON DELETE CASCADE,
     votes integer NOT NULL DEFAULT 0,
-    slug citext NOT NULL
+    slug citext NOT NULL,
+    status varchar NOT NULL DEFAULT 'approved',
+    version integer NOT NULL DEFAULT 1, -- upd_thread_version
+    updated timestamp with time zone NOT NULL DEFAULT now(),
+    posts_version integer NOT NULL DEFAULT 1, -- upd_posts_version
+    posts_updated timestamp with time zone NOT NULL DEFAULT now(),
+    edit_version integer NOT NULL DEFAULT 1 -- upd_edit_version
 );
 CREATE INDEX IF NOT EXISTS threads_slug ON threads (slug);
 CREATE INDEX IF NOT EXISTS threads_forum ON threads (forum);
@@ -51,17 +86,24 @@ CREATE INDEX IF NOT EXISTS threads_author ON threads (author);
 CREATE INDEX IF NOT EXISTS threads_forum_created ON threads (forum, created);
 CREATE INDEX IF NOT EXISTS threads_created ON threads (created);
 CREATE INDEX IF NOT EXISTS threads_cover ON threads (id, title, author, message, created, forum, votes, slug);
+CREATE INDEX IF NOT EXISTS threads_forum_pending ON threads (forum, id) WHERE status = 'pending';
 
 
 CREATE TABLE IF NOT EXISTS posts (
     id serial PRIMARY KEY,
     parent integer NOT NULL,
-    author citext NOT NULL REFERENCES users(nickname) ON DELETE CASCADE,
+    author citext NOT NULL REFERENCES users(nickname) ON DELETE CASCADE ON UPDATE CASCADE,
     message varchar NOT NULL,
     isedited boolean DEFAULT FALSE,
     forum citext NOT NULL REFERENCES forums(slug) ON DELETE CASCADE,
     thread integer NOT NULL REFERENCES threads(id) ON DELETE CASCADE,
     created timestamp with time zone NOT NULL DEFAULT now(),
+    score integer NOT NULL DEFAULT 0,
+    status varchar NOT NULL DEFAULT 'approved',
+    version integer NOT NULL DEFAULT 1, -- upd_version
+    updated timestamp with time zone NOT NULL DEFAULT now(),
+    edit_version integer NOT NULL DEFAULT 1, -- upd_edit_version
+    published timestamp with time zone, -- upd_published, NULL means that post was visible since created
     path INTEGER[] NOT NULL
 );
 CREATE INDEX IF NOT EXISTS posts_thread_id ON posts (thread, id);
@@ -71,10 +113,12 @@ CREATE INDEX IF NOT EXISTS posts_thread_created_id ON posts (thread, created, id
 CREATE INDEX IF NOT EXISTS posts_thread_path ON posts (thread, path);
 CREATE INDEX IF NOT EXISTS posts_thread_parent_path ON posts (thread, parent, path);
 CREATE INDEX IF NOT EXISTS posts_thread_path_path ON posts ((path[1]), path);
+CREATE INDEX IF NOT EXISTS posts_thread_score_id ON posts (thread, score, id);
+CREATE INDEX IF NOT EXISTS posts_forum_pending ON posts (forum, id) WHERE status = 'pending';
 
 
 CREATE TABLE IF NOT EXISTS votes (
-    nickname citext NOT NULL REFERENCES users(nickname) ON DELETE CASCADE,
+    nickname citext NOT NULL REFERENCES users(nickname) ON DELETE CASCADE ON UPDATE CASCADE,
     thread integer NOT NULL REFERENCES threads(id) ON DELETE CASCADE,
     voice integer NOT NULL,
     UNIQUE(nickname, thread)
@@ -83,20 +127,267 @@ CREATE INDEX IF NOT EXISTS votes_nickname ON votes (nickname);
 CREATE INDEX IF NOT EXISTS votes_thread ON votes (thread);
 
 
+CREATE TABLE IF NOT EXISTS post_votes (
+    nickname citext NOT NULL REFERENCES users(nickname) ON DELETE CASCADE ON UPDATE CASCADE,
+    post integer NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    voice integer NOT NULL,
+    UNIQUE(nickname, post)
+);
+CREATE INDEX IF NOT EXISTS post_votes_nickname ON post_votes (nickname);
+CREATE INDEX IF NOT EXISTS post_votes_post ON post_votes (post);
+
+
+CREATE TABLE IF NOT EXISTS user_follows (
+    follower citext NOT NULL REFERENCES users(nickname) ON DELETE CASCADE ON UPDATE CASCADE,
+    followee citext NOT NULL REFERENCES users(nickname) ON DELETE CASCADE ON UPDATE CASCADE,
+    UNIQUE(follower, followee)
+);
+CREATE INDEX IF NOT EXISTS user_follows_followee ON user_follows (followee);
+
+
+CREATE TABLE IF NOT EXISTS thread_follows (
+    nickname citext NOT NULL REFERENCES users(nickname) ON DELETE CASCADE ON UPDATE CASCADE,
+    thread integer NOT NULL REFERENCES threads(id) ON DELETE CASCADE,
+    UNIQUE(nickname, thread)
+);
+CREATE INDEX IF NOT EXISTS thread_follows_thread ON thread_follows (thread);
+
+
+CREATE TABLE IF NOT EXISTS forum_follows (
+    nickname citext NOT NULL REFERENCES users(nickname) ON DELETE CASCADE ON UPDATE CASCADE,
+    forum citext NOT NULL REFERENCES forums(slug) ON DELETE CASCADE,
+    UNIQUE(nickname, forum)
+);
+CREATE INDEX IF NOT EXISTS forum_follows_forum ON forum_follows (forum);
+
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id serial PRIMARY KEY,
+    nickname citext NOT NULL REFERENCES users(nickname) ON DELETE CASCADE ON UPDATE CASCADE,
+    type varchar NOT NULL,
+    author citext NOT NULL REFERENCES users(nickname) ON DELETE CASCADE ON UPDATE CASCADE,
+    post integer NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    thread integer NOT NULL REFERENCES threads(id) ON DELETE CASCADE,
+    isread boolean NOT NULL DEFAULT FALSE,
+    created timestamp with time zone NOT NULL DEFAULT now(),
+    UNIQUE(nickname, post)
+);
+CREATE INDEX IF NOT EXISTS notifications_nickname_id ON notifications (nickname, id);
+CREATE INDEX IF NOT EXISTS notifications_nickname_unread ON notifications (nickname) WHERE NOT isread;
+
+
+CREATE TABLE IF NOT EXISTS digest_settings (
+    nickname citext PRIMARY KEY REFERENCES users(nickname) ON DELETE CASCADE ON UPDATE CASCADE,
+    frequency varchar NOT NULL DEFAULT 'never',
+    last_sent timestamp with time zone NOT NULL DEFAULT now(),
+    -- Last post sent in digest, next digest continues after it, NULL means from last_sent
+    cursor_published timestamp with time zone,
+    cursor_post integer
+);
+CREATE INDEX IF NOT EXISTS digest_settings_frequency_last_sent ON digest_settings (frequency, last_sent);
+CREATE INDEX IF NOT EXISTS posts_published_id ON posts ((COALESCE(published, created)), id);
+
+
+-- Sanction without forum is a global ban, otherwise it is a mute in forum
+CREATE TABLE IF NOT EXISTS sanctions (
+    id serial PRIMARY KEY,
+    nickname citext NOT NULL REFERENCES users(nickname) ON DELETE CASCADE ON UPDATE CASCADE,
+    forum citext REFERENCES forums(slug) ON DELETE CASCADE,
+    reason varchar NOT NULL,
+    created timestamp with time zone NOT NULL DEFAULT now(),
+    expires timestamp with time zone,
+    lifted timestamp with time zone
+);
+CREATE INDEX IF NOT EXISTS sanctions_nickname_active ON sanctions (nickname) WHERE lifted IS NULL;
+CREATE INDEX IF NOT EXISTS sanctions_expires_active ON sanctions (expires) WHERE lifted IS NULL;
+
+
+CREATE TABLE IF NOT EXISTS moderation_rules (
+    forum citext PRIMARY KEY REFERENCES forums(slug) ON DELETE CASCADE,
+    banned_words varchar[] NOT NULL DEFAULT '{}',
+    patterns varchar[] NOT NULL DEFAULT '{}',
+    max_links integer NOT NULL DEFAULT 0,
+    premoderated integer NOT NULL DEFAULT 0
+);
+
+
+CREATE TABLE IF NOT EXISTS post_reports (
+    id serial PRIMARY KEY,
+    post integer NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    nickname citext NOT NULL REFERENCES users(nickname) ON DELETE CASCADE ON UPDATE CASCADE,
+    reason varchar NOT NULL,
+    status varchar NOT NULL DEFAULT 'open',
+    created timestamp with time zone NOT NULL DEFAULT now(),
+    UNIQUE(post, nickname)
+);
+CREATE INDEX IF NOT EXISTS post_reports_open_post ON post_reports (post) WHERE status = 'open';
+
+
+-- Files themselves are kept by storage backend, see pkg/storage
+CREATE TABLE IF NOT EXISTS attachments (
+    id serial PRIMARY KEY,
+    post integer NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    filename varchar NOT NULL,
+    content_type varchar NOT NULL,
+    size bigint NOT NULL,
+    storage_key varchar NOT NULL,
+    thumbnail_key varchar NOT NULL DEFAULT '',
+    created timestamp with time zone NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS attachments_post ON attachments (post, id);
+
+
+-- Storage keys of deleted attachments, files are removed from storage by periodic sweep
+CREATE TABLE IF NOT EXISTS orphaned_files (
+    key varchar PRIMARY KEY,
+    created timestamp with time zone NOT NULL DEFAULT now()
+);
+
+
+-- Poll is optional part of thread, voters and votes of options are counted by triggers on ballots
+CREATE TABLE IF NOT EXISTS polls (
+    thread integer PRIMARY KEY REFERENCES threads(id) ON DELETE CASCADE,
+    question varchar NOT NULL,
+    multiple boolean NOT NULL DEFAULT FALSE,
+    closes timestamp with time zone,
+    voters integer NOT NULL DEFAULT 0,
+    created timestamp with time zone NOT NULL DEFAULT now()
+);
+
+
+CREATE TABLE IF NOT EXISTS poll_options (
+    id serial PRIMARY KEY,
+    thread integer NOT NULL REFERENCES polls(thread) ON DELETE CASCADE,
+    position integer NOT NULL,
+    text varchar NOT NULL,
+    votes integer NOT NULL DEFAULT 0,
+    UNIQUE(thread, position)
+);
+
+
+-- Ballot keeps all options chosen by user, so user votes in poll only once
+CREATE TABLE IF NOT EXISTS poll_ballots (
+    thread integer NOT NULL REFERENCES polls(thread) ON DELETE CASCADE,
+    nickname citext NOT NULL REFERENCES users(nickname) ON DELETE CASCADE ON UPDATE CASCADE,
+    options integer[] NOT NULL,
+    created timestamp with time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY(thread, nickname)
+);
+CREATE INDEX IF NOT EXISTS poll_ballots_nickname ON poll_ballots (nickname);
+
+
+-- Draft of post has thread and draft of thread has forum, drafts with publish time are published by scheduler
+CREATE TABLE IF NOT EXISTS drafts (
+    id serial PRIMARY KEY,
+    author citext NOT NULL REFERENCES users(nickname) ON DELETE CASCADE ON UPDATE CASCADE,
+    forum citext REFERENCES forums(slug) ON DELETE CASCADE,
+    thread integer REFERENCES threads(id) ON DELETE CASCADE,
+    parent integer NOT NULL DEFAULT 0,
+    title varchar NOT NULL DEFAULT '',
+    slug citext NOT NULL DEFAULT '',
+    message varchar NOT NULL DEFAULT '',
+    publish_at timestamp with time zone,
+    publish_error varchar NOT NULL DEFAULT '',
+    attempts integer NOT NULL DEFAULT 0,
+    claimed_until timestamp with time zone, -- scheduler lease, expired one is claimed again
+    publishing boolean NOT NULL DEFAULT FALSE, -- set before content is created, so it isn't created twice
+    created timestamp with time zone NOT NULL DEFAULT now(),
+    updated timestamp with time zone NOT NULL DEFAULT now(),
+    CHECK ((forum IS NULL) <> (thread IS NULL))
+);
+CREATE INDEX IF NOT EXISTS drafts_author_id ON drafts (author, id);
+CREATE INDEX IF NOT EXISTS drafts_publish_at ON drafts (publish_at) WHERE publish_at IS NOT NULL;
+
+
+-- Token buckets shared between instances, see pkg/ratelimit
+CREATE UNLOGGED TABLE IF NOT EXISTS rate_limits (
+    key varchar PRIMARY KEY,
+    tokens double precision NOT NULL,
+    updated timestamp with time zone NOT NULL
+);
+CREATE INDEX IF NOT EXISTS rate_limits_updated ON rate_limits (updated);
+
+
+-- First responses to requests with Idempotency-Key header, see pkg/idempotency
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key varchar PRIMARY KEY,
+    fingerprint varchar NOT NULL,
+    status integer,
+    content_type varchar,
+    body bytea,
+    expires timestamp with time zone NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires ON idempotency_keys (expires);
+
+
+-- Ids of threads and posts of old forums given to them on import, see internal/importer
+CREATE TABLE IF NOT EXISTS import_ids (
+    source varchar NOT NULL,
+    kind varchar NOT NULL,
+    external_id varchar NOT NULL,
+    id integer NOT NULL,
+    PRIMARY KEY(source, kind, external_id)
+);
+
+
+-- Last imported line of dump, import started again continues after it
+CREATE TABLE IF NOT EXISTS import_checkpoints (
+    source varchar PRIMARY KEY,
+    line integer NOT NULL,
+    imported integer NOT NULL,
+    rejected integer NOT NULL,
+    updated timestamp with time zone NOT NULL DEFAULT now()
+);
+
+
 DROP TRIGGER IF EXISTS inc_threads ON threads;
 DROP TRIGGER IF EXISTS ins_author_on_ins_thread ON threads;
 DROP TRIGGER IF EXISTS ins_author_on_ins_post ON threads;
 DROP TRIGGER IF EXISTS inc_posts ON posts;
+DROP TRIGGER IF EXISTS upd_threads_on_status ON threads;
+DROP TRIGGER IF EXISTS upd_posts_on_status ON posts;
 DROP TRIGGER IF EXISTS upd_isEdited ON posts;
 DROP TRIGGER IF EXISTS upd_votes_on_insert ON votes;
 DROP TRIGGER IF EXISTS upd_votes_on_update ON votes;
+DROP TRIGGER IF EXISTS upd_votes_on_delete ON votes;
 DROP TRIGGER IF EXISTS upd_path ON posts;
-
-
--- Increment threads number in forums
+DROP TRIGGER IF EXISTS upd_score_on_insert ON post_votes;
+DROP TRIGGER IF EXISTS upd_score_on_update ON post_votes;
+DROP TRIGGER IF EXISTS upd_score_on_delete ON post_votes;
+DROP TRIGGER IF EXISTS upd_user_version ON users;
+DROP TRIGGER IF EXISTS upd_forum_version ON forums;
+DROP TRIGGER IF EXISTS upd_thread_version ON threads;
+DROP TRIGGER IF EXISTS upd_post_version ON posts;
+DROP TRIGGER IF EXISTS upd_user_edit_version ON users;
+DROP TRIGGER IF EXISTS upd_thread_edit_version ON threads;
+DROP TRIGGER IF EXISTS upd_post_edit_version ON posts;
+DROP TRIGGER IF EXISTS upd_posts_version ON posts;
+DROP TRIGGER IF EXISTS upd_published ON posts;
+DROP TRIGGER IF EXISTS upd_posts_version_on_insert ON posts;
+DROP TRIGGER IF EXISTS upd_posts_version_on_update ON posts;
+DROP TRIGGER IF EXISTS upd_post_on_attachment ON attachments;
+DROP TRIGGER IF EXISTS del_attachment_files ON attachments;
+DROP TRIGGER IF EXISTS del_attachment_files_on_truncate ON attachments;
+DROP TRIGGER IF EXISTS upd_poll_on_ballot_insert ON poll_ballots;
+DROP TRIGGER IF EXISTS upd_poll_on_ballot_delete ON poll_ballots;
+
+
+-- Import and restore set forum.bulk_load for their transaction and fill paths and counters themselves,
+-- so triggers on insert are skipped without altering tables and locking them
+CREATE OR REPLACE FUNCTION bulk_load() RETURNS boolean AS
+$bulk_load$
+    SELECT coalesce(current_setting('forum.bulk_load', true), '') = 'on';
+$bulk_load$
+LANGUAGE sql STABLE;
+
+
+-- Increment threads number in forums, held threads are counted on approval
 CREATE OR REPLACE FUNCTION inc_threads() RETURNS trigger AS
 $inc_threads$
     BEGIN
+        IF NEW.status <> 'approved' THEN
+            RETURN NEW;
+        END IF;
         UPDATE forums
         SET threads = threads + 1
         WHERE slug=NEW.forum;
@@ -106,7 +397,7 @@ $inc_threads$
 LANGUAGE plpgsql;
 
 CREATE TRIGGER inc_threads AFTER INSERT ON threads
-    FOR EACH ROW EXECUTE PROCEDURE inc_threads();
+    FOR EACH ROW WHEN (NOT bulk_load()) EXECUTE PROCEDURE inc_threads();
 
 
 -- Insert user into forum_user
@@ -122,15 +413,18 @@ $ins_author$
 LANGUAGE plpgsql;
 
 CREATE TRIGGER ins_author_on_ins_thread AFTER INSERT ON threads
-    FOR EACH ROW EXECUTE PROCEDURE ins_author();
+    FOR EACH ROW WHEN (NOT bulk_load()) EXECUTE PROCEDURE ins_author();
 
 CREATE TRIGGER ins_author_on_ins_post AFTER INSERT ON posts
-    FOR EACH ROW EXECUTE PROCEDURE ins_author();
+    FOR EACH ROW WHEN (NOT bulk_load()) EXECUTE PROCEDURE ins_author();
 
 -- Increment threads number in forums
 CREATE OR REPLACE FUNCTION inc_posts() RETURNS trigger AS
 $inc_posts$
     BEGIN
+        IF NEW.status <> 'approved' THEN
+            RETURN NEW;
+        END IF;
         UPDATE forums
         SET posts = posts + 1
         WHERE slug=NEW.forum;
@@ -140,7 +434,66 @@ $inc_posts$
 LANGUAGE plpgsql;
 
 CREATE TRIGGER inc_posts AFTER INSERT ON posts
-    FOR EACH ROW EXECUTE PROCEDURE inc_posts();
+    FOR EACH ROW WHEN (NOT bulk_load()) EXECUTE PROCEDURE inc_posts();
+
+
+-- Update threads number in forums when thread is approved or hidden
+CREATE OR REPLACE FUNCTION upd_threads_on_status() RETURNS trigger AS
+$upd_threads_on_status$
+    BEGIN
+        IF OLD.status <> 'approved' AND NEW.status = 'approved' THEN
+            UPDATE forums
+            SET threads = threads + 1
+            WHERE slug=NEW.forum;
+        ELSIF OLD.status = 'approved' AND NEW.status <> 'approved' THEN
+            UPDATE forums
+            SET threads = threads - 1
+            WHERE slug=NEW.forum;
+        END IF;
+        RETURN NEW;
+    END;
+$upd_threads_on_status$
+LANGUAGE plpgsql;
+
+CREATE TRIGGER upd_threads_on_status AFTER UPDATE OF status ON threads
+    FOR EACH ROW EXECUTE PROCEDURE upd_threads_on_status();
+
+
+-- Held post becomes visible only when approved, so it goes into digests from that moment
+CREATE OR REPLACE FUNCTION upd_published() RETURNS trigger AS
+$upd_published$
+    BEGIN
+        NEW.published = now();
+        RETURN NEW;
+    END;
+$upd_published$
+LANGUAGE plpgsql;
+
+CREATE TRIGGER upd_published BEFORE UPDATE OF status ON posts
+    FOR EACH ROW WHEN (OLD.status = 'pending' AND NEW.status = 'approved')
+    EXECUTE PROCEDURE upd_published();
+
+
+-- Update posts number in forums when post is approved or hidden
+CREATE OR REPLACE FUNCTION upd_posts_on_status() RETURNS trigger AS
+$upd_posts_on_status$
+    BEGIN
+        IF OLD.status <> 'approved' AND NEW.status = 'approved' THEN
+            UPDATE forums
+            SET posts = posts + 1
+            WHERE slug=NEW.forum;
+        ELSIF OLD.status = 'approved' AND NEW.status <> 'approved' THEN
+            UPDATE forums
+            SET posts = posts - 1
+            WHERE slug=NEW.forum;
+        END IF;
+        RETURN NEW;
+    END;
+$upd_posts_on_status$
+LANGUAGE plpgsql;
+
+CREATE TRIGGER upd_posts_on_status AFTER UPDATE OF status ON posts
+    FOR EACH ROW EXECUTE PROCEDURE upd_posts_on_status();
 
 
 -- Set isEdited true if message was updated
@@ -172,7 +525,7 @@ $upd_votes_on_insert$
 LANGUAGE plpgsql;
 
 CREATE TRIGGER upd_votes_on_insert AFTER INSERT ON votes
-    FOR EACH ROW EXECUTE PROCEDURE upd_votes_on_insert();
+    FOR EACH ROW WHEN (NOT bulk_load()) EXECUTE PROCEDURE upd_votes_on_insert();
 
 
 -- Update sum of thread votes on update
@@ -193,6 +546,72 @@ CREATE TRIGGER upd_votes_on_update AFTER UPDATE ON votes
     FOR EACH ROW EXECUTE PROCEDURE upd_votes_on_update();
 
 
+-- Update sum of thread votes on delete
+CREATE OR REPLACE FUNCTION upd_votes_on_delete() RETURNS trigger AS
+$upd_votes_on_delete$
+    BEGIN
+        UPDATE threads
+        SET votes = votes - OLD.voice
+        WHERE id=OLD.thread;
+        RETURN OLD;
+    END;
+$upd_votes_on_delete$
+LANGUAGE plpgsql;
+
+CREATE TRIGGER upd_votes_on_delete AFTER DELETE ON votes
+    FOR EACH ROW EXECUTE PROCEDURE upd_votes_on_delete();
+
+
+-- Update post score on insert
+CREATE OR REPLACE FUNCTION upd_score_on_insert() RETURNS trigger AS
+$upd_score_on_insert$
+    BEGIN
+        UPDATE posts
+        SET score = score + NEW.voice
+        WHERE id=NEW.post;
+        RETURN NEW;
+    END;
+$upd_score_on_insert$
+LANGUAGE plpgsql;
+
+CREATE TRIGGER upd_score_on_insert AFTER INSERT ON post_votes
+    FOR EACH ROW WHEN (NOT bulk_load()) EXECUTE PROCEDURE upd_score_on_insert();
+
+
+-- Update post score on update
+CREATE OR REPLACE FUNCTION upd_score_on_update() RETURNS trigger AS
+$upd_score_on_update$
+    BEGIN
+        IF NEW.voice <> OLD.voice THEN
+            UPDATE posts
+            SET score = score - OLD.voice + NEW.voice
+            WHERE id=NEW.post;
+        END IF;
+        RETURN NEW;
+    END;
+$upd_score_on_update$
+LANGUAGE plpgsql;
+
+CREATE TRIGGER upd_score_on_update AFTER UPDATE ON post_votes
+    FOR EACH ROW EXECUTE PROCEDURE upd_score_on_update();
+
+
+-- Update post score on delete
+CREATE OR REPLACE FUNCTION upd_score_on_delete() RETURNS trigger AS
+$upd_score_on_delete$
+    BEGIN
+        UPDATE posts
+        SET score = score - OLD.voice
+        WHERE id=OLD.post;
+        RETURN OLD;
+    END;
+$upd_score_on_delete$
+LANGUAGE plpgsql;
+
+CREATE TRIGGER upd_score_on_delete AFTER DELETE ON post_votes
+    FOR EACH ROW EXECUTE PROCEDURE upd_score_on_delete();
+
+
 -- Insert id into path on insert
 CREATE OR REPLACE FUNCTION upd_path() RETURNS trigger AS
 $upd_path$
@@ -218,4 +637,185 @@ $upd_path$
 LANGUAGE plpgsql;
 
 CREATE TRIGGER upd_path BEFORE INSERT ON posts
-    FOR EACH ROW EXECUTE PROCEDURE upd_path();
+    FOR EACH ROW WHEN (NOT bulk_load()) EXECUTE PROCEDURE upd_path();
+
+
+-- Bump version of updated row
+CREATE OR REPLACE FUNCTION upd_version() RETURNS trigger AS
+$upd_version$
+    BEGIN
+        NEW.version := OLD.version + 1;
+        NEW.updated := now();
+        RETURN NEW;
+    END;
+$upd_version$
+LANGUAGE plpgsql;
+
+CREATE TRIGGER upd_user_version BEFORE UPDATE ON users
+    FOR EACH ROW EXECUTE PROCEDURE upd_version();
+
+CREATE TRIGGER upd_forum_version BEFORE UPDATE ON forums
+    FOR EACH ROW EXECUTE PROCEDURE upd_version();
+
+CREATE TRIGGER upd_post_version BEFORE UPDATE ON posts
+    FOR EACH ROW EXECUTE PROCEDURE upd_version();
+
+
+-- Bump edit version when content is changed, so concurrent edits conflict and votes don't
+CREATE OR REPLACE FUNCTION upd_edit_version() RETURNS trigger AS
+$upd_edit_version$
+    BEGIN
+        NEW.edit_version := OLD.edit_version + 1;
+        RETURN NEW;
+    END;
+$upd_edit_version$
+LANGUAGE plpgsql;
+
+CREATE TRIGGER upd_user_edit_version BEFORE UPDATE OF fullname, email, about ON users
+    FOR EACH ROW WHEN ((OLD.fullname, OLD.email, OLD.about) IS DISTINCT FROM (NEW.fullname, NEW.email, NEW.about))
+    EXECUTE PROCEDURE upd_edit_version();
+
+CREATE TRIGGER upd_thread_edit_version BEFORE UPDATE OF title, message ON threads
+    FOR EACH ROW WHEN ((OLD.title, OLD.message) IS DISTINCT FROM (NEW.title, NEW.message))
+    EXECUTE PROCEDURE upd_edit_version();
+
+CREATE TRIGGER upd_post_edit_version BEFORE UPDATE OF message ON posts
+    FOR EACH ROW WHEN (OLD.message IS DISTINCT FROM NEW.message)
+    EXECUTE PROCEDURE upd_edit_version();
+
+
+-- Bump version of updated thread unless only version of its posts was bumped
+CREATE OR REPLACE FUNCTION upd_thread_version() RETURNS trigger AS
+$upd_thread_version$
+    BEGIN
+        IF NEW.posts_version = OLD.posts_version THEN
+            NEW.version := OLD.version + 1;
+            NEW.updated := now();
+        END IF;
+        RETURN NEW;
+    END;
+$upd_thread_version$
+LANGUAGE plpgsql;
+
+CREATE TRIGGER upd_thread_version BEFORE UPDATE ON threads
+    FOR EACH ROW EXECUTE PROCEDURE upd_thread_version();
+
+
+-- Bump version of thread posts once per statement, so batch of posts locks and updates its thread once
+CREATE OR REPLACE FUNCTION upd_posts_version() RETURNS trigger AS
+$upd_posts_version$
+    BEGIN
+        UPDATE threads
+        SET posts_version = posts_version + 1, posts_updated = now()
+        WHERE id IN (SELECT DISTINCT thread FROM new_posts);
+        RETURN NULL;
+    END;
+$upd_posts_version$
+LANGUAGE plpgsql;
+
+CREATE TRIGGER upd_posts_version_on_insert AFTER INSERT ON posts
+    REFERENCING NEW TABLE AS new_posts
+    FOR EACH STATEMENT WHEN (NOT bulk_load()) EXECUTE PROCEDURE upd_posts_version();
+
+CREATE TRIGGER upd_posts_version_on_update AFTER UPDATE ON posts
+    REFERENCING NEW TABLE AS new_posts
+    FOR EACH STATEMENT EXECUTE PROCEDURE upd_posts_version();
+
+
+-- Bump version of post when file is attached, so cached details and listings are refreshed
+CREATE OR REPLACE FUNCTION upd_post_on_attachment() RETURNS trigger AS
+$upd_post_on_attachment$
+    BEGIN
+        UPDATE posts
+        SET updated = now()
+        WHERE id=NEW.post;
+        RETURN NEW;
+    END;
+$upd_post_on_attachment$
+LANGUAGE plpgsql;
+
+CREATE TRIGGER upd_post_on_attachment AFTER INSERT ON attachments
+    FOR EACH ROW WHEN (NOT bulk_load()) EXECUTE PROCEDURE upd_post_on_attachment();
+
+
+-- Keep files of attachments removed with their posts or by truncation, so they are swept from storage
+CREATE OR REPLACE FUNCTION del_attachment_files() RETURNS trigger AS
+$del_attachment_files$
+    BEGIN
+        INSERT INTO orphaned_files(key)
+        SELECT key FROM unnest(ARRAY[OLD.storage_key, OLD.thumbnail_key]) AS key
+        WHERE key <> ''
+        ON CONFLICT DO NOTHING;
+        RETURN OLD;
+    END;
+$del_attachment_files$
+LANGUAGE plpgsql;
+
+CREATE TRIGGER del_attachment_files AFTER DELETE ON attachments
+    FOR EACH ROW EXECUTE PROCEDURE del_attachment_files();
+
+
+CREATE OR REPLACE FUNCTION del_attachment_files_on_truncate() RETURNS trigger AS
+$del_attachment_files_on_truncate$
+    BEGIN
+        INSERT INTO orphaned_files(key)
+        SELECT storage_key FROM attachments
+        UNION
+        SELECT thumbnail_key FROM attachments WHERE thumbnail_key <> ''
+        ON CONFLICT DO NOTHING;
+        RETURN NULL;
+    END;
+$del_attachment_files_on_truncate$
+LANGUAGE plpgsql;
+
+CREATE TRIGGER del_attachment_files_on_truncate BEFORE TRUNCATE ON attachments
+    FOR EACH STATEMENT EXECUTE PROCEDURE del_attachment_files_on_truncate();
+
+
+
+-- Count ballot in poll and bump version of thread, so cached details show new tallies
+CREATE OR REPLACE FUNCTION upd_poll_on_ballot_insert() RETURNS trigger AS
+$upd_poll_on_ballot_insert$
+    BEGIN
+        UPDATE poll_options
+        SET votes = votes + 1
+        WHERE thread=NEW.thread AND id = ANY(NEW.options);
+
+        UPDATE polls
+        SET voters = voters + 1
+        WHERE thread=NEW.thread;
+
+        UPDATE threads
+        SET updated = now()
+        WHERE id=NEW.thread;
+        RETURN NEW;
+    END;
+$upd_poll_on_ballot_insert$
+LANGUAGE plpgsql;
+
+CREATE TRIGGER upd_poll_on_ballot_insert AFTER INSERT ON poll_ballots
+    FOR EACH ROW WHEN (NOT bulk_load()) EXECUTE PROCEDURE upd_poll_on_ballot_insert();
+
+
+-- Uncount ballot removed with its user
+CREATE OR REPLACE FUNCTION upd_poll_on_ballot_delete() RETURNS trigger AS
+$upd_poll_on_ballot_delete$
+    BEGIN
+        UPDATE poll_options
+        SET votes = votes - 1
+        WHERE thread=OLD.thread AND id = ANY(OLD.options);
+
+        UPDATE polls
+        SET voters = voters - 1
+        WHERE thread=OLD.thread;
+
+        UPDATE threads
+        SET updated = now()
+        WHERE id=OLD.thread;
+        RETURN OLD;
+    END;
+$upd_poll_on_ballot_delete$
+LANGUAGE plpgsql;
+
+CREATE TRIGGER upd_poll_on_ballot_delete AFTER DELETE ON poll_ballots
+    FOR EACH ROW EXECUTE PROCEDURE upd_poll_on_ballot_delete();